@@ -2,11 +2,15 @@ package cmd
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"os"
 	"time"
 
 	"github.com/jtodic/docker-time-machine/pkg/analyzer"
+	"github.com/jtodic/docker-time-machine/pkg/inspect"
+	"github.com/jtodic/docker-time-machine/pkg/report"
+	"github.com/jtodic/docker-time-machine/pkg/scan"
 	"github.com/spf13/cobra"
 )
 
@@ -20,6 +24,26 @@ var analyzeFlags struct {
 	since          string
 	until          string
 	skipFailed     bool
+	sample         string
+	every          int
+	threshold      float64
+	files          bool
+	waste          bool
+	packages       bool
+	deps           bool
+	junk           bool
+	lint           bool
+	sizeBasis      string
+	sbom           string
+	vulnDB         string
+	licenses       bool
+	denyLicenses   []string
+	binaries       bool
+	secrets        bool
+	duplicates     bool
+	dirs           bool
+	dirDepth       int
+	treemap        bool
 }
 
 var analyzeCmd = &cobra.Command{
@@ -37,6 +61,112 @@ traversal), not chronological order. At each commit, it:
   • Calculates size deltas between consecutive successful builds
   • Cleans up temporary images
 
+Long histories can be sampled instead of building every commit: every Nth
+commit (--every), one commit per day, week or month (--sample weekly), or
+adaptively (--sample adaptive), which builds a coarse sample first and then
+recursively fills in between samples whose sizes differ by more than
+--adaptive-threshold, locating big jumps precisely. In adaptive mode
+--max-commits caps the total number of builds.
+
+With --files, each built image is exported and its layer tarballs are read to
+record the files every layer adds, modifies and deletes. The report then lists
+the files and directories that grew the most between consecutive commits.
+This is slower and needs temporary disk space for one image at a time.
+
+With --waste, the same layer contents are used to find bytes that a layer
+writes and a later layer overwrites or deletes (e.g. COPY of an archive
+followed by RUN rm). These bytes are still shipped. The report tracks wasted
+bytes and an efficiency score per commit and names the commit and the
+instructions that introduced the waste.
+
+With --packages, the dpkg, apk and rpm databases are read from each image and
+the report lists the OS packages added, removed or changed between consecutive
+commits with their installed sizes.
+
+With --deps, language ecosystem dependencies are detected in each image:
+packages below node_modules, Python distributions in site-packages and the
+modules embedded in Go binaries. The report lists the dependencies added,
+removed, upgraded or grown between consecutive commits with their on-disk
+sizes.
+
+With --junk, each image is checked for leftovers that are not needed at
+runtime: package manager lists and caches, pip, npm, yarn and Go caches, .git
+directories, tests and fixtures, compiler toolchains, docs, temporary files
+and logs. Findings are attributed to the layer and Dockerfile lines that
+created them, and the report shows the reclaimable bytes per commit with a
+suggested fix for each finding.
+
+With --lint, the Dockerfile of each commit is checked for patterns that bloat
+images (apt-get install without --no-install-recommends or cleanup, COPY .
+before dependency installs, single-stage builds with build tools, unpinned
+base images). The report lists the issues of the newest commit ranked by the
+size of the layers they affect. See 'dtm lint' to check the working tree.
+
+With --sbom spdx or --sbom cyclonedx, an SBOM listing the OS packages and
+language dependencies of each image is written next to the report, one file
+per commit (sbom-<commit>.spdx.json or sbom-<commit>.cdx.json). The report
+lists the components added or removed between consecutive commits.
+
+With --vuln-db, the OS packages and language dependencies of each image are
+matched against a local OSV advisory database: a directory of OSV JSON files or
+a .zip or .tar.gz archive of them, such as the per-ecosystem exports of
+osv.dev. Nothing is fetched over the network. The report lists the
+vulnerability counts by severity per commit and the vulnerabilities introduced
+or fixed by each commit, and the HTML chart plots the counts next to the size.
+
+With --licenses, the licenses of the OS packages (apk and rpm databases,
+Debian copyright files) and of the npm and Python dependencies are recorded
+per commit, and the report lists the licenses each commit added or removed
+with the components that brought them. --deny-licenses AGPL,SSPL reports
+where a denied license first appeared and makes dtm exit with a nonzero
+status, for use in CI.
+
+With --binaries, every ELF executable in the image is inspected: its
+architecture, whether it is statically linked and stripped, whether it carries
+debug info, and for Go programs the toolchain version and main module. The
+report lists the largest executables per commit, their size over the history,
+and the executables added, removed or changed by each commit, such as a binary
+that stopped being stripped.
+
+With --scan-secrets, every file written by any layer is checked for private
+keys, cloud and registry tokens and high entropy passwords, including files
+that a later layer deletes: those are still shipped in the layer tarball. Each
+secret is reported once, with the layer, instruction and commit where it first
+appeared, and dtm exits with a nonzero status when any is found.
+
+With --duplicates, the contents of every file in every layer are hashed to find
+the same bytes stored more than once, e.g. an artifact copied by two COPY
+instructions or from two build stages, or a file rewritten unchanged by
+chmod. The report shows the wasted bytes per commit and, for the newest commit,
+each duplicate group with its paths and the instructions that wrote them.
+
+With --dirs, the final filesystem of each commit is summed up by directory
+(/usr/lib, /app/node_modules, ...; --dir-depth sets how many path components
+are kept) and the report shows the largest directories over time. The HTML
+chart plots them as stacked areas, which shows growth that happens inside a
+single COPY layer.
+
+With --treemap, the HTML report embeds a zoomable treemap of the final
+filesystem of each commit. Click a directory to zoom in; the diff mode colors
+the paths that are new or grew since the previous commit.
+
+Both sizes are tracked for every commit and layer: the uncompressed size as
+reported by Docker, and the compressed size a registry would store, measured
+by exporting each image and gzipping its layers. Sizes are uncompressed by
+default; --size-basis compressed makes the numbers comparable with
+'dtm registry'. The selected basis drives diffs, charts and insights; JSON
+output keeps both sizes.
+
+Every report also compares the layer digests of consecutive commits and lists
+the bytes a host must download to upgrade from the previous commit, the bytes
+shared with it and the bytes unique to each commit.
+
+The size of each commit is split into the base image named by the final FROM
+(layers no Dockerfile instruction created) and the application layers built on
+top of it, charted as two series. The base image digest is recorded for every
+commit, and commits where the base image changed are flagged with the base
+image's own size delta, separating upstream growth from your changes.
+
 Output formats include interactive HTML charts, tables, JSON, CSV, and Markdown.
 The tool automatically identifies commits that caused the largest size increases
 (bloat) or decreases (optimizations).
@@ -59,7 +189,58 @@ and system load at the time of analysis.`,
   dtm analyze --repo /path/to/project --dockerfile build/Dockerfile
 
   # Skip commits that fail to build and continue analysis
-  dtm analyze --skip-failed`,
+  dtm analyze --skip-failed
+
+  # Analyze one commit per week across the whole history
+  dtm analyze --max-commits 0 --sample weekly
+
+  # Build a coarse sample, then zoom in on jumps larger than 10 MB
+  dtm analyze --max-commits 0 --sample adaptive --adaptive-threshold 10
+
+  # Show which files made the image grow
+  dtm analyze --max-commits 5 --files
+
+  # Find files that are deleted or overwritten in later layers
+  dtm analyze --max-commits 5 --waste
+
+  # List the OS packages added, removed or upgraded between commits
+  dtm analyze --max-commits 5 --packages
+
+  # List the npm, Python and Go dependencies that were added or grew
+  dtm analyze --max-commits 5 --deps
+
+  # Find caches and build leftovers, with suggested Dockerfile fixes
+  dtm analyze --max-commits 5 --junk
+
+  # Rank Dockerfile issues by the bytes they cost
+  dtm analyze --max-commits 5 --lint
+
+  # Write a CycloneDX SBOM per commit and list component changes
+  dtm analyze --max-commits 5 --sbom cyclonedx --format markdown --output report.md
+
+  # Match packages against a downloaded OSV export, fully offline
+  dtm analyze --max-commits 10 --vuln-db ~/osv/Debian.zip --format chart
+
+  # Fail when an AGPL licensed component shows up
+  dtm analyze --max-commits 10 --deny-licenses AGPL
+
+  # Find executables that grew or lost their stripping
+  dtm analyze --max-commits 10 --binaries
+
+  # Fail CI when a key or token was ever shipped in a layer
+  dtm analyze --max-commits 10 --scan-secrets
+
+  # Find artifacts stored in more than one layer
+  dtm analyze --max-commits 10 --duplicates
+
+  # Chart the largest directories over the history
+  dtm analyze --max-commits 20 --dirs --format chart
+
+  # Explore what takes up space in each commit
+  dtm analyze --max-commits 10 --treemap --format chart
+
+  # Report compressed sizes, comparable with dtm registry
+  dtm analyze --max-commits 5 --size-basis compressed`,
 	RunE: runAnalyze,
 }
 
@@ -75,9 +256,41 @@ func init() {
 	analyzeCmd.Flags().StringVar(&analyzeFlags.since, "since", "", "Analyze commits since date (YYYY-MM-DD)")
 	analyzeCmd.Flags().StringVar(&analyzeFlags.until, "until", "", "Analyze commits until date (YYYY-MM-DD)")
 	analyzeCmd.Flags().BoolVar(&analyzeFlags.skipFailed, "skip-failed", false, "Skip commits that fail to build")
+	analyzeCmd.Flags().StringVar(&analyzeFlags.sample, "sample", "", "Sampling mode: daily, weekly, monthly, adaptive (default: every commit)")
+	analyzeCmd.Flags().IntVar(&analyzeFlags.every, "every", 0, "Analyze every Nth commit (coarse step in adaptive mode)")
+	analyzeCmd.Flags().Float64Var(&analyzeFlags.threshold, "adaptive-threshold", 5, "Size difference in MB between samples that triggers refinement in adaptive mode")
+	analyzeCmd.Flags().BoolVar(&analyzeFlags.files, "files", false, "Inspect layer contents and report the top growing files and directories")
+	analyzeCmd.Flags().BoolVar(&analyzeFlags.waste, "waste", false, "Inspect layer contents and report space wasted by files overwritten or deleted in later layers")
+	analyzeCmd.Flags().BoolVar(&analyzeFlags.packages, "packages", false, "Inspect layer contents and report OS package changes between commits")
+	analyzeCmd.Flags().BoolVar(&analyzeFlags.deps, "deps", false, "Inspect layer contents and report npm, Python and Go dependency changes between commits")
+	analyzeCmd.Flags().BoolVar(&analyzeFlags.junk, "junk", false, "Inspect layer contents and report leftover caches and build files")
+	analyzeCmd.Flags().BoolVar(&analyzeFlags.lint, "lint", false, "Lint the Dockerfile and rank the issues by the size of the layers they affect")
+	analyzeCmd.Flags().StringVar(&analyzeFlags.sbom, "sbom", "", "Write an SBOM per commit next to the report and list component changes: spdx, cyclonedx")
+	analyzeCmd.Flags().StringVar(&analyzeFlags.vulnDB, "vuln-db", "", "Match packages against a local OSV advisory database (directory, .zip or .tar.gz) and report vulnerabilities per commit")
+	analyzeCmd.Flags().BoolVar(&analyzeFlags.licenses, "licenses", false, "Inspect layer contents and report the licenses added or removed between commits")
+	analyzeCmd.Flags().StringSliceVar(&analyzeFlags.denyLicenses, "deny-licenses", nil, "Fail when one of these licenses appears, e.g. AGPL,SSPL (implies --licenses)")
+	analyzeCmd.Flags().BoolVar(&analyzeFlags.binaries, "binaries", false, "Inspect layer contents and report ELF executables, their build properties and size per commit")
+	analyzeCmd.Flags().BoolVar(&analyzeFlags.secrets, "scan-secrets", false, "Inspect layer contents for keys, tokens and passwords, including deleted files, and fail when any is found")
+	analyzeCmd.Flags().BoolVar(&analyzeFlags.duplicates, "duplicates", false, "Inspect layer contents and report files whose content is stored in more than one place")
+	analyzeCmd.Flags().BoolVar(&analyzeFlags.dirs, "dirs", false, "Inspect layer contents and report the size of the largest directories per commit")
+	analyzeCmd.Flags().IntVar(&analyzeFlags.dirDepth, "dir-depth", inspect.DefaultDirDepth, "Path components directories are grouped by with --dirs, e.g. 2 for /usr/lib")
+	analyzeCmd.Flags().BoolVar(&analyzeFlags.treemap, "treemap", false, "Inspect layer contents and embed a zoomable treemap of each commit in the HTML report")
+	analyzeCmd.Flags().StringVar(&analyzeFlags.sizeBasis, "size-basis", "uncompressed", "Sizes that drive diffs, charts and insights: uncompressed, compressed")
 }
 
 func runAnalyze(cmd *cobra.Command, args []string) error {
+	sizeBasis, err := report.ParseSizeBasis(analyzeFlags.sizeBasis, report.Uncompressed)
+	if err != nil {
+		return err
+	}
+	if err := validateSBOMFormat(analyzeFlags.sbom); err != nil {
+		return err
+	}
+	vulnDB, err := loadVulnDB(analyzeFlags.vulnDB)
+	if err != nil {
+		return err
+	}
+
 	// Create analyzer config
 	config := analyzer.Config{
 		RepoPath:       analyzeFlags.repoPath,
@@ -88,6 +301,30 @@ func runAnalyze(cmd *cobra.Command, args []string) error {
 		Until:          analyzeFlags.until,
 		SkipFailed:     analyzeFlags.skipFailed,
 		Verbose:        verbose,
+
+		Sample:            analyzeFlags.sample,
+		Every:             analyzeFlags.every,
+		AdaptiveThreshold: analyzeFlags.threshold,
+
+		Scan: scan.Options{
+			Files:        analyzeFlags.files,
+			Waste:        analyzeFlags.waste,
+			Packages:     analyzeFlags.packages,
+			Dependencies: analyzeFlags.deps,
+			Junk:         analyzeFlags.junk,
+			SBOM:         analyzeFlags.sbom != "",
+			Vulns:        vulnDB,
+			Licenses:     analyzeFlags.licenses || len(analyzeFlags.denyLicenses) > 0,
+			DenyLicenses: analyzeFlags.denyLicenses,
+			Binaries:     analyzeFlags.binaries,
+			Secrets:      analyzeFlags.secrets,
+			Duplicates:   analyzeFlags.duplicates,
+			Dirs:         analyzeFlags.dirs,
+			DirDepth:     analyzeFlags.dirDepth,
+			Treemap:      analyzeFlags.treemap,
+		},
+		Lint:      analyzeFlags.lint,
+		SizeBasis: sizeBasis,
 	}
 
 	//fmt.Printf("\n%+v\n\n", config)
@@ -145,5 +382,23 @@ func runAnalyze(cmd *cobra.Command, args []string) error {
 		fmt.Fprintf(os.Stderr, "✅ Report saved to: %s\n", analyzeFlags.output)
 	}
 
+	if analyzeFlags.sbom != "" {
+		if err := writeSBOMs(analyzeFlags.output, analyzeFlags.sbom, tm.SBOMs()); err != nil {
+			return err
+		}
+	}
+
+	var failures []error
+	if violations := tm.LicenseViolations(); len(violations) > 0 {
+		failures = append(failures, licensePolicyError(violations))
+	}
+	if leaks := tm.SecretLeaks(); len(leaks) > 0 {
+		failures = append(failures, secretsError(leaks))
+	}
+	if len(failures) > 0 {
+		cmd.SilenceUsage = true
+		return errors.Join(failures...)
+	}
+
 	return nil
 }
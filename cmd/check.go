@@ -0,0 +1,169 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/jtodic/docker-time-machine/pkg/analyzer"
+	"github.com/olekukonko/tablewriter"
+	"github.com/spf13/cobra"
+)
+
+var checkFlags struct {
+	repoPath       string
+	dockerfilePath string
+	target         string
+	revision       string
+	format         string
+	maxIncrease    float64
+	maxIncreasePct float64
+	maxNewLayers   int
+}
+
+var checkCmd = &cobra.Command{
+	Use:   "check",
+	Short: "Predict the image impact of uncommitted changes",
+	Long: `Build the current working tree (including staged and unstaged changes) and
+the merge-base with a target branch, then report how image size and layers
+would change.
+
+The merge-base is built straight from git objects, so your working tree is
+never checked out or modified. The working tree build only includes the files
+git tracks or has staged, as the merge-base does; untracked and ignored files
+such as a local node_modules are left out. With --rev, a commit is built from git objects
+in place of the working tree, ignoring uncommitted changes.
+
+When a budget flag is set and exceeded, dtm exits with a nonzero status. Use
+'dtm hooks install' to run this check automatically before every push.`,
+	Example: `  # Compare the working tree against main
+  dtm check
+
+  # Fail when the image grows by more than 10 MB or 5%
+  dtm check --target develop --max-increase 10 --max-increase-percent 5
+
+  # Check a commit instead of the working tree
+  dtm check --rev HEAD~1
+
+  # Export the result as JSON
+  dtm check --format json`,
+	RunE: runCheck,
+}
+
+func init() {
+	rootCmd.AddCommand(checkCmd)
+
+	checkCmd.Flags().StringVarP(&checkFlags.repoPath, "repo", "r", ".", "Path to git repository")
+	checkCmd.Flags().StringVarP(&checkFlags.dockerfilePath, "dockerfile", "d", "Dockerfile", "Path to Dockerfile relative to repo root")
+	checkCmd.Flags().StringVarP(&checkFlags.target, "target", "t", "main", "Branch or revision to compare against")
+	checkCmd.Flags().StringVar(&checkFlags.revision, "rev", "", "Commit to check instead of the working tree")
+	checkCmd.Flags().StringVarP(&checkFlags.format, "format", "f", "table", "Output format: table, json")
+	checkCmd.Flags().Float64Var(&checkFlags.maxIncrease, "max-increase", 0, "Maximum allowed size increase in MB (0 = no limit)")
+	checkCmd.Flags().Float64Var(&checkFlags.maxIncreasePct, "max-increase-percent", 0, "Maximum allowed size increase in percent (0 = no limit)")
+	checkCmd.Flags().IntVar(&checkFlags.maxNewLayers, "max-new-layers", 0, "Maximum allowed increase in layer count (0 = no limit)")
+}
+
+func runCheck(cmd *cobra.Command, args []string) error {
+	checker, err := analyzer.NewChecker(analyzer.CheckConfig{
+		RepoPath:       checkFlags.repoPath,
+		DockerfilePath: checkFlags.dockerfilePath,
+		Target:         checkFlags.target,
+		Revision:       checkFlags.revision,
+		MaxIncreaseMB:  checkFlags.maxIncrease,
+		MaxIncreasePct: checkFlags.maxIncreasePct,
+		MaxNewLayers:   checkFlags.maxNewLayers,
+		Verbose:        verbose,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create checker: %w", err)
+	}
+
+	if checkFlags.revision != "" {
+		fmt.Fprintf(os.Stderr, "🔍 Checking %s against %s\n", checkFlags.revision, checkFlags.target)
+	} else {
+		fmt.Fprintf(os.Stderr, "🔍 Checking working tree against %s\n", checkFlags.target)
+	}
+
+	result, err := checker.Run(context.Background())
+	if err != nil {
+		return fmt.Errorf("check failed: %w", err)
+	}
+
+	switch checkFlags.format {
+	case "table":
+		writeCheckTable(os.Stdout, result)
+	case "json":
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(result); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("unsupported format: %s", checkFlags.format)
+	}
+
+	if result.OverBudget() {
+		cmd.SilenceUsage = true
+		return fmt.Errorf("image budget exceeded")
+	}
+
+	return nil
+}
+
+func writeCheckTable(w io.Writer, result *analyzer.CheckResult) {
+	title, label := "Working Tree Impact", "Working tree:"
+	if result.Revision != "" {
+		title, label = "Revision Impact", fmt.Sprintf("Revision (%s):", result.WorkingTree.Commit)
+	}
+	fmt.Fprintf(w, "\n📊 %s\n", title)
+	fmt.Fprintln(w, strings.Repeat("=", len(title)+3))
+	fmt.Fprintf(w, "Merge-base (%s @ %s): %.2f MB, %d layers\n",
+		result.Target, result.MergeBase.Commit, result.MergeBase.SizeMB, result.MergeBase.Layers)
+	fmt.Fprintf(w, "%-30s %.2f MB, %d layers\n",
+		label, result.WorkingTree.SizeMB, result.WorkingTree.Layers)
+	fmt.Fprintf(w, "%-30s %+.2f MB (%+.1f%%), %+d layers\n",
+		"Change:", result.SizeDiff, result.SizeDiffPercent, result.LayersDiff)
+
+	if len(result.LayerDeltas) > 0 {
+		fmt.Fprintln(w, "\n📦 Changed Layers:")
+		fmt.Fprintln(w, "------------------")
+
+		table := tablewriter.NewWriter(w)
+		table.SetHeader([]string{"Layer", "Before (MB)", "After (MB)", "Diff"})
+		table.SetBorder(false)
+		table.SetAutoWrapText(false)
+		table.SetColumnSeparator(" ")
+		table.SetHeaderAlignment(tablewriter.ALIGN_LEFT)
+		table.SetAlignment(tablewriter.ALIGN_LEFT)
+
+		for _, delta := range result.LayerDeltas {
+			table.Append([]string{
+				truncate(delta.Command, 50),
+				formatLayerSize(delta.BeforeMB),
+				formatLayerSize(delta.AfterMB),
+				fmt.Sprintf("%+.2f", delta.DiffMB),
+			})
+		}
+		table.Render()
+	}
+
+	if result.OverBudget() {
+		fmt.Fprintln(w)
+		for _, v := range result.Violations {
+			fmt.Fprintf(w, "❌ %s\n", v)
+		}
+	} else {
+		fmt.Fprintln(w, "\n✅ Within budget")
+	}
+}
+
+// formatLayerSize formats a layer size in MB, using "-" for absent layers
+func formatLayerSize(sizeMB float64) string {
+	if sizeMB < 0 {
+		return "-"
+	}
+	return fmt.Sprintf("%.2f", sizeMB)
+}
@@ -0,0 +1,86 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/jtodic/docker-time-machine/pkg/analyzer"
+	"github.com/spf13/cobra"
+)
+
+var compareFlags struct {
+	repoPath       string
+	dockerfilePath string
+	format         string
+	output         string
+}
+
+var compareCmd = &cobra.Command{
+	Use:   "compare <ref> <ref> [ref...]",
+	Short: "Compare Docker images built from several branches or tags",
+	Long: `Build the Docker image at each given branch, tag or revision once and compare
+all of them against each other.
+
+The report contains an N×N matrix of size deltas (row → column) and a
+layer-by-ref table that matches layers by the Dockerfile instruction that
+created them. Images are built from git objects, so the working tree is not
+touched.`,
+	Example: `  # Compare long-lived release branches
+  dtm compare main release/3.x release/2.x feature/slim
+
+  # Generate an HTML report
+  dtm compare main develop --format chart --output compare.html`,
+	Args: cobra.MinimumNArgs(2),
+	RunE: runCompare,
+}
+
+func init() {
+	rootCmd.AddCommand(compareCmd)
+
+	compareCmd.Flags().StringVarP(&compareFlags.repoPath, "repo", "r", ".", "Path to git repository")
+	compareCmd.Flags().StringVarP(&compareFlags.dockerfilePath, "dockerfile", "d", "Dockerfile", "Path to Dockerfile relative to repo root")
+	compareCmd.Flags().StringVarP(&compareFlags.format, "format", "f", "table", "Output format: table, json, markdown, chart")
+	compareCmd.Flags().StringVarP(&compareFlags.output, "output", "o", "", "Output file path (default: stdout, auto-generated timestamped file for chart)")
+}
+
+func runCompare(cmd *cobra.Command, args []string) error {
+	comparer, err := analyzer.NewComparer(analyzer.ComparerConfig{
+		RepoPath:       compareFlags.repoPath,
+		DockerfilePath: compareFlags.dockerfilePath,
+		Verbose:        verbose,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create comparer: %w", err)
+	}
+
+	fmt.Fprintf(os.Stderr, "🔍 Comparing %d refs\n", len(args))
+
+	result, err := comparer.CompareMatrix(context.Background(), args)
+	if err != nil {
+		return fmt.Errorf("comparison failed: %w", err)
+	}
+
+	output := os.Stdout
+	if compareFlags.output == "" && compareFlags.format == "chart" {
+		compareFlags.output = fmt.Sprintf("compare-%s.html", time.Now().Format("2006-01-02-150405"))
+	}
+	if compareFlags.output != "" {
+		output, err = os.Create(compareFlags.output)
+		if err != nil {
+			return fmt.Errorf("failed to create output file: %w", err)
+		}
+		defer output.Close()
+	}
+
+	if err := result.GenerateReport(compareFlags.format, output); err != nil {
+		return fmt.Errorf("failed to generate report: %w", err)
+	}
+
+	if compareFlags.output != "" {
+		fmt.Fprintf(os.Stderr, "✅ Report saved to: %s\n", compareFlags.output)
+	}
+
+	return nil
+}
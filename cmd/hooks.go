@@ -0,0 +1,150 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// hookMarker identifies hooks written by dtm so they can be safely replaced
+const hookMarker = "# Installed by dtm (Docker Time Machine)"
+
+var hooksFlags struct {
+	repoPath       string
+	dockerfilePath string
+	target         string
+	maxIncrease    float64
+	maxIncreasePct float64
+	maxNewLayers   int
+	force          bool
+}
+
+var hooksCmd = &cobra.Command{
+	Use:   "hooks",
+	Short: "Manage git hooks that run dtm automatically",
+}
+
+var hooksInstallCmd = &cobra.Command{
+	Use:   "install",
+	Short: "Install a pre-push hook that runs 'dtm check'",
+	Long: `Install a git pre-push hook that runs 'dtm check' with the given budget on
+every pushed commit, so image bloat is reported before it reaches CI. The
+commits are built from git objects, so uncommitted changes are not included.
+The push is aborted when the budget is exceeded; use 'git push --no-verify'
+to skip the check once.
+
+The hook is written to the hooks directory git uses for the repository,
+honoring core.hooksPath, worktrees and submodules.`,
+	Example: `  # Block pushes that grow the image by more than 20 MB compared to main
+  dtm hooks install --max-increase 20
+
+  # Compare against develop and replace an existing pre-push hook
+  dtm hooks install --target develop --max-increase-percent 10 --force`,
+	Args: cobra.NoArgs,
+	RunE: runHooksInstall,
+}
+
+func init() {
+	rootCmd.AddCommand(hooksCmd)
+	hooksCmd.AddCommand(hooksInstallCmd)
+
+	hooksInstallCmd.Flags().StringVarP(&hooksFlags.repoPath, "repo", "r", ".", "Path to git repository")
+	hooksInstallCmd.Flags().StringVarP(&hooksFlags.dockerfilePath, "dockerfile", "d", "Dockerfile", "Path to Dockerfile relative to repo root")
+	hooksInstallCmd.Flags().StringVarP(&hooksFlags.target, "target", "t", "main", "Branch or revision to compare against")
+	hooksInstallCmd.Flags().Float64Var(&hooksFlags.maxIncrease, "max-increase", 0, "Maximum allowed size increase in MB (0 = no limit)")
+	hooksInstallCmd.Flags().Float64Var(&hooksFlags.maxIncreasePct, "max-increase-percent", 0, "Maximum allowed size increase in percent (0 = no limit)")
+	hooksInstallCmd.Flags().IntVar(&hooksFlags.maxNewLayers, "max-new-layers", 0, "Maximum allowed increase in layer count (0 = no limit)")
+	hooksInstallCmd.Flags().BoolVar(&hooksFlags.force, "force", false, "Overwrite an existing pre-push hook")
+}
+
+func runHooksInstall(cmd *cobra.Command, args []string) error {
+	hooksDir, err := gitHooksDir(hooksFlags.repoPath)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(hooksDir, 0755); err != nil {
+		return fmt.Errorf("failed to create hooks directory: %w", err)
+	}
+
+	hookPath := filepath.Join(hooksDir, "pre-push")
+	if existing, err := os.ReadFile(hookPath); err == nil {
+		if !strings.Contains(string(existing), hookMarker) && !hooksFlags.force {
+			return fmt.Errorf("a pre-push hook already exists at %s - use --force to overwrite it", hookPath)
+		}
+	}
+
+	// Prefer dtm from PATH so the hook survives reinstalls; fall back to
+	// the absolute path of the running binary
+	dtmPath := "dtm"
+	if _, err := exec.LookPath("dtm"); err != nil {
+		if exe, err := os.Executable(); err == nil {
+			dtmPath = exe
+		}
+	}
+
+	checkArgs := []string{
+		shellQuote(dtmPath), "check",
+		"--dockerfile", shellQuote(hooksFlags.dockerfilePath),
+		"--target", shellQuote(hooksFlags.target),
+	}
+	if hooksFlags.maxIncrease > 0 {
+		checkArgs = append(checkArgs, "--max-increase", fmt.Sprintf("%g", hooksFlags.maxIncrease))
+	}
+	if hooksFlags.maxIncreasePct > 0 {
+		checkArgs = append(checkArgs, "--max-increase-percent", fmt.Sprintf("%g", hooksFlags.maxIncreasePct))
+	}
+	if hooksFlags.maxNewLayers > 0 {
+		checkArgs = append(checkArgs, "--max-new-layers", fmt.Sprintf("%d", hooksFlags.maxNewLayers))
+	}
+
+	// git passes "<local ref> <local sha> <remote ref> <remote sha>" for every
+	// pushed ref on stdin. Each pushed commit is checked rather than the
+	// working tree; deleted refs have an all-zero local sha.
+	script := fmt.Sprintf(`#!/bin/sh
+%s
+# Reports the image size impact of the pushed commits and aborts the push when
+# the budget is exceeded. Skip once with: git push --no-verify
+cd "$(git rev-parse --show-toplevel)" || exit 1
+status=0
+while read -r local_ref local_sha remote_ref remote_sha; do
+	case "$local_sha" in
+	*[!0]*) ;;
+	*) continue ;;
+	esac
+	%s --rev "$local_sha" </dev/null || status=1
+done
+exit $status
+`, hookMarker, strings.Join(checkArgs, " "))
+
+	if err := os.WriteFile(hookPath, []byte(script), 0755); err != nil {
+		return fmt.Errorf("failed to write hook: %w", err)
+	}
+
+	fmt.Fprintf(os.Stderr, "✅ Installed pre-push hook: %s\n", hookPath)
+	return nil
+}
+
+// gitHooksDir returns the hooks directory of the repository at repoPath as git
+// resolves it, which honors core.hooksPath, worktrees and submodules
+func gitHooksDir(repoPath string) (string, error) {
+	cmd := exec.Command("git", "rev-parse", "--git-path", "hooks")
+	cmd.Dir = repoPath
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("%s is not a git repository: %w", repoPath, err)
+	}
+	dir := strings.TrimSpace(string(out))
+	if !filepath.IsAbs(dir) {
+		dir = filepath.Join(repoPath, dir)
+	}
+	return dir, nil
+}
+
+// shellQuote quotes s for safe use in a POSIX shell script
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
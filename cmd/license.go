@@ -0,0 +1,17 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/jtodic/docker-time-machine/pkg/license"
+)
+
+// licensePolicyError reports the denied licenses found and returns the error
+// that makes dtm exit with a nonzero status
+func licensePolicyError(violations []license.Violation) error {
+	for _, v := range violations {
+		fmt.Fprintf(os.Stderr, "🚫 %s\n", v)
+	}
+	return fmt.Errorf("license policy violated: %d denied license(s) found", len(violations))
+}
@@ -0,0 +1,130 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/jtodic/docker-time-machine/pkg/analyzer"
+	"github.com/jtodic/docker-time-machine/pkg/lint"
+	"github.com/jtodic/docker-time-machine/pkg/report"
+	"github.com/spf13/cobra"
+)
+
+var lintFlags struct {
+	repoPath       string
+	dockerfilePath string
+	format         string
+	noBuild        bool
+}
+
+var lintCmd = &cobra.Command{
+	Use:   "lint",
+	Short: "Find Dockerfile patterns that bloat the image, ranked by their measured cost",
+	Long: `Check the Dockerfile for patterns that make images larger or rebuilds slower:
+
+  • apt-get install without --no-install-recommends
+  • package manager caches not cleaned in the same RUN (apt lists, apk, yum/dnf, pip)
+  • COPY . before installing dependencies, which invalidates the install layer
+    on every source change
+  • build tools in a single-stage build instead of a multi-stage build
+  • base images without a version tag or digest
+
+The working tree is built first and every issue is ranked by the bytes of the
+layers it affects in the built image, so the most expensive fixes come first.
+Use --no-build to lint without Docker; issues are then listed in file order.`,
+	Example: `  # Lint and rank by measured layer sizes
+  dtm lint
+
+  # Lint a Dockerfile in a subdirectory without building it
+  dtm lint --dockerfile build/Dockerfile --no-build
+
+  # Export the issues as JSON
+  dtm lint --format json`,
+	RunE: runLint,
+}
+
+func init() {
+	rootCmd.AddCommand(lintCmd)
+
+	lintCmd.Flags().StringVarP(&lintFlags.repoPath, "repo", "r", ".", "Path to the build context")
+	lintCmd.Flags().StringVarP(&lintFlags.dockerfilePath, "dockerfile", "d", "Dockerfile", "Path to Dockerfile relative to repo root")
+	lintCmd.Flags().StringVarP(&lintFlags.format, "format", "f", "table", "Output format: table, json, markdown")
+	lintCmd.Flags().BoolVar(&lintFlags.noBuild, "no-build", false, "Lint without building the image (issues are not ranked by size)")
+}
+
+func runLint(cmd *cobra.Command, args []string) error {
+	if lintFlags.noBuild {
+		fmt.Fprintf(os.Stderr, "🔎 Linting %s\n", lintFlags.dockerfilePath)
+	} else {
+		fmt.Fprintf(os.Stderr, "🔎 Building and linting %s\n", lintFlags.dockerfilePath)
+	}
+
+	result, err := analyzer.LintWorkingTree(context.Background(), analyzer.LintConfig{
+		RepoPath:       lintFlags.repoPath,
+		DockerfilePath: lintFlags.dockerfilePath,
+		Build:          !lintFlags.noBuild,
+		Verbose:        verbose,
+	})
+	if err != nil {
+		return fmt.Errorf("lint failed: %w", err)
+	}
+
+	switch lintFlags.format {
+	case "table":
+		writeLintTable(os.Stdout, result)
+	case "markdown":
+		section := lintSection(result)
+		section.WriteMarkdown(os.Stdout)
+		if len(result.Issues) == 0 {
+			fmt.Fprintln(os.Stdout, "\n✅ No issues found")
+		}
+	case "json":
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(result)
+	default:
+		return fmt.Errorf("unsupported format: %s", lintFlags.format)
+	}
+
+	return nil
+}
+
+func writeLintTable(w io.Writer, result *analyzer.LintResult) {
+	if len(result.Issues) == 0 {
+		fmt.Fprintln(w, "\n✅ No issues found")
+		return
+	}
+
+	section := lintSection(result)
+	section.WriteTable(w)
+
+	if result.Measured {
+		// Several issues can affect the same layer, count each layer once
+		var total int64
+		counted := make(map[int]bool)
+		for _, issue := range result.Issues {
+			if issue.Cost > 0 && !counted[issue.StartLine] {
+				counted[issue.StartLine] = true
+				total += issue.Cost
+			}
+		}
+		fmt.Fprintf(w, "\nImage size: %s, layers affected by issues: %s\n",
+			report.FormatBytes(result.ImageSize), report.FormatBytes(total))
+	}
+}
+
+// lintSection renders the issues of the linted Dockerfile as a report section
+func lintSection(result *analyzer.LintResult) report.Section {
+	section := lint.Section(result.Dockerfile, result.Issues)
+	if !result.Measured {
+		note := "Not built: costs are unknown, issues are listed in file order."
+		if section.Note != "" {
+			note += " " + section.Note
+		}
+		section.Note = note
+	}
+	return section
+}
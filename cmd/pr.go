@@ -0,0 +1,99 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/jtodic/docker-time-machine/pkg/analyzer"
+	"github.com/spf13/cobra"
+)
+
+var prFlags struct {
+	repoPath       string
+	dockerfilePath string
+	base           string
+	head           string
+	output         string
+	jsonOutput     string
+}
+
+var prCmd = &cobra.Command{
+	Use:   "pr",
+	Short: "Compare a pull request's head against its merge-base",
+	Long: `Answer "how much does this pull request change the image" in one step.
+
+The command resolves the merge-base of --base and --head, builds both from git
+objects (the working tree is not touched) and writes a compact Markdown
+summary suitable for a pull request comment. It includes per-layer size
+deltas and the Dockerfile lines that changed. A JSON artifact with the full
+result can be written alongside.`,
+	Example: `  # Summarize the current branch against main
+  dtm pr --base main --head HEAD
+
+  # Write the comment body and a JSON artifact in CI
+  dtm pr --base origin/main --head HEAD --output pr-comment.md --json pr-impact.json`,
+	Args: cobra.NoArgs,
+	RunE: runPR,
+}
+
+func init() {
+	rootCmd.AddCommand(prCmd)
+
+	prCmd.Flags().StringVarP(&prFlags.repoPath, "repo", "r", ".", "Path to git repository")
+	prCmd.Flags().StringVarP(&prFlags.dockerfilePath, "dockerfile", "d", "Dockerfile", "Path to Dockerfile relative to repo root")
+	prCmd.Flags().StringVar(&prFlags.base, "base", "main", "Base branch the pull request targets")
+	prCmd.Flags().StringVar(&prFlags.head, "head", "HEAD", "Head revision of the pull request")
+	prCmd.Flags().StringVarP(&prFlags.output, "output", "o", "", "Markdown output file path (default: stdout)")
+	prCmd.Flags().StringVar(&prFlags.jsonOutput, "json", "", "Also write the full result as JSON to this path")
+}
+
+func runPR(cmd *cobra.Command, args []string) error {
+	comparer, err := analyzer.NewComparer(analyzer.ComparerConfig{
+		RepoPath:       prFlags.repoPath,
+		DockerfilePath: prFlags.dockerfilePath,
+		Verbose:        verbose,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create comparer: %w", err)
+	}
+
+	fmt.Fprintf(os.Stderr, "🔍 Comparing %s against merge-base with %s\n", prFlags.head, prFlags.base)
+
+	result, err := comparer.ComparePR(context.Background(), prFlags.base, prFlags.head)
+	if err != nil {
+		return fmt.Errorf("comparison failed: %w", err)
+	}
+
+	output := os.Stdout
+	if prFlags.output != "" {
+		output, err = os.Create(prFlags.output)
+		if err != nil {
+			return fmt.Errorf("failed to create output file: %w", err)
+		}
+		defer output.Close()
+	}
+
+	if err := result.WriteMarkdown(output); err != nil {
+		return fmt.Errorf("failed to generate report: %w", err)
+	}
+
+	if prFlags.jsonOutput != "" {
+		jsonFile, err := os.Create(prFlags.jsonOutput)
+		if err != nil {
+			return fmt.Errorf("failed to create JSON file: %w", err)
+		}
+		defer jsonFile.Close()
+
+		if err := result.WriteJSON(jsonFile); err != nil {
+			return fmt.Errorf("failed to write JSON: %w", err)
+		}
+		fmt.Fprintf(os.Stderr, "✅ JSON saved to: %s\n", prFlags.jsonOutput)
+	}
+
+	if prFlags.output != "" {
+		fmt.Fprintf(os.Stderr, "✅ Report saved to: %s\n", prFlags.output)
+	}
+
+	return nil
+}
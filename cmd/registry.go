@@ -3,6 +3,7 @@ package cmd
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"os"
@@ -10,7 +11,14 @@ import (
 	"strings"
 	"time"
 
+	"github.com/jtodic/docker-time-machine/pkg/base"
 	"github.com/jtodic/docker-time-machine/pkg/docker"
+	"github.com/jtodic/docker-time-machine/pkg/imgconfig"
+	"github.com/jtodic/docker-time-machine/pkg/inspect"
+	"github.com/jtodic/docker-time-machine/pkg/layerid"
+	"github.com/jtodic/docker-time-machine/pkg/pull"
+	"github.com/jtodic/docker-time-machine/pkg/report"
+	"github.com/jtodic/docker-time-machine/pkg/scan"
 	"github.com/olekukonko/tablewriter"
 	"github.com/schollz/progressbar/v3"
 	"github.com/spf13/cobra"
@@ -24,6 +32,25 @@ var registryFlags struct {
 	format   string
 	output   string
 	platform string
+	files    bool
+	waste    bool
+	packages bool
+	deps     bool
+	junk     bool
+
+	sizeBasis string
+	baseImage string
+	sbom      string
+	vulnDB    string
+
+	licenses     bool
+	denyLicenses []string
+	binaries     bool
+	secrets      bool
+	duplicates   bool
+	dirs         bool
+	dirDepth     int
+	treemap      bool
 }
 
 // RegistryResult holds analysis results for a registry image
@@ -37,6 +64,22 @@ type RegistryResult struct {
 	Layers     []LayerInfo `json:"layers,omitempty"`
 	SizeDiff   int64       `json:"size_diff,omitempty"`
 	Error      string      `json:"error,omitempty"`
+
+	// Size follows the size basis; both sizes are kept when known
+	CompressedSize   int64 `json:"compressed_size,omitempty"`
+	UncompressedSize int64 `json:"uncompressed_size,omitempty"`
+
+	// Base splits the image size into base image and application layers
+	Base *base.Split `json:"base,omitempty"`
+
+	// PullCost holds the bytes to download when upgrading from the previous tag
+	PullCost *pull.Cost `json:"pull_cost,omitempty"`
+
+	// Config holds the user, entrypoint, env and other runtime settings
+	Config *imgconfig.Config `json:"config,omitempty"`
+
+	// Result holds the layer contents findings when scanning is enabled
+	*scan.Result
 }
 
 // LayerInfo represents a single layer
@@ -45,10 +88,15 @@ type LayerInfo struct {
 	CreatedBy string  `json:"created_by"`
 	Size      int64   `json:"size"`
 	SizeMB    float64 `json:"size_mb"`
+	Key       string  `json:"key"` // identity used to match the layer across tags
+
+	CompressedSize   int64 `json:"compressed_size,omitempty"`
+	UncompressedSize int64 `json:"uncompressed_size,omitempty"`
 }
 
 // RegistryLayerComparison represents layer sizes across tags
 type RegistryLayerComparison struct {
+	LayerKey     string             `json:"layer_key"`
 	LayerCommand string             `json:"layer_command"`
 	SizeByTag    map[string]float64 `json:"size_by_tag"`
 }
@@ -59,6 +107,10 @@ type RegistryInsights struct {
 	BloatSizeDiff    float64 `json:"bloat_size_diff_mb,omitempty"`
 	OptimizationTag  string  `json:"optimization_tag,omitempty"`
 	OptimizationDiff float64 `json:"optimization_size_diff_mb,omitempty"`
+
+	// BloatUpstreamDiff is the part of the increase caused by a base image
+	// update, set when the base image changed in the bloat tag
+	BloatUpstreamDiff *float64 `json:"bloat_upstream_size_diff_mb,omitempty"`
 }
 
 var registryCmd = &cobra.Command{
@@ -83,6 +135,83 @@ Supported registries:
   - Azure ACR (*.azurecr.io)
   - Any OCI-compliant registry
 
+With --files, the layer blobs of every tag are downloaded and read to record
+the files each layer adds, modifies and deletes, and the report lists the files
+and directories that grew the most between consecutive tags. This downloads
+the full images and is much slower than the default metadata-only mode.
+
+With --waste, the downloaded layers are also checked for files that a layer
+writes and a later layer overwrites or deletes. The report tracks wasted bytes
+and an efficiency score per tag.
+
+With --packages, the dpkg, apk and rpm databases are read from the downloaded
+layers and the report lists the OS packages added, removed or changed between
+consecutive tags with their installed sizes.
+
+With --deps, npm packages, Python distributions and Go modules are detected in
+the downloaded layers and the report lists the dependencies added, removed,
+upgraded or grown between consecutive tags.
+
+With --junk, the downloaded layers are checked for package manager caches,
+.git directories, tests, compiler toolchains and other leftovers, and the
+report shows the reclaimable bytes per tag with a suggested fix.
+
+With --sbom spdx or --sbom cyclonedx, an SBOM listing the OS packages and
+language dependencies found in the downloaded layers is written next to the
+report, one file per tag, and the report lists the components added or removed
+between consecutive tags.
+
+With --vuln-db, the OS packages and language dependencies found in the
+downloaded layers are matched against a local OSV advisory database (a
+directory, .zip or .tar.gz of OSV JSON files) without network access to any
+vulnerability service. The report lists the counts by severity per tag and the
+vulnerabilities introduced or fixed by each tag.
+
+With --licenses, the licenses of the OS packages and of the npm and Python
+dependencies are recorded per tag and the report lists the licenses each tag
+added or removed. --deny-licenses AGPL,SSPL reports where a denied license
+first appeared and makes dtm exit with a nonzero status.
+
+With --binaries, the ELF executables in the downloaded layers are inspected
+for architecture, static linking, stripping, debug info and Go build info, and
+the report tracks their sizes across tags with the executables each tag added,
+removed or changed.
+
+With --scan-secrets, the files of every downloaded layer are checked for
+private keys, tokens and high entropy passwords, including files deleted by a
+later layer. Each secret is reported with the layer, instruction and tag where
+it first appeared, and dtm exits with a nonzero status when any is found.
+
+With --duplicates, the files of every downloaded layer are hashed and the
+report lists the contents stored more than once, with the bytes they waste per
+tag and the instructions that wrote each copy.
+
+With --dirs, the final filesystem of each tag is summed up by directory
+(--dir-depth path components deep) and the report and HTML chart show the
+largest directories over time.
+
+With --treemap, the HTML report embeds a zoomable treemap of the final
+filesystem of each tag, with a mode that colors the paths that are new or grew
+since the previous tag.
+
+Both sizes are tracked for every tag and layer: the compressed size as stored
+in the registry, and the uncompressed size on disk, measured by downloading
+every layer blob once and summing the bytes of its files as Docker does. Sizes
+are compressed by default; --size-basis uncompressed makes the numbers
+comparable with 'dtm analyze'. The selected basis drives diffs, charts and
+insights; JSON output keeps both sizes.
+
+Every report also compares the layer digests of consecutive tags and lists the
+bytes a host must download to upgrade from the previous tag, the bytes shared
+with it and the bytes unique to each tag.
+
+With --base-image, or when a tag's manifest carries the OCI base image
+annotations, the base image manifest is resolved and its layer digests are
+matched against the lowest layers of each tag to split the size into base
+image and application layers, charted as two series. Tags whose base image
+changed are flagged with the base image's own size delta, separating upstream
+growth from your changes.
+
 Authentication uses your existing Docker credentials (~/.docker/config.json).
 Run 'docker login <registry>' first if needed.`,
 	Example: `  # Analyze last 10 tags
@@ -95,7 +224,52 @@ Run 'docker login <registry>' first if needed.`,
   dtm registry node --last 15 --format chart
 
   # Specify platform for multi-arch images
-  dtm registry nginx --last 5 --platform linux/amd64`,
+  dtm registry nginx --last 5 --platform linux/amd64
+
+  # Show which files made the image grow between tags
+  dtm registry mycompany/api --last 3 --files
+
+  # Find files that are deleted or overwritten in later layers
+  dtm registry mycompany/api --last 3 --waste
+
+  # List the OS packages added, removed or upgraded between tags
+  dtm registry mycompany/api --last 3 --packages
+
+  # List the npm, Python and Go dependencies that were added or grew
+  dtm registry mycompany/api --last 3 --deps
+
+  # Find caches and build leftovers
+  dtm registry mycompany/api --last 3 --junk
+
+  # Write an SPDX SBOM per tag and list component changes
+  dtm registry mycompany/api --last 3 --sbom spdx
+
+  # Track vulnerabilities across tags with a local OSV export
+  dtm registry mycompany/api --last 5 --vuln-db ~/osv/Alpine.zip
+
+  # Fail when an AGPL licensed component shows up in a tag
+  dtm registry mycompany/api --last 5 --deny-licenses AGPL
+
+  # Track the size and build properties of executables across tags
+  dtm registry mycompany/api --last 5 --binaries
+
+  # Fail when a key or token is shipped in any layer of the last tags
+  dtm registry mycompany/api --last 5 --scan-secrets
+
+  # Find artifacts stored in more than one layer
+  dtm registry mycompany/api --last 5 --duplicates
+
+  # Chart the largest directories across tags
+  dtm registry mycompany/api --last 10 --dirs --format chart
+
+  # Explore what takes up space in each tag
+  dtm registry mycompany/api --last 5 --treemap --format chart
+
+  # Split each tag into node:20 layers and application layers
+  dtm registry mycompany/api --last 5 --base-image node:20
+
+  # Report uncompressed sizes, comparable with dtm analyze
+  dtm registry mycompany/api --last 3 --size-basis uncompressed`,
 	Args: cobra.ExactArgs(1),
 	RunE: runRegistry,
 }
@@ -110,11 +284,40 @@ func init() {
 	registryCmd.Flags().StringVarP(&registryFlags.format, "format", "f", "table", "Output format: table, json, csv, chart, markdown")
 	registryCmd.Flags().StringVarP(&registryFlags.output, "output", "o", "", "Output file path")
 	registryCmd.Flags().StringVar(&registryFlags.platform, "platform", "", "Platform for multi-arch images (e.g., linux/amd64)")
+	registryCmd.Flags().BoolVar(&registryFlags.files, "files", false, "Download layers and report the top growing files and directories")
+	registryCmd.Flags().BoolVar(&registryFlags.waste, "waste", false, "Download layers and report space wasted by files overwritten or deleted in later layers")
+	registryCmd.Flags().BoolVar(&registryFlags.packages, "packages", false, "Download layers and report OS package changes between tags")
+	registryCmd.Flags().BoolVar(&registryFlags.deps, "deps", false, "Download layers and report npm, Python and Go dependency changes between tags")
+	registryCmd.Flags().BoolVar(&registryFlags.junk, "junk", false, "Download layers and report leftover caches and build files")
+	registryCmd.Flags().StringVar(&registryFlags.sbom, "sbom", "", "Download layers, write an SBOM per tag next to the report and list component changes: spdx, cyclonedx")
+	registryCmd.Flags().StringVar(&registryFlags.vulnDB, "vuln-db", "", "Download layers and match packages against a local OSV advisory database (directory, .zip or .tar.gz)")
+	registryCmd.Flags().BoolVar(&registryFlags.licenses, "licenses", false, "Download layers and report the licenses added or removed between tags")
+	registryCmd.Flags().StringSliceVar(&registryFlags.denyLicenses, "deny-licenses", nil, "Fail when one of these licenses appears, e.g. AGPL,SSPL (implies --licenses)")
+	registryCmd.Flags().BoolVar(&registryFlags.binaries, "binaries", false, "Download layers and report ELF executables, their build properties and size per tag")
+	registryCmd.Flags().BoolVar(&registryFlags.secrets, "scan-secrets", false, "Download layers and check them for keys, tokens and passwords, including deleted files; fail when any is found")
+	registryCmd.Flags().BoolVar(&registryFlags.duplicates, "duplicates", false, "Download layers and report files whose content is stored in more than one place")
+	registryCmd.Flags().BoolVar(&registryFlags.dirs, "dirs", false, "Download layers and report the size of the largest directories per tag")
+	registryCmd.Flags().IntVar(&registryFlags.dirDepth, "dir-depth", inspect.DefaultDirDepth, "Path components directories are grouped by with --dirs, e.g. 2 for /usr/lib")
+	registryCmd.Flags().BoolVar(&registryFlags.treemap, "treemap", false, "Download layers and embed a zoomable treemap of each tag in the HTML report")
+	registryCmd.Flags().StringVar(&registryFlags.sizeBasis, "size-basis", "compressed", "Sizes that drive diffs, charts and insights: compressed, uncompressed")
+	registryCmd.Flags().StringVar(&registryFlags.baseImage, "base-image", "", "Base image to split sizes against, e.g. node:20 (default: the OCI base image annotation of each tag)")
 }
 
 func runRegistry(cmd *cobra.Command, args []string) error {
 	imageName := args[0]
 
+	if _, err := report.ParseSizeBasis(registryFlags.sizeBasis, report.Compressed); err != nil {
+		return err
+	}
+	if err := validateSBOMFormat(registryFlags.sbom); err != nil {
+		return err
+	}
+	db, err := loadVulnDB(registryFlags.vulnDB)
+	if err != nil {
+		return err
+	}
+	registryVulnDB = db
+
 	ctx := context.Background()
 
 	regClient := docker.NewRegistryClient()
@@ -139,10 +342,11 @@ func runRegistry(cmd *cobra.Command, args []string) error {
 
 	var results []RegistryResult
 	var errorCount int
+	cache := newRegistryCache()
 	for _, tag := range tags {
 		bar.Add(1)
 
-		result := analyzeRegistryImageMetadata(ctx, regClient, imageName, tag)
+		result := analyzeRegistryImageMetadata(ctx, regClient, cache, imageName, tag)
 
 		if result.Error != "" {
 			errorCount++
@@ -199,7 +403,95 @@ func runRegistry(cmd *cobra.Command, args []string) error {
 		}
 	}
 
-	return generateRegistryReport(results, imageName)
+	var validResults []RegistryResult
+	for _, r := range results {
+		if r.Error == "" {
+			validResults = append(validResults, r)
+		}
+	}
+	matchRegistryLayers(validResults)
+	base.Diff(registryBaseVersions(validResults))
+	computeRegistryPullCosts(results)
+
+	if err := generateRegistryReport(results, imageName); err != nil {
+		return err
+	}
+
+	if registryFlags.sbom != "" {
+		if err := writeSBOMs(registryFlags.output, registryFlags.sbom, registrySBOMs(results, imageName)); err != nil {
+			return err
+		}
+	}
+
+	var failures []error
+	if violations := scan.LicenseViolations(registryScanOptions(), registryScanVersions(validResults)); len(violations) > 0 {
+		failures = append(failures, licensePolicyError(violations))
+	}
+	if leaks := scan.SecretLeaks(registryScanOptions(), registryScanVersions(validResults)); len(leaks) > 0 {
+		failures = append(failures, secretsError(leaks))
+	}
+	if len(failures) > 0 {
+		cmd.SilenceUsage = true
+		return errors.Join(failures...)
+	}
+	return nil
+}
+
+// computeRegistryPullCosts compares the layer digests of consecutive tags
+func computeRegistryPullCosts(results []RegistryResult) {
+	var indexes []int
+	for i := range results {
+		if results[i].Error == "" {
+			indexes = append(indexes, i)
+		}
+	}
+
+	versions := make([]pull.Version, len(indexes))
+	for k, i := range indexes {
+		versions[k] = registryPullVersion(results[i])
+	}
+	for k, cost := range pull.Compute(versions) {
+		results[indexes[k]].PullCost = &cost
+	}
+}
+
+// registryPullVersion returns the layer blobs of a tag
+func registryPullVersion(r RegistryResult) pull.Version {
+	version := pull.Version{Name: r.Tag}
+	for _, layer := range r.Layers {
+		version.Layers = append(version.Layers, pull.Layer{Digest: layer.Digest, Size: layer.Size})
+	}
+	return version
+}
+
+// registryConfigVersions pairs each tag with its image config
+func registryConfigVersions(validResults []RegistryResult) []imgconfig.Version {
+	versions := make([]imgconfig.Version, 0, len(validResults))
+	for _, r := range validResults {
+		versions = append(versions, imgconfig.Version{Name: r.Tag, Config: r.Config})
+	}
+	return versions
+}
+
+// registrySections returns the optional report sections for the enabled
+// features
+func registrySections(validResults []RegistryResult) []report.Section {
+	var sections []report.Section
+	if len(validResults) > 1 {
+		versions := make([]pull.Version, 0, len(validResults))
+		costs := make([]pull.Cost, 0, len(validResults))
+		for _, r := range validResults {
+			if r.PullCost == nil {
+				continue
+			}
+			versions = append(versions, registryPullVersion(r))
+			costs = append(costs, *r.PullCost)
+		}
+		sections = append(sections, pull.Section(versions, costs))
+	}
+	sections = append(sections, base.Section(registryBaseVersions(validResults)))
+	sections = append(sections, imgconfig.Section(imgconfig.CompareVersions(registryConfigVersions(validResults))))
+	return append(sections, scan.Sections(registryScanOptions(), registryScanVersions(validResults))...)
 }
 
 func getTagsToAnalyze(ctx context.Context, regClient *docker.RegistryClient, imageName string) ([]string, error) {
@@ -229,7 +521,7 @@ func getTagsToAnalyze(ctx context.Context, regClient *docker.RegistryClient, ima
 }
 
 // analyzeRegistryImageMetadata fetches image metadata from registry without pulling full image
-func analyzeRegistryImageMetadata(ctx context.Context, regClient *docker.RegistryClient, imageName, tag string) RegistryResult {
+func analyzeRegistryImageMetadata(ctx context.Context, regClient *docker.RegistryClient, cache *registryCache, imageName, tag string) RegistryResult {
 	result := RegistryResult{
 		Tag: tag,
 	}
@@ -243,7 +535,9 @@ func analyzeRegistryImageMetadata(ctx context.Context, regClient *docker.Registr
 	result.Digest = metadata.Digest
 	result.Size = metadata.Size
 	result.SizeMB = float64(metadata.Size) / 1024 / 1024
+	result.CompressedSize = metadata.Size
 	result.Created = metadata.Created
+	result.Config = imgconfig.FromImage(metadata.Config)
 	result.LayerCount = metadata.LayerCount
 
 	// Convert layer metadata to LayerInfo
@@ -253,66 +547,112 @@ func analyzeRegistryImageMetadata(ctx context.Context, regClient *docker.Registr
 			CreatedBy: layer.CreatedBy,
 			Size:      layer.Size,
 			SizeMB:    layer.SizeMB,
+
+			CompressedSize: layer.Size,
 		}
 		result.Layers = append(result.Layers, info)
 	}
 
-	return result
-}
+	// Both sizes are always tracked; the size basis only selects the one
+	// that drives diffs
+	if err := measureUncompressed(ctx, regClient, cache.layerSizes, imageName, metadata, &result); err != nil {
+		if registrySizeBasis() == report.Uncompressed {
+			result.Error = fmt.Sprintf("failed to measure uncompressed size: %v", err)
+			return result
+		}
+		if verbose {
+			fmt.Fprintf(os.Stderr, "\n  ⚠️ %s: failed to measure uncompressed size: %v\n", tag, err)
+		}
+	}
 
-func buildRegistryLayerComparison(validResults []RegistryResult) ([]string, []RegistryLayerComparison) {
-	layerCommands := make([]string, 0)
-	layerCommandSet := make(map[string]bool)
+	if ref := registryBaseReference(metadata); ref != "" {
+		split, err := splitRegistryBase(ctx, regClient, cache, ref, metadata, &result)
+		if err != nil {
+			if verbose {
+				fmt.Fprintf(os.Stderr, "\n  ⚠️ %s: %v\n", tag, err)
+			}
+		} else {
+			result.Base = split
+		}
+	}
 
-	// Handle empty input
-	if len(validResults) == 0 {
-		return layerCommands, []RegistryLayerComparison{}
+	if opts := registryScanOptions(); opts.Enabled() {
+		scanned, err := scanRegistryImage(ctx, regClient, imageName, metadata, opts)
+		if err != nil {
+			if verbose {
+				fmt.Fprintf(os.Stderr, "\n  ⚠️ %s: layer scan failed: %v\n", tag, err)
+			}
+		} else {
+			result.Result = scanned
+		}
 	}
 
-	// Collect unique layer commands from first result
-	for _, layer := range validResults[0].Layers {
-		if !layerCommandSet[layer.CreatedBy] {
-			layerCommands = append(layerCommands, layer.CreatedBy)
-			layerCommandSet[layer.CreatedBy] = true
+	return result
+}
+
+// matchRegistryLayers sets the Key of every layer so that the same logical
+// layer has the same key in all tags, even when a version bump changed its
+// command (see layerid.Assign). The base image layers form their own stage
+// when the base image is known.
+func matchRegistryLayers(validResults []RegistryResult) {
+	versions := make([][]layerid.Layer, len(validResults))
+	for v, result := range validResults {
+		for i, layer := range result.Layers {
+			stage := ""
+			if result.Base != nil && i < result.Base.Layers {
+				stage = "base"
+			}
+			versions[v] = append(versions[v], layerid.Layer{
+				Digest:  layer.Digest,
+				Command: layer.CreatedBy,
+				Stage:   stage,
+			})
+		}
+	}
+	for v, keys := range layerid.Assign(versions) {
+		for i, key := range keys {
+			validResults[v].Layers[i].Key = key
 		}
 	}
+}
 
-	// Collect from remaining results
-	if len(validResults) > 1 {
-		for _, result := range validResults[1:] {
-			for _, layer := range result.Layers {
-				if !layerCommandSet[layer.CreatedBy] {
-					layerCommands = append(layerCommands, layer.CreatedBy)
-					layerCommandSet[layer.CreatedBy] = true
-				}
+// buildRegistryLayerComparison builds layer comparison data across tags.
+// Layers are matched by their Key, so a layer keeps its row when its command
+// changes between tags.
+func buildRegistryLayerComparison(validResults []RegistryResult) ([]string, []RegistryLayerComparison) {
+	// Collect all unique layer keys, in the order they first appear from the
+	// newest tag on, labeled with their newest command
+	layerKeys := make([]string, 0)
+	layerLabels := make(map[string]string)
+	for _, result := range validResults {
+		for _, layer := range result.Layers {
+			if _, ok := layerLabels[layer.Key]; !ok {
+				layerKeys = append(layerKeys, layer.Key)
+				layerLabels[layer.Key] = layer.CreatedBy
 			}
 		}
 	}
 
-	comparisons := make([]RegistryLayerComparison, 0, len(layerCommands))
-	for _, cmd := range layerCommands {
+	layerCommands := make([]string, 0, len(layerKeys))
+	comparisons := make([]RegistryLayerComparison, 0, len(layerKeys))
+	for _, key := range layerKeys {
 		comparison := RegistryLayerComparison{
-			LayerCommand: cmd,
+			LayerKey:     key,
+			LayerCommand: layerLabels[key],
 			SizeByTag:    make(map[string]float64),
 		}
 
 		for _, result := range validResults {
-			// Sum all layers with this command (handles duplicate commands)
-			var totalSize float64
-			found := false
+			comparison.SizeByTag[result.Tag] = -1 // -1 indicates not present
 			for _, layer := range result.Layers {
-				if layer.CreatedBy == cmd {
-					totalSize += layer.SizeMB
-					found = true
+				if layer.Key == key {
+					comparison.SizeByTag[result.Tag] = layer.SizeMB
+					break
 				}
 			}
-			if found {
-				comparison.SizeByTag[result.Tag] = totalSize
-			} else {
-				comparison.SizeByTag[result.Tag] = -1
-			}
 		}
 
+		layerCommands = append(layerCommands, comparison.LayerCommand)
 		comparisons = append(comparisons, comparison)
 	}
 
@@ -329,6 +669,11 @@ func findInsights(validResults []RegistryResult) RegistryInsights {
 			maxIncrease = r.SizeDiff
 			insights.BloatTag = r.Tag
 			insights.BloatSizeDiff = float64(r.SizeDiff) / 1024 / 1024
+			insights.BloatUpstreamDiff = nil
+			if r.Base != nil && r.Base.Changed {
+				upstream := float64(r.Base.BaseDiff) / 1024 / 1024
+				insights.BloatUpstreamDiff = &upstream
+			}
 		}
 		if r.Error == "" && r.SizeDiff < maxDecrease {
 			maxDecrease = r.SizeDiff
@@ -382,6 +727,7 @@ func generateRegistryReport(results []RegistryResult, imageName string) error {
 func generateRegistryTableReport(w io.Writer, results []RegistryResult, imageName string) error {
 	fmt.Fprintf(w, "\n📊 Registry Image Analysis: %s\n", imageName)
 	fmt.Fprintln(w, "==========================================")
+	fmt.Fprintln(w, registrySizeBasis().Description())
 
 	table := tablewriter.NewWriter(w)
 	table.SetHeader([]string{"Tag", "Date", "Size (MB)", "Diff", "Layers"})
@@ -431,6 +777,9 @@ func generateRegistryTableReport(w io.Writer, results []RegistryResult, imageNam
 	if insights.BloatTag != "" && insights.BloatSizeDiff > 0 {
 		fmt.Fprintf(w, "\n⚠️  Biggest size increase: %s\n", insights.BloatTag)
 		fmt.Fprintf(w, "   Size increased by: %.2f MB\n", insights.BloatSizeDiff)
+		if insights.BloatUpstreamDiff != nil {
+			fmt.Fprintf(w, "   Base image changed: %+.2f MB upstream\n", *insights.BloatUpstreamDiff)
+		}
 	}
 
 	if insights.OptimizationTag != "" && insights.OptimizationDiff > 0 {
@@ -440,7 +789,7 @@ func generateRegistryTableReport(w io.Writer, results []RegistryResult, imageNam
 
 	// Print layer comparison across tags
 	if len(validResults) > 0 {
-		layerCommands, _ := buildRegistryLayerComparison(validResults)
+		layerCommands, comparisons := buildRegistryLayerComparison(validResults)
 
 		if len(layerCommands) > 0 {
 			fmt.Fprintln(w, "\n📦 Layer Size Comparison Across Tags:")
@@ -459,20 +808,15 @@ func generateRegistryTableReport(w io.Writer, results []RegistryResult, imageNam
 			layerTable.SetHeaderAlignment(tablewriter.ALIGN_LEFT)
 			layerTable.SetAlignment(tablewriter.ALIGN_LEFT)
 
-			for _, cmd := range layerCommands {
+			for i, cmd := range layerCommands {
 				row := []string{truncate(cmd, 40)}
 
 				for _, result := range validResults {
-					found := false
-					for _, layer := range result.Layers {
-						if layer.CreatedBy == cmd {
-							row = append(row, fmt.Sprintf("%.2f", layer.SizeMB))
-							found = true
-							break
-						}
-					}
-					if !found {
+					size := comparisons[i].SizeByTag[result.Tag]
+					if size < 0 {
 						row = append(row, "-")
+					} else {
+						row = append(row, fmt.Sprintf("%.2f", size))
 					}
 				}
 
@@ -483,6 +827,10 @@ func generateRegistryTableReport(w io.Writer, results []RegistryResult, imageNam
 		}
 	}
 
+	for _, section := range registrySections(validResults) {
+		section.WriteTable(w)
+	}
+
 	return nil
 }
 
@@ -494,6 +842,10 @@ type RegistryJSONReport struct {
 	Results         []RegistryResult          `json:"results"`
 	LayerComparison []RegistryLayerComparison `json:"layer_comparison"`
 	TagOrder        []string                  `json:"tag_order"`
+	SizeBasis       report.SizeBasis          `json:"size_basis"`
+	ConfigDiffs     []imgconfig.VersionDiff   `json:"config_diffs,omitempty"`
+
+	scan.Diffs
 }
 
 // RegistrySummary holds summary statistics
@@ -541,6 +893,11 @@ func generateRegistryJSONReport(w io.Writer, results []RegistryResult, imageName
 		Results:         results,
 		LayerComparison: comparisons,
 		TagOrder:        tagOrder,
+		SizeBasis:       registrySizeBasis(),
+		ConfigDiffs:     imgconfig.CompareVersions(registryConfigVersions(validResults)),
+	}
+	if opts := registryScanOptions(); opts.Enabled() {
+		report.Diffs = scan.Compare(opts, registryScanVersions(validResults))
 	}
 
 	encoder := json.NewEncoder(w)
@@ -558,6 +915,7 @@ func generateRegistryCSVReport(w io.Writer, results []RegistryResult, imageName
 
 	// Part 1: Summary
 	fmt.Fprintf(w, "# Registry Image Analysis: %s\n", imageName)
+	fmt.Fprintf(w, "# %s\n", registrySizeBasis().Description())
 	fmt.Fprintln(w)
 
 	// Part 2: Main results
@@ -625,6 +983,10 @@ func generateRegistryCSVReport(w io.Writer, results []RegistryResult, imageName
 		fmt.Fprintln(w, strings.Join(row, ","))
 	}
 
+	for _, section := range registrySections(validResults) {
+		section.WriteCSV(w)
+	}
+
 	return nil
 }
 
@@ -640,6 +1002,8 @@ func generateRegistryMarkdownReport(w io.Writer, results []RegistryResult, image
 
 	// Summary section
 	fmt.Fprintln(w, "## Summary")
+	fmt.Fprintln(w, registrySizeBasis().Description())
+	fmt.Fprintln(w)
 	fmt.Fprintf(w, "- **Tags analyzed:** %d\n", len(validResults))
 
 	if len(validResults) > 0 {
@@ -658,6 +1022,9 @@ func generateRegistryMarkdownReport(w io.Writer, results []RegistryResult, image
 		fmt.Fprintln(w, "## Insights")
 		if insights.BloatTag != "" && insights.BloatSizeDiff > 0 {
 			fmt.Fprintf(w, "- ⚠️ **Biggest size increase:** %s (+%.2f MB)\n", insights.BloatTag, insights.BloatSizeDiff)
+			if insights.BloatUpstreamDiff != nil {
+				fmt.Fprintf(w, "  - Base image changed: %+.2f MB upstream\n", *insights.BloatUpstreamDiff)
+			}
 		}
 		if insights.OptimizationTag != "" && insights.OptimizationDiff > 0 {
 			fmt.Fprintf(w, "- ✅ **Biggest size reduction:** %s (-%.2f MB)\n", insights.OptimizationTag, insights.OptimizationDiff)
@@ -722,6 +1089,10 @@ func generateRegistryMarkdownReport(w io.Writer, results []RegistryResult, image
 		}
 	}
 
+	for _, section := range registrySections(validResults) {
+		section.WriteMarkdown(w)
+	}
+
 	return nil
 }
 
@@ -735,10 +1106,16 @@ func generateRegistryChartReport(w io.Writer, results []RegistryResult, imageNam
 
 	var labels []string
 	var sizeData []float64
+	var downloadData []float64
 
 	for _, r := range validResults {
 		labels = append(labels, r.Tag)
 		sizeData = append(sizeData, r.SizeMB)
+		if r.PullCost != nil {
+			downloadData = append(downloadData, float64(r.PullCost.Download)/1024/1024)
+		} else {
+			downloadData = append(downloadData, 0)
+		}
 	}
 
 	layerCommands, comparisons := buildRegistryLayerComparison(validResults)
@@ -802,6 +1179,7 @@ func generateRegistryChartReport(w io.Writer, results []RegistryResult, imageNam
 
 	labelsJSON, _ := json.Marshal(labels)
 	sizeJSON, _ := json.Marshal(sizeData)
+	downloadJSON, _ := json.Marshal(downloadData)
 
 	// Build insights HTML
 	insightsHTML := ""
@@ -831,6 +1209,11 @@ func generateRegistryChartReport(w io.Writer, results []RegistryResult, imageNam
         </div>`, len(validResults), first.Tag, first.SizeMB, last.Tag, last.SizeMB, changeSign, change)
 	}
 
+	var sectionsHTML strings.Builder
+	for _, section := range registrySections(validResults) {
+		sectionsHTML.WriteString(section.HTML())
+	}
+
 	html := fmt.Sprintf(`<!DOCTYPE html>
 <html>
 <head>
@@ -941,10 +1324,12 @@ func generateRegistryChartReport(w io.Writer, results []RegistryResult, imageNam
         .layer-table tr:hover td:first-child:hover { background: #ffffcc; }
         .size-cell { text-align: right; font-family: 'Monaco', 'Menlo', monospace; }
         .size-cell.missing { color: #999; }
+        .size-basis { color: #666; }
     </style>
 </head>
 <body>
     <h1>🐳 Registry Image Analysis: %s</h1>
+    <p class="size-basis">%s</p>
     %s
     %s
     
@@ -952,6 +1337,12 @@ func generateRegistryChartReport(w io.Writer, results []RegistryResult, imageNam
         <h2>📈 Image Size Over Tags</h2>
         <canvas id="sizeChart"></canvas>
     </div>
+
+    <div class="chart-container" id="vulnChartContainer" style="display: none">
+        <h2>🛡️ Vulnerabilities Over Tags</h2>
+        <canvas id="vulnChart"></canvas>
+        <p class="note">Matched offline against the local advisory database. Hover over bars to see the vulnerabilities introduced and fixed at each tag.</p>
+    </div>
     
     <div class="chart-container">
         <h2>📊 Image Size by Layer</h2>
@@ -959,6 +1350,12 @@ func generateRegistryChartReport(w io.Writer, results []RegistryResult, imageNam
         <p class="note">Each color represents a different layer. Hover over bars to see layer details.</p>
     </div>
 
+    <div class="chart-container" id="dirChartContainer" style="display: none">
+        <h2>📁 Image Size by Directory</h2>
+        <canvas id="dirChart"></canvas>
+        <p class="note">Size of the final filesystem by directory, including growth inside a single layer. Smaller directories are summed up as other.</p>
+    </div>
+
     <div class="chart-container">
         <h2>📦 Layer Size Comparison Across Tags</h2>
         <p class="note">Scroll horizontally to see all tags. Hover over layer commands to see full text.</p>
@@ -974,31 +1371,67 @@ func generateRegistryChartReport(w io.Writer, results []RegistryResult, imageNam
             </table>
         </div>
     </div>
-
+%s
+%s
     <script>
         const labels = %s;
         const sizeData = %s;
+        const downloadData = %s;
+        const splitData = %s;
+        const vulnData = %s;
+        const dirData = %s;
         const stackedDatasets = %s;
         const layerTableData = %s;
         
+        const sizeDatasets = [{
+            label: 'Image Size (MB)',
+            data: sizeData,
+            borderColor: 'rgb(75, 192, 192)',
+            backgroundColor: 'rgba(75, 192, 192, 0.2)',
+            tension: 0.1,
+            fill: true,
+            pointRadius: 4,
+            pointHoverRadius: 6
+        }, {
+            label: 'Upgrade Download (MB)',
+            data: downloadData,
+            borderColor: 'rgb(255, 159, 64)',
+            backgroundColor: 'rgba(255, 159, 64, 0.2)',
+            tension: 0.1,
+            fill: false,
+            pointRadius: 4,
+            pointHoverRadius: 6
+        }];
+        if (splitData) {
+            sizeDatasets.push({
+                label: 'Base Image (MB)',
+                data: splitData.base,
+                borderColor: 'rgb(153, 102, 255)',
+                backgroundColor: 'rgba(153, 102, 255, 0.2)',
+                tension: 0.1,
+                fill: false,
+                pointRadius: 4,
+                pointHoverRadius: 6
+            }, {
+                label: 'Application (MB)',
+                data: splitData.app,
+                borderColor: 'rgb(255, 99, 132)',
+                backgroundColor: 'rgba(255, 99, 132, 0.2)',
+                tension: 0.1,
+                fill: false,
+                pointRadius: 4,
+                pointHoverRadius: 6
+            });
+        }
         new Chart(document.getElementById('sizeChart'), {
             type: 'line',
             data: {
                 labels: labels,
-                datasets: [{
-                    label: 'Image Size (MB)',
-                    data: sizeData,
-                    borderColor: 'rgb(75, 192, 192)',
-                    backgroundColor: 'rgba(75, 192, 192, 0.2)',
-                    tension: 0.1,
-                    fill: true,
-                    pointRadius: 4,
-                    pointHoverRadius: 6
-                }]
+                datasets: sizeDatasets
             },
             options: {
                 responsive: true,
-                plugins: { legend: { display: false } },
+                plugins: { legend: { display: true } },
                 scales: {
                     y: { beginAtZero: true, title: { display: true, text: 'Size (MB)' } },
                     x: { title: { display: true, text: 'Tag' } }
@@ -1006,6 +1439,55 @@ func generateRegistryChartReport(w io.Writer, results []RegistryResult, imageNam
             }
         });
 
+        if (vulnData) {
+            document.getElementById('vulnChartContainer').style.display = 'block';
+            const severityColors = {
+                CRITICAL: 'rgba(139, 0, 0, 0.8)',
+                HIGH: 'rgba(255, 99, 132, 0.8)',
+                MEDIUM: 'rgba(255, 159, 64, 0.8)',
+                LOW: 'rgba(255, 206, 86, 0.8)',
+                UNKNOWN: 'rgba(199, 199, 199, 0.8)'
+            };
+            const vulnDatasets = Object.keys(severityColors).map(severity => ({
+                type: 'bar',
+                label: severity.charAt(0) + severity.slice(1).toLowerCase(),
+                data: vulnData.counts[severity],
+                backgroundColor: severityColors[severity],
+                stack: 'vulnerabilities',
+                yAxisID: 'y'
+            }));
+            vulnDatasets.push({
+                type: 'line', label: 'Image Size (MB)', data: sizeData,
+                borderColor: 'rgb(75, 192, 192)', backgroundColor: 'rgba(75, 192, 192, 0.2)',
+                tension: 0.1, pointRadius: 4, yAxisID: 'y1'
+            });
+            new Chart(document.getElementById('vulnChart'), {
+                data: { labels: labels, datasets: vulnDatasets },
+                options: {
+                    responsive: true,
+                    plugins: {
+                        legend: { display: true },
+                        tooltip: {
+                            callbacks: {
+                                footer: function(items) {
+                                    const i = items[0].dataIndex;
+                                    const lines = [];
+                                    vulnData.introduced[i].forEach(id => lines.push('+ ' + id));
+                                    vulnData.fixed[i].forEach(id => lines.push('- ' + id));
+                                    return lines;
+                                }
+                            }
+                        }
+                    },
+                    scales: {
+                        x: { stacked: true, title: { display: true, text: 'Tag' } },
+                        y: { stacked: true, beginAtZero: true, title: { display: true, text: 'Vulnerabilities' } },
+                        y1: { position: 'right', beginAtZero: true, grid: { drawOnChartArea: false }, title: { display: true, text: 'Size (MB)' } }
+                    }
+                }
+            });
+        }
+
         new Chart(document.getElementById('stackedLayerChart'), {
             type: 'bar',
             data: {
@@ -1035,6 +1517,48 @@ func generateRegistryChartReport(w io.Writer, results []RegistryResult, imageNam
             }
         });
 
+        if (dirData) {
+            document.getElementById('dirChartContainer').style.display = 'block';
+            const dirColors = [
+                'rgba(75, 192, 192, 0.6)', 'rgba(255, 99, 132, 0.6)', 'rgba(255, 206, 86, 0.6)',
+                'rgba(54, 162, 235, 0.6)', 'rgba(153, 102, 255, 0.6)', 'rgba(255, 159, 64, 0.6)',
+                'rgba(83, 102, 255, 0.6)', 'rgba(99, 255, 132, 0.6)', 'rgba(199, 199, 199, 0.6)'
+            ];
+            new Chart(document.getElementById('dirChart'), {
+                type: 'line',
+                data: {
+                    labels: labels,
+                    datasets: dirData.dirs.map((dir, i) => ({
+                        label: dir,
+                        data: dirData.sizes[i],
+                        borderColor: dirColors[i %% dirColors.length],
+                        backgroundColor: dirColors[i %% dirColors.length],
+                        fill: i === 0 ? 'origin' : '-1',
+                        tension: 0.1,
+                        pointRadius: 2
+                    }))
+                },
+                options: {
+                    responsive: true,
+                    interaction: { mode: 'index', intersect: false },
+                    plugins: {
+                        legend: { display: true, position: 'bottom' },
+                        tooltip: {
+                            callbacks: {
+                                label: function(context) {
+                                    return context.dataset.label + ': ' + context.raw.toFixed(2) + ' MB';
+                                }
+                            }
+                        }
+                    },
+                    scales: {
+                        x: { title: { display: true, text: 'Tag' } },
+                        y: { stacked: true, beginAtZero: true, title: { display: true, text: 'Size (MB)' } }
+                    }
+                }
+            });
+        }
+
         const headerRow = document.getElementById('layerTableHeader');
         const tbody = document.getElementById('layerTableBody');
 
@@ -1071,8 +1595,8 @@ func generateRegistryChartReport(w io.Writer, results []RegistryResult, imageNam
     </script>
 </body>
 </html>`,
-		imageName, imageName, summaryHTML, insightsHTML,
-		string(labelsJSON), string(sizeJSON), string(stackedDatasetsJSON), string(layerTableJSON))
+		imageName, imageName, registrySizeBasis().Description(), summaryHTML, insightsHTML, scan.TreemapHTML(registryScanOptions(), registryScanVersions(validResults)), sectionsHTML.String(),
+		string(labelsJSON), string(sizeJSON), string(downloadJSON), base.ChartData(registryBaseVersions(validResults)), registryVulnChartData(validResults), registryDirChartData(validResults), string(stackedDatasetsJSON), string(layerTableJSON))
 
 	_, err := w.Write([]byte(html))
 	return err
@@ -0,0 +1,97 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jtodic/docker-time-machine/pkg/base"
+	"github.com/jtodic/docker-time-machine/pkg/docker"
+	"github.com/jtodic/docker-time-machine/pkg/inspect"
+)
+
+// registryCache holds what is fetched once and shared by all analyzed tags
+type registryCache struct {
+	layerSizes inspect.SizeCache
+	bases      map[string]*docker.ImageMetadata
+}
+
+func newRegistryCache() *registryCache {
+	return &registryCache{
+		layerSizes: make(inspect.SizeCache),
+		bases:      make(map[string]*docker.ImageMetadata),
+	}
+}
+
+// registryBaseReference returns the base image of a tag: the --base-image
+// flag, or the OCI base image annotations of its manifest
+func registryBaseReference(metadata *docker.ImageMetadata) string {
+	if registryFlags.baseImage != "" {
+		return registryFlags.baseImage
+	}
+	if metadata.BaseImage == "" {
+		return ""
+	}
+	if metadata.BaseDigest != "" {
+		name, _ := base.SplitReference(metadata.BaseImage)
+		return name + "@" + metadata.BaseDigest
+	}
+	return metadata.BaseImage
+}
+
+// splitRegistryBase resolves the base image manifest and matches its layer
+// digests against the lowest layers of the tag. A reference by tag resolves to
+// the current base image; older tags built on an earlier version of it don't
+// match, so their lowest layers are assumed to be the base as long as the
+// base image kept its layer count. Such splits have no digest, and the base
+// layer ID reveals the base image update.
+func splitRegistryBase(ctx context.Context, regClient *docker.RegistryClient, cache *registryCache, ref string, metadata *docker.ImageMetadata, result *RegistryResult) (*base.Split, error) {
+	baseMetadata, ok := cache.bases[ref]
+	if !ok {
+		name, tag := base.SplitReference(ref)
+		var err error
+		baseMetadata, err = regClient.GetImageMetadata(ctx, name, tag, registryFlags.platform)
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve base image %s: %w", ref, err)
+		}
+		cache.bases[ref] = baseMetadata
+	}
+
+	split := &base.Split{Image: ref}
+	if registryFlags.baseImage == "" {
+		// Show the annotated name rather than the name@digest reference
+		split.Image = metadata.BaseImage
+	}
+	n := base.PrefixLength(metadata.LayerDigests, baseMetadata.LayerDigests)
+	if n > 0 {
+		split.Digest = baseMetadata.Digest
+	} else {
+		n = len(baseMetadata.LayerDigests)
+		if n == 0 || n > len(metadata.LayerDigests) {
+			return nil, fmt.Errorf("image is not built on %s", ref)
+		}
+	}
+	split.Layers = n
+	split.ID = base.LayerID(metadata.LayerDigests[:n])
+
+	baseLayers := make(map[string]bool)
+	for _, digest := range metadata.LayerDigests[:n] {
+		baseLayers[digest] = true
+	}
+
+	for _, layer := range result.Layers {
+		if baseLayers[layer.Digest] {
+			split.Base += layer.Size
+		}
+	}
+	split.App = result.Size - split.Base
+	return split, nil
+}
+
+// registryBaseVersions pairs each tag with its base split
+func registryBaseVersions(validResults []RegistryResult) []base.Version {
+	versions := make([]base.Version, 0, len(validResults))
+	for _, r := range validResults {
+		versions = append(versions, base.Version{Name: r.Tag, Split: r.Base})
+	}
+	return versions
+}
@@ -0,0 +1,151 @@
+package cmd
+
+import (
+	"context"
+	"io"
+
+	"github.com/jtodic/docker-time-machine/pkg/docker"
+	"github.com/jtodic/docker-time-machine/pkg/inspect"
+	"github.com/jtodic/docker-time-machine/pkg/report"
+	"github.com/jtodic/docker-time-machine/pkg/sbom"
+	"github.com/jtodic/docker-time-machine/pkg/scan"
+	"github.com/jtodic/docker-time-machine/pkg/vuln"
+)
+
+// registryVulnDB is the --vuln-db advisory database, loaded when the command
+// starts
+var registryVulnDB *vuln.DB
+
+// registryScanOptions returns the layer content features enabled by flags
+func registryScanOptions() scan.Options {
+	return scan.Options{
+		Files:        registryFlags.files,
+		Waste:        registryFlags.waste,
+		Packages:     registryFlags.packages,
+		Dependencies: registryFlags.deps,
+		Junk:         registryFlags.junk,
+		SBOM:         registryFlags.sbom != "",
+		Vulns:        registryVulnDB,
+		Licenses:     registryFlags.licenses || len(registryFlags.denyLicenses) > 0,
+		DenyLicenses: registryFlags.denyLicenses,
+		Binaries:     registryFlags.binaries,
+		Secrets:      registryFlags.secrets,
+		Duplicates:   registryFlags.duplicates,
+		Dirs:         registryFlags.dirs,
+		DirDepth:     registryFlags.dirDepth,
+		Treemap:      registryFlags.treemap,
+	}
+}
+
+// registrySizeBasis returns the size basis selected by flags. The value is
+// validated when the command starts.
+func registrySizeBasis() report.SizeBasis {
+	basis, _ := report.ParseSizeBasis(registryFlags.sizeBasis, report.Compressed)
+	return basis
+}
+
+// registryLayerSources returns the layer blobs of an image, lowest first,
+// downloaded when opened
+func registryLayerSources(ctx context.Context, regClient *docker.RegistryClient, imageName string, metadata *docker.ImageMetadata) []inspect.LayerSource {
+	createdBy := make(map[string]string)
+	for _, layer := range metadata.Layers {
+		createdBy[layer.Digest] = layer.CreatedBy
+	}
+
+	var layers []inspect.LayerSource
+	for _, digest := range metadata.LayerDigests {
+		layers = append(layers, inspect.LayerSource{
+			Digest:    digest,
+			CreatedBy: createdBy[digest],
+			Open: func() (io.ReadCloser, error) {
+				return regClient.FetchBlob(ctx, imageName, digest)
+			},
+		})
+	}
+	return layers
+}
+
+// measureUncompressed downloads and decompresses the layer blobs of an image
+// to find its size on disk, and switches the result to uncompressed sizes when
+// they drive the report. Blobs shared with tags measured before are not
+// downloaded again.
+func measureUncompressed(ctx context.Context, regClient *docker.RegistryClient, layerSizes inspect.SizeCache, imageName string, metadata *docker.ImageMetadata, result *RegistryResult) error {
+	uncompressed := make(map[string]int64)
+	var total int64
+	for _, layer := range registryLayerSources(ctx, regClient, imageName, metadata) {
+		size, err := layerSizes.Measure(layer)
+		if err != nil {
+			return err
+		}
+		uncompressed[layer.Digest] = size.Uncompressed
+		total += size.Uncompressed
+	}
+
+	result.UncompressedSize = total
+	for i := range result.Layers {
+		result.Layers[i].UncompressedSize = uncompressed[result.Layers[i].Digest]
+	}
+	if registrySizeBasis() != report.Uncompressed {
+		return nil
+	}
+
+	result.Size = total
+	result.SizeMB = float64(total) / 1024 / 1024
+	for i := range result.Layers {
+		layer := &result.Layers[i]
+		layer.Size = layer.UncompressedSize
+		layer.SizeMB = float64(layer.Size) / 1024 / 1024
+	}
+	return nil
+}
+
+// scanRegistryImage downloads the layer blobs of an image one at a time and
+// scans their contents
+func scanRegistryImage(ctx context.Context, regClient *docker.RegistryClient, imageName string, metadata *docker.ImageMetadata, opts scan.Options) (*scan.Result, error) {
+	return scan.Run(registryLayerSources(ctx, regClient, imageName, metadata), opts)
+}
+
+// registryScanVersions pairs each tag with its scan result
+func registryScanVersions(validResults []RegistryResult) []scan.Version {
+	versions := make([]scan.Version, 0, len(validResults))
+	for _, r := range validResults {
+		versions = append(versions, scan.Version{Name: r.Tag, Result: r.Result})
+	}
+	return versions
+}
+
+// registrySBOMs returns the SBOM documents of the scanned tags, newest first
+func registrySBOMs(results []RegistryResult, imageName string) []sbom.Document {
+	var docs []sbom.Document
+	for _, r := range results {
+		if r.Error != "" || r.Result == nil {
+			continue
+		}
+		docs = append(docs, sbom.Document{
+			Image:      imageName,
+			Version:    r.Tag,
+			Created:    r.Created,
+			Components: r.SBOM,
+		})
+	}
+	return docs
+}
+
+// registryDirChartData returns the directory sizes for the HTML chart, or
+// null when the directory breakdown is off
+func registryDirChartData(validResults []RegistryResult) string {
+	if !registryFlags.dirs {
+		return "null"
+	}
+	return inspect.DirChartData(scan.DirVersions(registryScanVersions(validResults)))
+}
+
+// registryVulnChartData returns the vulnerability counts for the HTML chart,
+// or null when vulnerability matching is off
+func registryVulnChartData(validResults []RegistryResult) string {
+	if registryVulnDB == nil {
+		return "null"
+	}
+	versions := scan.VulnVersions(registryScanVersions(validResults))
+	return vuln.ChartData(versions, vuln.CompareVersions(versions))
+}
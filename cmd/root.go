@@ -30,6 +30,11 @@ Use DTM to:
 Commands:
   registry  - Analyze images directly from a container registry (fast, no rebuilds)
   analyze   - Build and analyze images across git history (requires source code)
+  compare   - Compare images built from several branches or tags
+  check     - Predict the image impact of uncommitted changes
+  pr        - Compare a pull request's head against its merge-base
+  lint      - Find Dockerfile patterns that bloat the image, ranked by cost
+  hooks     - Install git hooks that run dtm before pushing
 
 Getting started:
   Run 'dtm registry nginx --last 10' to analyze the last 10 tags of nginx.
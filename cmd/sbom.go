@@ -0,0 +1,45 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/jtodic/docker-time-machine/pkg/sbom"
+)
+
+// validateSBOMFormat checks the --sbom flag; an empty value disables SBOMs
+func validateSBOMFormat(format string) error {
+	if format == "" {
+		return nil
+	}
+	return sbom.ValidateFormat(format)
+}
+
+// writeSBOMs writes one SBOM file per analyzed version next to the report:
+// in the directory of the output file, or the current directory when the
+// report goes to stdout
+func writeSBOMs(output, format string, docs []sbom.Document) error {
+	dir := "."
+	if output != "" {
+		dir = filepath.Dir(output)
+	}
+
+	for _, doc := range docs {
+		path := filepath.Join(dir, sbom.FileName(doc.Version, format))
+		f, err := os.Create(path)
+		if err != nil {
+			return fmt.Errorf("failed to create SBOM file: %w", err)
+		}
+		if err := sbom.Write(f, format, doc); err != nil {
+			f.Close()
+			return fmt.Errorf("failed to write SBOM %s: %w", path, err)
+		}
+		if err := f.Close(); err != nil {
+			return fmt.Errorf("failed to write SBOM %s: %w", path, err)
+		}
+	}
+
+	fmt.Fprintf(os.Stderr, "📜 %d SBOMs saved to: %s\n", len(docs), dir)
+	return nil
+}
@@ -0,0 +1,17 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/jtodic/docker-time-machine/pkg/secrets"
+)
+
+// secretsError reports the secrets found and returns the error that makes
+// dtm exit with a nonzero status
+func secretsError(leaks []secrets.Leak) error {
+	for _, l := range leaks {
+		fmt.Fprintf(os.Stderr, "🔑 %s\n", l)
+	}
+	return fmt.Errorf("secrets found: %d secret(s) shipped in the image layers", len(leaks))
+}
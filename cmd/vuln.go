@@ -0,0 +1,22 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/jtodic/docker-time-machine/pkg/vuln"
+)
+
+// loadVulnDB loads the --vuln-db advisory database; an empty path disables
+// vulnerability matching
+func loadVulnDB(path string) (*vuln.DB, error) {
+	if path == "" {
+		return nil, nil
+	}
+	db, err := vuln.Load(path)
+	if err != nil {
+		return nil, err
+	}
+	fmt.Fprintf(os.Stderr, "🛡️ Loaded %d advisories from %s\n", db.Advisories(), path)
+	return db, nil
+}
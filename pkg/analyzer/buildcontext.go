@@ -0,0 +1,211 @@
+package analyzer
+
+import (
+	"archive/tar"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/filemode"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/jtodic/docker-time-machine/pkg/dockerfile"
+)
+
+// contextFile is a build context tar held in a temporary file, so that large
+// repositories are not kept in memory. Close removes the file.
+type contextFile struct {
+	*os.File
+}
+
+func (f contextFile) Close() error {
+	f.File.Close()
+	return os.Remove(f.Name())
+}
+
+// writeBuildContext writes a build context tar to a temporary file and
+// returns it rewound for reading
+func writeBuildContext(write func(tw *tar.Writer) error) (io.ReadCloser, error) {
+	tmp, err := os.CreateTemp("", "dtm-context-*.tar")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create build context: %w", err)
+	}
+	f := contextFile{tmp}
+
+	tw := tar.NewWriter(f)
+	if err := write(tw); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("failed to create tar: %w", err)
+	}
+	if err := tw.Close(); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("failed to create tar: %w", err)
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("failed to create build context: %w", err)
+	}
+	return f, nil
+}
+
+// contextPrefix returns the path prefix of the files below dir (relative to
+// the repository root, "." for the root)
+func contextPrefix(dir string) string {
+	if dir == "" || dir == "." {
+		return ""
+	}
+	return strings.TrimSuffix(path.Clean(dir), "/") + "/"
+}
+
+// commitBuildContext creates a build context tar from the tree of a commit
+// without checking it out, so the working tree is left untouched. Only files
+// below dir (relative to the repository root, "." for the root) are included.
+func commitBuildContext(commit *object.Commit, dir string) (io.ReadCloser, error) {
+	tree, err := commit.Tree()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get tree: %w", err)
+	}
+	prefix := contextPrefix(dir)
+
+	return writeBuildContext(func(tw *tar.Writer) error {
+		return tree.Files().ForEach(func(f *object.File) error {
+			if !strings.HasPrefix(f.Name, prefix) {
+				return nil
+			}
+			name := strings.TrimPrefix(f.Name, prefix)
+
+			header := &tar.Header{
+				Name:    name,
+				ModTime: commit.Committer.When,
+			}
+
+			switch f.Mode {
+			case filemode.Symlink:
+				target, err := f.Contents()
+				if err != nil {
+					return err
+				}
+				header.Typeflag = tar.TypeSymlink
+				header.Linkname = target
+				header.Mode = 0777
+				return tw.WriteHeader(header)
+			case filemode.Executable:
+				header.Mode = 0755
+			case filemode.Regular, filemode.Deprecated:
+				header.Mode = 0644
+			default:
+				// Submodules have no content in this tree
+				return nil
+			}
+
+			header.Typeflag = tar.TypeReg
+			header.Size = f.Size
+			if err := tw.WriteHeader(header); err != nil {
+				return err
+			}
+
+			reader, err := f.Reader()
+			if err != nil {
+				return err
+			}
+			defer reader.Close()
+
+			_, err = io.Copy(tw, reader)
+			return err
+		})
+	})
+}
+
+// worktreeBuildContext creates a build context tar from the files git tracks,
+// including staged additions, with their contents as they are on disk. It
+// contains the same kind of files as commitBuildContext, so untracked and
+// ignored files such as local node_modules never reach the build. Files
+// deleted from the disk are left out.
+func worktreeBuildContext(repo *git.Repository, repoPath, dir string) (io.ReadCloser, error) {
+	index, err := repo.Storer.Index()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read index: %w", err)
+	}
+	prefix := contextPrefix(dir)
+
+	return writeBuildContext(func(tw *tar.Writer) error {
+		for _, entry := range index.Entries {
+			if !strings.HasPrefix(entry.Name, prefix) || entry.Mode == filemode.Submodule {
+				continue
+			}
+			if err := writeWorktreeFile(tw, filepath.Join(repoPath, filepath.FromSlash(entry.Name)), strings.TrimPrefix(entry.Name, prefix)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// writeWorktreeFile adds a file from the disk to a build context tar, with
+// the same modes commitBuildContext uses for git objects
+func writeWorktreeFile(tw *tar.Writer, diskPath, name string) error {
+	info, err := os.Lstat(diskPath)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	header := &tar.Header{
+		Name:    name,
+		ModTime: info.ModTime().Truncate(time.Second),
+	}
+	switch {
+	case info.Mode()&fs.ModeSymlink != 0:
+		target, err := os.Readlink(diskPath)
+		if err != nil {
+			return err
+		}
+		header.Typeflag = tar.TypeSymlink
+		header.Linkname = filepath.ToSlash(target)
+		header.Mode = 0777
+		return tw.WriteHeader(header)
+	case !info.Mode().IsRegular():
+		return nil
+	case info.Mode()&0111 != 0:
+		header.Mode = 0755
+	default:
+		header.Mode = 0644
+	}
+
+	file, err := os.Open(diskPath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	header.Typeflag = tar.TypeReg
+	header.Size = info.Size()
+	if err := tw.WriteHeader(header); err != nil {
+		return err
+	}
+	_, err = io.CopyN(tw, file, info.Size())
+	return err
+}
+
+// commitDockerfile parses the Dockerfile as it exists in the given commit
+func commitDockerfile(commit *object.Commit, dockerfilePath string) (*dockerfile.Dockerfile, error) {
+	file, err := commit.File(path.Clean(dockerfilePath))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", dockerfilePath, err)
+	}
+
+	contents, err := file.Contents()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", dockerfilePath, err)
+	}
+
+	return dockerfile.Parse(strings.NewReader(contents))
+}
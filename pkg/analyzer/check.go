@@ -0,0 +1,260 @@
+package analyzer
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/jtodic/docker-time-machine/pkg/docker"
+	"github.com/jtodic/docker-time-machine/pkg/dockerfile"
+)
+
+// CheckConfig holds configuration for the Checker
+type CheckConfig struct {
+	RepoPath       string
+	DockerfilePath string
+	Target         string  // branch or revision to compare the working tree against
+	Revision       string  // commit to check instead of the working tree (empty = working tree)
+	MaxIncreaseMB  float64 // size budget in MB (0 = no limit)
+	MaxIncreasePct float64 // size budget in percent (0 = no limit)
+	MaxNewLayers   int     // layer count budget (0 = no limit)
+	Verbose        bool
+}
+
+// CheckResult holds the outcome of comparing the working tree, or the checked
+// revision, against the merge-base with the target branch
+type CheckResult struct {
+	Target          string       `json:"target"`
+	Revision        string       `json:"revision,omitempty"`
+	MergeBase       BranchInfo   `json:"merge_base"`
+	WorkingTree     BranchInfo   `json:"working_tree"`
+	SizeDiff        float64      `json:"size_diff_mb"`
+	SizeDiffPercent float64      `json:"size_diff_percent"`
+	LayersDiff      int          `json:"layers_diff"`
+	LayerDeltas     []LayerDelta `json:"layer_deltas,omitempty"`
+	Violations      []string     `json:"violations,omitempty"`
+}
+
+// OverBudget reports whether any budget was exceeded
+func (r *CheckResult) OverBudget() bool {
+	return len(r.Violations) > 0
+}
+
+// Checker predicts the image impact of uncommitted changes
+type Checker struct {
+	config  CheckConfig
+	repo    *git.Repository
+	builder *docker.Builder
+}
+
+// NewChecker creates a new Checker
+func NewChecker(config CheckConfig) (*Checker, error) {
+	repo, err := git.PlainOpen(config.RepoPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open repository: %w", err)
+	}
+
+	builder, err := docker.NewBuilder()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Docker builder: %w", err)
+	}
+
+	return &Checker{
+		config:  config,
+		repo:    repo,
+		builder: builder,
+	}, nil
+}
+
+// Run builds the merge-base of HEAD and the target branch from git objects and
+// the tracked files of the working tree as they are on disk (including staged
+// and unstaged changes),
+// then compares both images against the configured budgets. When a Revision
+// is set, that commit is built from git objects in place of the working tree.
+func (c *Checker) Run(ctx context.Context) (*CheckResult, error) {
+	var revision *object.Commit
+	var headHash plumbing.Hash
+	if c.config.Revision != "" {
+		hash, err := c.repo.ResolveRevision(plumbing.Revision(c.config.Revision))
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve reference %s: %w", c.config.Revision, err)
+		}
+		revision, err = c.repo.CommitObject(*hash)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get commit %s: %w", c.config.Revision, err)
+		}
+		headHash = revision.Hash
+	} else {
+		head, err := c.repo.Head()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get HEAD: %w", err)
+		}
+		headHash = head.Hash()
+	}
+
+	mergeBase, err := resolveMergeBase(c.repo, c.config.Target, headHash)
+	if err != nil {
+		return nil, err
+	}
+
+	if c.config.Verbose {
+		fmt.Fprintf(os.Stderr, "Building merge-base %s with %s...\n", mergeBase.Hash.String()[:8], c.config.Target)
+	}
+	baseInfo, err := c.buildCommit(ctx, mergeBase)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build merge-base: %w", err)
+	}
+
+	var workInfo *BranchInfo
+	if revision != nil {
+		if c.config.Verbose {
+			fmt.Fprintf(os.Stderr, "Building %s...\n", revision.Hash.String()[:8])
+		}
+		workInfo, err = c.buildCommit(ctx, revision)
+		if err != nil {
+			return nil, fmt.Errorf("failed to build %s: %w", c.config.Revision, err)
+		}
+	} else {
+		if c.config.Verbose {
+			fmt.Fprintf(os.Stderr, "Building working tree...\n")
+		}
+		workInfo, err = c.buildWorkingTree(ctx, headHash)
+		if err != nil {
+			return nil, fmt.Errorf("failed to build working tree: %w", err)
+		}
+	}
+
+	result := &CheckResult{
+		Target:      c.config.Target,
+		Revision:    c.config.Revision,
+		MergeBase:   *baseInfo,
+		WorkingTree: *workInfo,
+		SizeDiff:    workInfo.SizeMB - baseInfo.SizeMB,
+		LayersDiff:  workInfo.Layers - baseInfo.Layers,
+		LayerDeltas: diffLayers(baseInfo.LayerDetails, workInfo.LayerDetails),
+	}
+	if baseInfo.SizeMB > 0 {
+		result.SizeDiffPercent = (result.SizeDiff / baseInfo.SizeMB) * 100
+	}
+
+	if c.config.MaxIncreaseMB > 0 && result.SizeDiff > c.config.MaxIncreaseMB {
+		result.Violations = append(result.Violations,
+			fmt.Sprintf("size increased by %.2f MB (budget %.2f MB)", result.SizeDiff, c.config.MaxIncreaseMB))
+	}
+	if c.config.MaxIncreasePct > 0 && result.SizeDiffPercent > c.config.MaxIncreasePct {
+		result.Violations = append(result.Violations,
+			fmt.Sprintf("size increased by %.1f%% (budget %.1f%%)", result.SizeDiffPercent, c.config.MaxIncreasePct))
+	}
+	if c.config.MaxNewLayers > 0 && result.LayersDiff > c.config.MaxNewLayers {
+		result.Violations = append(result.Violations,
+			fmt.Sprintf("layer count increased by %d (budget %d)", result.LayersDiff, c.config.MaxNewLayers))
+	}
+
+	return result, nil
+}
+
+// buildCommit builds the image for a commit from its git tree
+func (c *Checker) buildCommit(ctx context.Context, commit *object.Commit) (*BranchInfo, error) {
+	buildContext, err := commitBuildContext(commit, filepath.ToSlash(filepath.Dir(c.config.DockerfilePath)))
+	if err != nil {
+		return nil, err
+	}
+	defer buildContext.Close()
+
+	df, err := commitDockerfile(commit, c.config.DockerfilePath)
+	if err != nil && c.config.Verbose {
+		fmt.Fprintf(os.Stderr, "  ⚠️ Could not parse Dockerfile, matching layers by command: %v\n", err)
+	}
+
+	imageName := fmt.Sprintf("dtm-check-%s", commit.Hash.String()[:12])
+	startTime := time.Now()
+
+	err = c.builder.BuildImageFromContext(ctx, buildContext, filepath.Base(c.config.DockerfilePath), imageName)
+	if err != nil {
+		return nil, fmt.Errorf("build failed: %w", err)
+	}
+
+	return c.inspect(ctx, imageName, commit.Hash.String()[:8], commit.Hash.String()[:8], time.Since(startTime).Seconds(), df)
+}
+
+// buildWorkingTree builds the image from the tracked and staged files as they
+// are on disk, so that it sees the same files as the merge-base build
+func (c *Checker) buildWorkingTree(ctx context.Context, head plumbing.Hash) (*BranchInfo, error) {
+	buildContext, err := worktreeBuildContext(c.repo, c.config.RepoPath, filepath.ToSlash(filepath.Dir(c.config.DockerfilePath)))
+	if err != nil {
+		return nil, err
+	}
+	defer buildContext.Close()
+
+	df, err := dockerfile.ParseFile(filepath.Join(c.config.RepoPath, c.config.DockerfilePath))
+	if err != nil && c.config.Verbose {
+		fmt.Fprintf(os.Stderr, "  ⚠️ Could not parse Dockerfile, matching layers by command: %v\n", err)
+	}
+
+	imageName := fmt.Sprintf("dtm-check-worktree-%d", time.Now().UnixNano())
+	startTime := time.Now()
+
+	err = c.builder.BuildImageFromContext(ctx, buildContext, filepath.Base(c.config.DockerfilePath), imageName)
+	if err != nil {
+		return nil, fmt.Errorf("build failed: %w", err)
+	}
+
+	return c.inspect(ctx, imageName, "working tree", head.String()[:8], time.Since(startTime).Seconds(), df)
+}
+
+// inspect collects size and layer information of a built image and removes it
+func (c *Checker) inspect(ctx context.Context, imageName, name, commit string, buildTime float64, df *dockerfile.Dockerfile) (*BranchInfo, error) {
+	defer c.builder.RemoveImage(ctx, imageName)
+
+	imageInfo, err := c.builder.GetImageInfo(ctx, imageName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to inspect image: %w", err)
+	}
+
+	layers, err := collectLayers(ctx, c.builder, imageName, df)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get image history: %w", err)
+	}
+
+	return &BranchInfo{
+		Name:         name,
+		Commit:       commit,
+		SizeMB:       float64(imageInfo.Size) / 1024 / 1024,
+		Layers:       len(imageInfo.RootFS.Layers),
+		BuildTime:    buildTime,
+		LayerDetails: layers,
+	}, nil
+}
+
+// resolveMergeBase finds the best common ancestor of a revision and a commit
+func resolveMergeBase(repo *git.Repository, revision string, hash plumbing.Hash) (*object.Commit, error) {
+	targetHash, err := repo.ResolveRevision(plumbing.Revision(revision))
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve reference %s: %w", revision, err)
+	}
+
+	target, err := repo.CommitObject(*targetHash)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get commit %s: %w", revision, err)
+	}
+
+	commit, err := repo.CommitObject(hash)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get commit %s: %w", hash.String()[:8], err)
+	}
+
+	bases, err := commit.MergeBase(target)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compute merge-base with %s: %w", revision, err)
+	}
+	if len(bases) == 0 {
+		return nil, fmt.Errorf("no common ancestor with %s", revision)
+	}
+
+	return bases[0], nil
+}
@@ -3,10 +3,13 @@ package analyzer
 import (
 	"context"
 	"fmt"
+	"os"
+	"path/filepath"
 	"time"
 
 	"github.com/go-git/go-git/v5"
 	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
 	"github.com/jtodic/docker-time-machine/pkg/docker"
 )
 
@@ -30,11 +33,12 @@ type CompareResult struct {
 
 // BranchInfo holds information about a branch's Docker image
 type BranchInfo struct {
-	Name      string  `json:"name"`
-	Commit    string  `json:"commit"`
-	SizeMB    float64 `json:"size_mb"`
-	Layers    int     `json:"layers"`
-	BuildTime float64 `json:"build_time"`
+	Name         string      `json:"name"`
+	Commit       string      `json:"commit"`
+	SizeMB       float64     `json:"size_mb"`
+	Layers       int         `json:"layers"`
+	BuildTime    float64     `json:"build_time"`
+	LayerDetails []LayerInfo `json:"layer_details,omitempty"`
 }
 
 // Comparer compares Docker images between branches
@@ -104,7 +108,11 @@ func (c *Comparer) Compare(ctx context.Context, branchA, branchB string) (*Compa
 		fmt.Printf("Warning: failed to restore original branch: %v\n", err)
 	}
 
-	// Calculate differences
+	return newCompareResult(infoA, infoB), nil
+}
+
+// newCompareResult calculates the differences from build A to build B
+func newCompareResult(infoA, infoB *BranchInfo) *CompareResult {
 	result := &CompareResult{
 		BranchA:       *infoA,
 		BranchB:       *infoB,
@@ -121,7 +129,7 @@ func (c *Comparer) Compare(ctx context.Context, branchA, branchB string) (*Compa
 		result.BuildTimeDiffPercent = (result.BuildTimeDiff / infoA.BuildTime) * 100
 	}
 
-	return result, nil
+	return result
 }
 
 // buildBranch builds a Docker image for a specific branch
@@ -180,3 +188,53 @@ func (c *Comparer) buildBranch(ctx context.Context, worktree *git.Worktree, bran
 		BuildTime: buildTime,
 	}, nil
 }
+
+// buildCommit builds a Docker image from the tree of a commit for the pr and
+// compare commands. The image is built from git objects, so the working tree
+// is never checked out.
+func (c *Comparer) buildCommit(ctx context.Context, commit *object.Commit) (*BranchInfo, error) {
+	buildContext, err := commitBuildContext(commit, filepath.ToSlash(filepath.Dir(c.config.DockerfilePath)))
+	if err != nil {
+		return nil, err
+	}
+	defer buildContext.Close()
+
+	df, err := commitDockerfile(commit, c.config.DockerfilePath)
+	if err != nil && c.config.Verbose {
+		fmt.Fprintf(os.Stderr, "  ⚠️ Could not parse Dockerfile, matching layers by command: %v\n", err)
+	}
+
+	// Build the Docker image
+	imageName := fmt.Sprintf("dtm-compare-%s", commit.Hash.String()[:12])
+	startTime := time.Now()
+
+	err = c.builder.BuildImageFromContext(ctx, buildContext, filepath.Base(c.config.DockerfilePath), imageName)
+	if err != nil {
+		return nil, fmt.Errorf("build failed: %w", err)
+	}
+
+	buildTime := time.Since(startTime).Seconds()
+
+	// Clean up
+	defer c.builder.RemoveImage(ctx, imageName)
+
+	// Get image info
+	imageInfo, err := c.builder.GetImageInfo(ctx, imageName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to inspect image: %w", err)
+	}
+
+	layers, err := collectLayers(ctx, c.builder, imageName, df)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get image history: %w", err)
+	}
+
+	return &BranchInfo{
+		Name:         commit.Hash.String()[:8],
+		Commit:       commit.Hash.String()[:8],
+		SizeMB:       float64(imageInfo.Size) / 1024 / 1024,
+		Layers:       len(imageInfo.RootFS.Layers),
+		BuildTime:    buildTime,
+		LayerDetails: layers,
+	}, nil
+}
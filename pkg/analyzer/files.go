@@ -0,0 +1,112 @@
+package analyzer
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jtodic/docker-time-machine/pkg/docker"
+	"github.com/jtodic/docker-time-machine/pkg/dockerfile"
+	"github.com/jtodic/docker-time-machine/pkg/inspect"
+	"github.com/jtodic/docker-time-machine/pkg/license"
+	"github.com/jtodic/docker-time-machine/pkg/scan"
+	"github.com/jtodic/docker-time-machine/pkg/secrets"
+	"github.com/jtodic/docker-time-machine/pkg/vuln"
+)
+
+// exportImage saves a built image and extracts its layer tarballs. Call Close
+// on the archive to remove the extracted files.
+func exportImage(ctx context.Context, builder *docker.Builder, imageName string) (*inspect.Archive, error) {
+	rc, err := builder.SaveImage(ctx, imageName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to save image: %w", err)
+	}
+	defer rc.Close()
+
+	return inspect.ExtractArchive(rc)
+}
+
+// scanArchive scans the layer tarballs of an exported image. Layers are
+// labeled with the Dockerfile lines that created them when df is available.
+func scanArchive(archive *inspect.Archive, df *dockerfile.Dockerfile, opts scan.Options) (*scan.Result, error) {
+	labelLayerSources(archive.Layers, archive.History, df)
+	return scan.Run(archive.Layers, opts)
+}
+
+// labelLayerSources replaces the raw history commands of the layers with the
+// labels used by the layer comparison, e.g. "L12-14 RUN apt-get ..."
+func labelLayerSources(layers []inspect.LayerSource, history []inspect.HistoryEntry, df *dockerfile.Dockerfile) {
+	var matches []*dockerfile.Instruction
+	if df != nil {
+		createdBy := make([]string, len(history))
+		for i, h := range history {
+			createdBy[i] = h.CreatedBy
+		}
+		matches = df.MatchHistory(createdBy)
+	}
+
+	i := 0
+	for j, h := range history {
+		if h.EmptyLayer || i >= len(layers) {
+			continue
+		}
+		info := LayerInfo{CreatedBy: truncateLayerCommand(h.CreatedBy)}
+		if matches != nil && matches[j] != nil {
+			info.StartLine = matches[j].StartLine
+			info.EndLine = matches[j].EndLine
+		}
+		layers[i].CreatedBy = info.Label()
+		i++
+	}
+}
+
+// scanVersions pairs each result with its scan result
+func scanVersions(validResults []BuildResult) []scan.Version {
+	versions := make([]scan.Version, 0, len(validResults))
+	for _, r := range validResults {
+		versions = append(versions, scan.Version{Name: r.CommitHash[:8], Result: r.Result})
+	}
+	return versions
+}
+
+// vulnChartData returns the vulnerability counts for the HTML chart, or null
+// when vulnerability matching is off
+func (tm *TimeMachine) vulnChartData(validResults []BuildResult) string {
+	if tm.config.Scan.Vulns == nil {
+		return "null"
+	}
+	versions := scan.VulnVersions(scanVersions(validResults))
+	return vuln.ChartData(versions, vuln.CompareVersions(versions))
+}
+
+// dirChartData returns the directory sizes for the HTML chart, or null when
+// the directory breakdown is off
+func (tm *TimeMachine) dirChartData(validResults []BuildResult) string {
+	if !tm.config.Scan.Dirs {
+		return "null"
+	}
+	return inspect.DirChartData(scan.DirVersions(scanVersions(validResults)))
+}
+
+// LicenseViolations returns the denied licenses found in the successful
+// builds, at the commit where each appeared
+func (tm *TimeMachine) LicenseViolations() []license.Violation {
+	var validResults []BuildResult
+	for _, r := range tm.results {
+		if r.Error == "" {
+			validResults = append(validResults, r)
+		}
+	}
+	return scan.LicenseViolations(tm.config.Scan, scanVersions(validResults))
+}
+
+// SecretLeaks returns the secrets found in the successful builds, at the
+// commit where each first appeared
+func (tm *TimeMachine) SecretLeaks() []secrets.Leak {
+	var validResults []BuildResult
+	for _, r := range tm.results {
+		if r.Error == "" {
+			validResults = append(validResults, r)
+		}
+	}
+	return scan.SecretLeaks(tm.config.Scan, scanVersions(validResults))
+}
@@ -0,0 +1,209 @@
+package analyzer
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/docker/docker/api/types/image"
+	"github.com/jtodic/docker-time-machine/pkg/docker"
+	"github.com/jtodic/docker-time-machine/pkg/dockerfile"
+	"github.com/jtodic/docker-time-machine/pkg/layerid"
+)
+
+// LayerDelta describes how a single layer changed between two builds
+type LayerDelta struct {
+	Key      string  `json:"key"`
+	Command  string  `json:"command"`
+	BeforeMB float64 `json:"before_mb"` // -1 when the layer is new
+	AfterMB  float64 `json:"after_mb"`  // -1 when the layer was removed
+	DiffMB   float64 `json:"diff_mb"`
+}
+
+// splitDockerfilePath determines the build context directory and Dockerfile
+// name. If the Dockerfile path contains a directory, that directory becomes
+// the build context.
+func splitDockerfilePath(repoPath, dockerfilePath string) (contextPath, dockerfileName string) {
+	if strings.Contains(dockerfilePath, "/") {
+		return filepath.Join(repoPath, filepath.Dir(dockerfilePath)), filepath.Base(dockerfilePath)
+	}
+	return repoPath, dockerfilePath
+}
+
+// collectLayers reads the history of a built image and returns its non-empty
+// layers, newest first. When the Dockerfile is available each layer is mapped
+// to the stage, instruction and line range that created it.
+func collectLayers(ctx context.Context, builder *docker.Builder, imageName string, df *dockerfile.Dockerfile) ([]LayerInfo, error) {
+	history, err := builder.GetImageHistory(ctx, imageName)
+	if err != nil {
+		return nil, err
+	}
+
+	var instructions []*dockerfile.Instruction
+	if df != nil {
+		// History is newest first, MatchHistory expects oldest first
+		createdBy := make([]string, len(history))
+		for i, layer := range history {
+			createdBy[len(history)-1-i] = layer.CreatedBy
+		}
+		matches := df.MatchHistory(createdBy)
+		instructions = make([]*dockerfile.Instruction, len(history))
+		for i := range history {
+			instructions[i] = matches[len(history)-1-i]
+		}
+	}
+
+	// Layer digests identify layers shared between builds
+	var digests []string
+	if info, err := builder.GetImageInfo(ctx, imageName); err == nil {
+		digests = historyDigests(history, info.RootFS.Layers)
+	}
+
+	var layers []LayerInfo
+	for i, layer := range history {
+		// Skip empty layers (metadata-only)
+		if layer.Size == 0 {
+			continue
+		}
+		layerInfo := LayerInfo{
+			ID:               layer.ID,
+			CreatedBy:        truncateLayerCommand(layer.CreatedBy),
+			Size:             layer.Size,
+			SizeMB:           float64(layer.Size) / 1024 / 1024,
+			UncompressedSize: layer.Size,
+			history:          len(history) - 1 - i,
+		}
+		if digests != nil {
+			layerInfo.Digest = digests[i]
+		}
+		layerInfo.Key = layerInfo.CreatedBy
+		if instructions != nil && instructions[i] != nil {
+			inst := instructions[i]
+			layerInfo.Key = inst.Key()
+			layerInfo.Stage = inst.StageName
+			if layerInfo.Stage == "" {
+				layerInfo.Stage = fmt.Sprintf("%d", inst.Stage)
+			}
+			layerInfo.Instruction = inst.Keyword
+			layerInfo.StartLine = inst.StartLine
+			layerInfo.EndLine = inst.EndLine
+		}
+		layers = append(layers, layerInfo)
+	}
+
+	return layers, nil
+}
+
+// historyDigests maps the history entries (newest first) that created a
+// layer to the layer diff IDs of the image (lowest first). The history does not
+// mark which entries created a layer, so entries with a size or a filesystem
+// instruction are assumed to, and nil is returned when that does not add up.
+func historyDigests(history []image.HistoryResponseItem, diffIDs []string) []string {
+	assign := func(createsLayer func(image.HistoryResponseItem) bool) []string {
+		digests := make([]string, len(history))
+		next := 0
+		for i := len(history) - 1; i >= 0; i-- {
+			if !createsLayer(history[i]) {
+				continue
+			}
+			if next >= len(diffIDs) {
+				return nil
+			}
+			digests[i] = diffIDs[next]
+			next++
+		}
+		if next != len(diffIDs) {
+			return nil
+		}
+		return digests
+	}
+
+	if digests := assign(func(h image.HistoryResponseItem) bool {
+		switch dockerfile.HistoryKeyword(h.CreatedBy) {
+		case "RUN", "COPY", "ADD", "WORKDIR":
+			return true
+		}
+		return h.Size > 0
+	}); digests != nil {
+		return digests
+	}
+	return assign(func(h image.HistoryResponseItem) bool { return h.Size > 0 })
+}
+
+// matchLayers sets the Key of every layer so that a layer keeps its key
+// across builds when its command changes or instructions are inserted before
+// it (see layerid.Assign). The Dockerfile instruction key of a layer is only a
+// hint, used after the digest and the command. builds are ordered newest
+// first and their layers newest first, as collectLayers returns them.
+func matchLayers(builds ...[]LayerInfo) {
+	versions := make([][]layerid.Layer, len(builds))
+	for v, layers := range builds {
+		for i := len(layers) - 1; i >= 0; i-- {
+			layer := layerid.Layer{
+				Digest:  layers[i].Digest,
+				Command: layers[i].CreatedBy,
+				Stage:   layers[i].Stage,
+			}
+			if layers[i].Instruction != "" {
+				layer.Hint = layers[i].Key
+			}
+			versions[v] = append(versions[v], layer)
+		}
+	}
+	for v, keys := range layerid.Assign(versions) {
+		layers := builds[v]
+		for i, key := range keys {
+			layers[len(layers)-1-i].Key = key
+		}
+	}
+}
+
+// diffLayers matches layers of two builds by key and returns the layers whose
+// size changed, were added or were removed, ordered as in after followed by
+// removed layers
+func diffLayers(before, after []LayerInfo) []LayerDelta {
+	matchLayers(after, before)
+
+	beforeByKey := make(map[string]LayerInfo)
+	for _, layer := range before {
+		beforeByKey[layer.Key] = layer
+	}
+
+	var deltas []LayerDelta
+	seen := make(map[string]bool)
+	for _, layer := range after {
+		seen[layer.Key] = true
+		delta := LayerDelta{
+			Key:      layer.Key,
+			Command:  layer.Label(),
+			BeforeMB: -1,
+			AfterMB:  layer.SizeMB,
+			DiffMB:   layer.SizeMB,
+		}
+		if old, ok := beforeByKey[layer.Key]; ok {
+			if old.Size == layer.Size {
+				continue
+			}
+			delta.BeforeMB = old.SizeMB
+			delta.DiffMB = layer.SizeMB - old.SizeMB
+		}
+		deltas = append(deltas, delta)
+	}
+
+	for _, layer := range before {
+		if seen[layer.Key] {
+			continue
+		}
+		seen[layer.Key] = true
+		deltas = append(deltas, LayerDelta{
+			Key:      layer.Key,
+			Command:  layer.Label(),
+			BeforeMB: layer.SizeMB,
+			AfterMB:  -1,
+			DiffMB:   -layer.SizeMB,
+		})
+	}
+
+	return deltas
+}
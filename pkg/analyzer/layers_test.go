@@ -0,0 +1,93 @@
+package analyzer
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/docker/docker/api/types/image"
+)
+
+func TestHistoryDigests(t *testing.T) {
+	tests := []struct {
+		name    string
+		history []image.HistoryResponseItem // newest first
+		diffIDs []string                    // lowest first
+		want    []string
+	}{
+		{
+			"buildkit",
+			[]image.HistoryResponseItem{
+				{CreatedBy: "CMD [\"node\"]"},
+				{CreatedBy: "RUN /bin/sh -c npm ci # buildkit", Size: 300},
+				{CreatedBy: "COPY . . # buildkit", Size: 20},
+				{CreatedBy: "WORKDIR /app"},
+				{CreatedBy: "/bin/sh -c #(nop)  CMD [\"bash\"]"},
+				{CreatedBy: "/bin/sh -c #(nop) ADD file:4f1a in / ", Size: 100},
+			},
+			[]string{"base", "workdir", "copy", "run"},
+			[]string{"", "run", "copy", "workdir", "", "base"},
+		},
+		{
+			"classic builder",
+			[]image.HistoryResponseItem{
+				{CreatedBy: "/bin/sh -c #(nop)  CMD [\"node\"]"},
+				{CreatedBy: "|1 VERSION=1.0 /bin/sh -c npm ci", Size: 300},
+				{CreatedBy: "/bin/sh -c #(nop) COPY dir:9b2c in . ", Size: 20},
+				{CreatedBy: "/bin/sh -c #(nop)  ARG VERSION"},
+				{CreatedBy: "/bin/sh -c #(nop) ADD file:4f1a in / ", Size: 100},
+			},
+			[]string{"base", "copy", "run"},
+			[]string{"", "run", "copy", "", "base"},
+		},
+		{
+			"empty run layer",
+			[]image.HistoryResponseItem{
+				{CreatedBy: "COPY app /app # buildkit", Size: 20},
+				{CreatedBy: "RUN /bin/sh -c true # buildkit"},
+				{CreatedBy: "RUN /bin/sh -c mkdir -p /data # buildkit", Size: 1},
+				{CreatedBy: "/bin/sh -c #(nop) ADD file:4f1a in / ", Size: 100},
+			},
+			[]string{"base", "mkdir", "copy"},
+			[]string{"copy", "", "mkdir", "base"},
+		},
+		{
+			"onbuild triggers",
+			[]image.HistoryResponseItem{
+				{CreatedBy: "RUN /bin/sh -c npm test # buildkit", Size: 5},
+				{CreatedBy: "COPY . . # buildkit", Size: 20},
+				{CreatedBy: "RUN /bin/sh -c npm install # buildkit", Size: 300},
+				{CreatedBy: "COPY package.json /app/ # buildkit", Size: 1},
+				{CreatedBy: "/bin/sh -c #(nop) ADD file:4f1a in / ", Size: 100},
+			},
+			[]string{"base", "package", "install", "copy", "test"},
+			[]string{"test", "copy", "install", "package", "base"},
+		},
+		{
+			"multi-stage",
+			[]image.HistoryResponseItem{
+				{CreatedBy: "ENTRYPOINT [\"/app\"]"},
+				{CreatedBy: "COPY /app /app # buildkit", Size: 2000},
+				{CreatedBy: "/bin/sh -c #(nop)  CMD [\"/bin/sh\"]"},
+				{CreatedBy: "/bin/sh -c #(nop) ADD file:4f1a in / ", Size: 100},
+			},
+			[]string{"base", "app"},
+			[]string{"", "app", "", "base"},
+		},
+		{
+			"layer count does not add up",
+			[]image.HistoryResponseItem{
+				{CreatedBy: "COPY app /app # buildkit", Size: 20},
+				{CreatedBy: "/bin/sh -c #(nop) ADD file:4f1a in / ", Size: 100},
+			},
+			[]string{"base", "app", "extra"},
+			nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := historyDigests(tt.history, tt.diffIDs); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("historyDigests() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
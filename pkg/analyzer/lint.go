@@ -0,0 +1,89 @@
+package analyzer
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/jtodic/docker-time-machine/pkg/docker"
+	"github.com/jtodic/docker-time-machine/pkg/dockerfile"
+	"github.com/jtodic/docker-time-machine/pkg/lint"
+)
+
+// LintConfig holds configuration for LintWorkingTree
+type LintConfig struct {
+	RepoPath       string
+	DockerfilePath string
+	Build          bool // build the working tree to measure the cost of each issue
+	Verbose        bool
+}
+
+// LintResult holds the issues found in a Dockerfile
+type LintResult struct {
+	Dockerfile string       `json:"dockerfile"`
+	Measured   bool         `json:"measured"`
+	ImageSize  int64        `json:"image_size,omitempty"`
+	Issues     []lint.Issue `json:"issues"`
+}
+
+// LintWorkingTree lints the Dockerfile on disk. With Build set, the working
+// tree is built first so the issues can be ranked by the bytes they cost.
+func LintWorkingTree(ctx context.Context, config LintConfig) (*LintResult, error) {
+	path := filepath.Join(config.RepoPath, config.DockerfilePath)
+	df, err := dockerfile.ParseFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse Dockerfile: %w", err)
+	}
+
+	result := &LintResult{Dockerfile: config.DockerfilePath}
+	if !config.Build {
+		result.Issues = lint.Lint(df, nil)
+		return result, nil
+	}
+
+	builder, err := docker.NewBuilder()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Docker builder: %w", err)
+	}
+
+	contextPath, dockerfileName := splitDockerfilePath(config.RepoPath, config.DockerfilePath)
+	imageName := fmt.Sprintf("dtm-lint-worktree-%d", time.Now().UnixNano())
+
+	if config.Verbose {
+		fmt.Fprintf(os.Stderr, "Building working tree...\n")
+	}
+	if err := builder.BuildImage(ctx, contextPath, dockerfileName, imageName); err != nil {
+		return nil, fmt.Errorf("build failed: %w", err)
+	}
+	defer builder.RemoveImage(ctx, imageName)
+
+	imageInfo, err := builder.GetImageInfo(ctx, imageName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to inspect image: %w", err)
+	}
+	layers, err := collectLayers(ctx, builder, imageName, df)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get image history: %w", err)
+	}
+
+	result.Measured = true
+	result.ImageSize = imageInfo.Size
+	result.Issues = lint.Lint(df, lintSizes(layers))
+	return result, nil
+}
+
+// lintSizes maps the measured layers to the instructions that created them.
+// Layers that match no instruction of the Dockerfile come from the base image.
+func lintSizes(layers []LayerInfo) *lint.Sizes {
+	sizes := &lint.Sizes{ByInstruction: make(map[string]int64)}
+	for _, layer := range layers {
+		if layer.StartLine == 0 {
+			sizes.Base += layer.Size
+			continue
+		}
+		sizes.ByInstruction[layer.Key] += layer.Size
+	}
+	return sizes
+}
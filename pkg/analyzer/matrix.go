@@ -0,0 +1,436 @@
+package analyzer
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"html"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/olekukonko/tablewriter"
+)
+
+// MatrixResult holds the comparison of several refs against each other
+type MatrixResult struct {
+	Refs []BranchInfo `json:"refs"`
+	// SizeDiff[i][j] is the size change in MB going from Refs[i] to Refs[j]
+	SizeDiff [][]float64 `json:"size_diff_matrix_mb"`
+	// LayersDiff[i][j] is the layer count change going from Refs[i] to Refs[j]
+	LayersDiff      [][]int              `json:"layers_diff_matrix"`
+	LayerComparison []RefLayerComparison `json:"layer_comparison"`
+}
+
+// RefLayerComparison represents layer sizes across refs
+type RefLayerComparison struct {
+	LayerKey     string             `json:"layer_key"`
+	LayerCommand string             `json:"layer_command"`
+	SizeByRef    map[string]float64 `json:"size_by_ref"` // ref -> size in MB, -1 when absent
+}
+
+// CompareMatrix builds every ref once and compares all of them pairwise.
+// Refs that resolve to the same commit share a single build.
+func (c *Comparer) CompareMatrix(ctx context.Context, refs []string) (*MatrixResult, error) {
+	if len(refs) < 2 {
+		return nil, fmt.Errorf("at least two refs are required")
+	}
+
+	built := make(map[plumbing.Hash]*BranchInfo)
+	result := &MatrixResult{}
+
+	for _, ref := range refs {
+		hash, err := c.repo.ResolveRevision(plumbing.Revision(ref))
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve reference %s: %w", ref, err)
+		}
+
+		info, ok := built[*hash]
+		if !ok {
+			commit, err := c.repo.CommitObject(*hash)
+			if err != nil {
+				return nil, fmt.Errorf("failed to get commit for %s: %w", ref, err)
+			}
+
+			if c.config.Verbose {
+				fmt.Fprintf(os.Stderr, "Building %s...\n", ref)
+			}
+			info, err = c.buildCommit(ctx, commit)
+			if err != nil {
+				return nil, fmt.Errorf("failed to build %s: %w", ref, err)
+			}
+			built[*hash] = info
+		}
+
+		refInfo := *info
+		refInfo.Name = ref
+		result.Refs = append(result.Refs, refInfo)
+	}
+
+	n := len(result.Refs)
+	result.SizeDiff = make([][]float64, n)
+	result.LayersDiff = make([][]int, n)
+	for i := range result.Refs {
+		result.SizeDiff[i] = make([]float64, n)
+		result.LayersDiff[i] = make([]int, n)
+		for j := range result.Refs {
+			result.SizeDiff[i][j] = result.Refs[j].SizeMB - result.Refs[i].SizeMB
+			result.LayersDiff[i][j] = result.Refs[j].Layers - result.Refs[i].Layers
+		}
+	}
+
+	result.LayerComparison = buildRefLayerComparison(result.Refs)
+
+	return result, nil
+}
+
+// buildRefLayerComparison matches layers across refs by key, in the order
+// they first appear
+func buildRefLayerComparison(refs []BranchInfo) []RefLayerComparison {
+	builds := make([][]LayerInfo, len(refs))
+	for i, ref := range refs {
+		builds[i] = ref.LayerDetails
+	}
+	matchLayers(builds...)
+
+	var keys []string
+	labels := make(map[string]string)
+	for _, ref := range refs {
+		for _, layer := range ref.LayerDetails {
+			if _, ok := labels[layer.Key]; !ok {
+				keys = append(keys, layer.Key)
+				labels[layer.Key] = layer.Label()
+			}
+		}
+	}
+
+	comparisons := make([]RefLayerComparison, 0, len(keys))
+	for _, key := range keys {
+		comparison := RefLayerComparison{
+			LayerKey:     key,
+			LayerCommand: labels[key],
+			SizeByRef:    make(map[string]float64),
+		}
+		for _, ref := range refs {
+			comparison.SizeByRef[ref.Name] = -1
+			for _, layer := range ref.LayerDetails {
+				if layer.Key == key {
+					comparison.SizeByRef[ref.Name] = layer.SizeMB
+					break
+				}
+			}
+		}
+		comparisons = append(comparisons, comparison)
+	}
+
+	return comparisons
+}
+
+// GenerateReport generates output in the specified format
+func (r *MatrixResult) GenerateReport(format string, w io.Writer) error {
+	switch format {
+	case "table":
+		return r.generateTableReport(w)
+	case "json":
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(r)
+	case "markdown":
+		return r.generateMarkdownReport(w)
+	case "chart":
+		return r.generateHTMLReport(w)
+	default:
+		return fmt.Errorf("unsupported format: %s", format)
+	}
+}
+
+// generateTableReport creates a table output
+func (r *MatrixResult) generateTableReport(w io.Writer) error {
+	fmt.Fprintln(w, "\n📊 Image Comparison")
+	fmt.Fprintln(w, "===================")
+
+	table := newPlainTable(w, []string{"Ref", "Commit", "Size (MB)", "Layers", "Time (s)"})
+	for _, ref := range r.Refs {
+		table.Append([]string{
+			truncate(ref.Name, 30),
+			ref.Commit,
+			fmt.Sprintf("%.2f", ref.SizeMB),
+			fmt.Sprintf("%d", ref.Layers),
+			fmt.Sprintf("%.1f", ref.BuildTime),
+		})
+	}
+	table.Render()
+
+	fmt.Fprintln(w, "\n↔️  Size Delta Matrix (MB, row → column):")
+	fmt.Fprintln(w, "-----------------------------------------")
+	header := []string{"From \\ To"}
+	for _, ref := range r.Refs {
+		header = append(header, truncate(ref.Name, 14))
+	}
+	matrix := newPlainTable(w, header)
+	for i, ref := range r.Refs {
+		row := []string{truncate(ref.Name, 14)}
+		for j := range r.Refs {
+			row = append(row, formatMatrixCell(i, j, r.SizeDiff[i][j]))
+		}
+		matrix.Append(row)
+	}
+	matrix.Render()
+
+	if len(r.LayerComparison) > 0 {
+		fmt.Fprintln(w, "\n📦 Layer Size Comparison Across Refs:")
+		fmt.Fprintln(w, "--------------------------------------")
+		header := []string{"Layer"}
+		for _, ref := range r.Refs {
+			header = append(header, truncate(ref.Name, 14))
+		}
+		layerTable := newPlainTable(w, header)
+		for _, layer := range r.LayerComparison {
+			row := []string{truncate(layer.LayerCommand, 40)}
+			for _, ref := range r.Refs {
+				row = append(row, markdownLayerSize(layer.SizeByRef[ref.Name]))
+			}
+			layerTable.Append(row)
+		}
+		layerTable.Render()
+	}
+
+	return nil
+}
+
+// generateMarkdownReport creates a markdown report
+func (r *MatrixResult) generateMarkdownReport(w io.Writer) error {
+	fmt.Fprintln(w, "# Docker Image Comparison")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "| Ref | Commit | Size (MB) | Layers | Time (s) |")
+	fmt.Fprintln(w, "|-----|--------|-----------|--------|----------|")
+	for _, ref := range r.Refs {
+		fmt.Fprintf(w, "| %s | %s | %.2f | %d | %.1f |\n", markdownCell(ref.Name), ref.Commit, ref.SizeMB, ref.Layers, ref.BuildTime)
+	}
+
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "## Size Delta Matrix (MB, row → column)")
+	fmt.Fprintln(w)
+	header := "| From \\ To |"
+	separator := "|-----------|"
+	for _, ref := range r.Refs {
+		header += fmt.Sprintf(" %s |", markdownCell(ref.Name))
+		separator += "----------|"
+	}
+	fmt.Fprintln(w, header)
+	fmt.Fprintln(w, separator)
+	for i, ref := range r.Refs {
+		row := fmt.Sprintf("| **%s** |", markdownCell(ref.Name))
+		for j := range r.Refs {
+			row += fmt.Sprintf(" %s |", formatMatrixCell(i, j, r.SizeDiff[i][j]))
+		}
+		fmt.Fprintln(w, row)
+	}
+
+	if len(r.LayerComparison) > 0 {
+		fmt.Fprintln(w)
+		fmt.Fprintln(w, "## Layer Size Comparison Across Refs")
+		fmt.Fprintln(w)
+		header := "| Layer |"
+		separator := "|-------|"
+		for _, ref := range r.Refs {
+			header += fmt.Sprintf(" %s |", markdownCell(ref.Name))
+			separator += "----------|"
+		}
+		fmt.Fprintln(w, header)
+		fmt.Fprintln(w, separator)
+		for _, layer := range r.LayerComparison {
+			row := fmt.Sprintf("| `%s` |", markdownCell(truncate(layer.LayerCommand, 40)))
+			for _, ref := range r.Refs {
+				row += fmt.Sprintf(" %s |", markdownLayerSize(layer.SizeByRef[ref.Name]))
+			}
+			fmt.Fprintln(w, row)
+		}
+	}
+
+	return nil
+}
+
+// markdownCell escapes the pipes of a markdown table cell, e.g. in ref names
+func markdownCell(s string) string {
+	return strings.ReplaceAll(s, "|", "\\|")
+}
+
+// generateHTMLReport creates an HTML report with a heat-colored delta matrix
+func (r *MatrixResult) generateHTMLReport(w io.Writer) error {
+	var labels []string
+	var sizes []float64
+	for _, ref := range r.Refs {
+		labels = append(labels, ref.Name)
+		sizes = append(sizes, ref.SizeMB)
+	}
+
+	// Scale cell colors by the largest absolute delta
+	var maxDelta float64
+	for i := range r.SizeDiff {
+		for j := range r.SizeDiff[i] {
+			if d := r.SizeDiff[i][j]; d > maxDelta {
+				maxDelta = d
+			} else if -d > maxDelta {
+				maxDelta = -d
+			}
+		}
+	}
+
+	var matrix strings.Builder
+	matrix.WriteString("<tr><th>From \\ To</th>")
+	for _, ref := range r.Refs {
+		matrix.WriteString("<th>" + html.EscapeString(ref.Name) + "</th>")
+	}
+	matrix.WriteString("</tr>\n")
+	for i, ref := range r.Refs {
+		matrix.WriteString("<tr><th>" + html.EscapeString(ref.Name) + "</th>")
+		for j := range r.Refs {
+			d := r.SizeDiff[i][j]
+			color := "transparent"
+			if i != j && maxDelta > 0 {
+				alpha := 0.15 + 0.6*abs(d)/maxDelta
+				if d > 0 {
+					color = fmt.Sprintf("rgba(255, 99, 132, %.2f)", alpha)
+				} else if d < 0 {
+					color = fmt.Sprintf("rgba(75, 192, 192, %.2f)", alpha)
+				}
+			}
+			fmt.Fprintf(&matrix, `<td class="size-cell" style="background:%s">%s</td>`, color, formatMatrixCell(i, j, d))
+		}
+		matrix.WriteString("</tr>\n")
+	}
+
+	var layers strings.Builder
+	layers.WriteString("<tr><th>Layer Command</th>")
+	for _, ref := range r.Refs {
+		layers.WriteString(`<th style="text-align:right">` + html.EscapeString(ref.Name) + "</th>")
+	}
+	layers.WriteString("</tr>\n")
+	for _, layer := range r.LayerComparison {
+		fmt.Fprintf(&layers, `<tr><td title="%s">%s</td>`, html.EscapeString(layer.LayerCommand), html.EscapeString(truncate(layer.LayerCommand, 80)))
+		for _, ref := range r.Refs {
+			size := layer.SizeByRef[ref.Name]
+			if size < 0 {
+				layers.WriteString(`<td class="size-cell missing">-</td>`)
+			} else {
+				fmt.Fprintf(&layers, `<td class="size-cell">%.2f</td>`, size)
+			}
+		}
+		layers.WriteString("</tr>\n")
+	}
+
+	page := fmt.Sprintf(`<!DOCTYPE html>
+<html>
+<head>
+    <title>Docker Image Comparison</title>
+    <script src="https://cdn.jsdelivr.net/npm/chart.js"></script>
+    <style>
+        body {
+            font-family: -apple-system, BlinkMacSystemFont, 'Segoe UI', Roboto, Oxygen, Ubuntu, sans-serif;
+            margin: 0;
+            padding: 20px;
+            background: #f5f5f5;
+            color: #333;
+        }
+        h1 { color: #333; margin-bottom: 30px; }
+        h2 { color: #555; margin-top: 0; font-size: 1.2em; }
+        .chart-container {
+            background: white;
+            border-radius: 8px;
+            padding: 20px;
+            margin: 20px 0;
+            box-shadow: 0 2px 4px rgba(0,0,0,0.1);
+            overflow-x: auto;
+        }
+        canvas { max-height: 400px; }
+        .note { font-size: 0.85em; color: #666; font-style: italic; margin-top: 10px; }
+        table { border-collapse: collapse; font-size: 0.9em; }
+        th, td { padding: 8px 12px; border-bottom: 1px solid #eee; white-space: nowrap; text-align: left; }
+        th { background: #f8f9fa; font-weight: 600; }
+        td:first-child { font-family: 'Monaco', 'Menlo', monospace; font-size: 0.85em; }
+        .size-cell { text-align: right; font-family: 'Monaco', 'Menlo', monospace; }
+        .size-cell.missing { color: #999; }
+    </style>
+</head>
+<body>
+    <h1>🐳 Docker Image Comparison</h1>
+
+    <div class="chart-container">
+        <h2>📊 Image Size by Ref</h2>
+        <canvas id="sizeChart"></canvas>
+    </div>
+
+    <div class="chart-container">
+        <h2>↔️ Size Delta Matrix (MB)</h2>
+        <table>%s</table>
+        <p class="note">Each cell shows the size change going from the row's ref to the column's ref. Red means growth, teal means reduction.</p>
+    </div>
+
+    <div class="chart-container">
+        <h2>📦 Layer Size Comparison Across Refs</h2>
+        <table>%s</table>
+    </div>
+
+    <script>
+        new Chart(document.getElementById('sizeChart'), {
+            type: 'bar',
+            data: {
+                labels: %s,
+                datasets: [{
+                    label: 'Image Size (MB)',
+                    data: %s,
+                    backgroundColor: 'rgba(54, 162, 235, 0.6)',
+                    borderColor: 'rgb(54, 162, 235)',
+                    borderWidth: 1
+                }]
+            },
+            options: {
+                responsive: true,
+                plugins: { legend: { display: false } },
+                scales: {
+                    y: { beginAtZero: true, title: { display: true, text: 'Size (MB)' } },
+                    x: { title: { display: true, text: 'Ref' } }
+                }
+            }
+        });
+    </script>
+</body>
+</html>`,
+		matrix.String(),
+		layers.String(),
+		toJSONArray(labels),
+		toJSONFloatArray(sizes),
+	)
+
+	_, err := w.Write([]byte(page))
+	return err
+}
+
+// newPlainTable creates a borderless, left-aligned table
+func newPlainTable(w io.Writer, header []string) *tablewriter.Table {
+	table := tablewriter.NewWriter(w)
+	table.SetHeader(header)
+	table.SetBorder(false)
+	table.SetAutoWrapText(false)
+	table.SetColumnSeparator(" ")
+	table.SetHeaderAlignment(tablewriter.ALIGN_LEFT)
+	table.SetAlignment(tablewriter.ALIGN_LEFT)
+	return table
+}
+
+// formatMatrixCell formats a matrix delta, leaving the diagonal blank
+func formatMatrixCell(i, j int, delta float64) string {
+	if i == j {
+		return "·"
+	}
+	return fmt.Sprintf("%+.2f", delta)
+}
+
+func abs(f float64) float64 {
+	if f < 0 {
+		return -f
+	}
+	return f
+}
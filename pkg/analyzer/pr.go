@@ -0,0 +1,239 @@
+package analyzer
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/utils/diff"
+	"github.com/jtodic/docker-time-machine/pkg/dockerfile"
+	"github.com/sergi/go-diff/diffmatchpatch"
+)
+
+// PRResult holds the image impact of a pull request: the head compared to
+// the merge-base with the base branch
+type PRResult struct {
+	CompareResult
+	Base              string             `json:"base"`
+	Head              string             `json:"head"`
+	MergeBase         string             `json:"merge_base"`
+	LayerDeltas       []LayerDelta       `json:"layer_deltas"`
+	DockerfileChanges []DockerfileChange `json:"dockerfile_changes"`
+}
+
+// DockerfileChange is a single added or removed Dockerfile line
+type DockerfileChange struct {
+	Type        string `json:"type"` // "added" or "removed"
+	Line        int    `json:"line"` // line in head for added lines, in merge-base for removed lines
+	Text        string `json:"text"`
+	Instruction string `json:"instruction,omitempty"` // enclosing instruction, e.g. "RUN L12-14"
+}
+
+// ComparePR builds the merge-base of base and head and the head itself, and
+// compares both images layer by layer
+func (c *Comparer) ComparePR(ctx context.Context, base, head string) (*PRResult, error) {
+	headHash, err := c.repo.ResolveRevision(plumbing.Revision(head))
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve reference %s: %w", head, err)
+	}
+
+	headCommit, err := c.repo.CommitObject(*headHash)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get commit %s: %w", head, err)
+	}
+
+	mergeBase, err := resolveMergeBase(c.repo, base, *headHash)
+	if err != nil {
+		return nil, err
+	}
+
+	if c.config.Verbose {
+		fmt.Fprintf(os.Stderr, "Building merge-base %s...\n", mergeBase.Hash.String()[:8])
+	}
+	baseInfo, err := c.buildCommit(ctx, mergeBase)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build merge-base: %w", err)
+	}
+	baseInfo.Name = base
+
+	if c.config.Verbose {
+		fmt.Fprintf(os.Stderr, "Building head %s...\n", headCommit.Hash.String()[:8])
+	}
+	headInfo, err := c.buildCommit(ctx, headCommit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build head: %w", err)
+	}
+	headInfo.Name = head
+
+	changes, err := c.dockerfileChanges(mergeBase, headCommit)
+	if err != nil && c.config.Verbose {
+		fmt.Fprintf(os.Stderr, "  ⚠️ Could not diff Dockerfile: %v\n", err)
+	}
+
+	return &PRResult{
+		CompareResult:     *newCompareResult(baseInfo, headInfo),
+		Base:              base,
+		Head:              head,
+		MergeBase:         mergeBase.Hash.String(),
+		LayerDeltas:       diffLayers(baseInfo.LayerDetails, headInfo.LayerDetails),
+		DockerfileChanges: changes,
+	}, nil
+}
+
+// dockerfileChanges returns the Dockerfile lines that differ between two
+// commits, each annotated with the instruction it belongs to
+func (c *Comparer) dockerfileChanges(from, to *object.Commit) ([]DockerfileChange, error) {
+	before, err := fileContents(from, c.config.DockerfilePath)
+	if err != nil {
+		return nil, err
+	}
+	after, err := fileContents(to, c.config.DockerfilePath)
+	if err != nil {
+		return nil, err
+	}
+
+	dfBefore, _ := dockerfile.Parse(strings.NewReader(before))
+	dfAfter, _ := dockerfile.Parse(strings.NewReader(after))
+
+	var changes []DockerfileChange
+	lineBefore, lineAfter := 1, 1
+	for _, d := range diff.Do(before, after) {
+		lines := strings.SplitAfter(d.Text, "\n")
+		if lines[len(lines)-1] == "" {
+			lines = lines[:len(lines)-1]
+		}
+
+		for _, line := range lines {
+			text := strings.TrimRight(line, "\r\n")
+			switch d.Type {
+			case diffmatchpatch.DiffEqual:
+				lineBefore++
+				lineAfter++
+			case diffmatchpatch.DiffDelete:
+				changes = append(changes, DockerfileChange{
+					Type:        "removed",
+					Line:        lineBefore,
+					Text:        text,
+					Instruction: instructionAt(dfBefore, lineBefore),
+				})
+				lineBefore++
+			case diffmatchpatch.DiffInsert:
+				changes = append(changes, DockerfileChange{
+					Type:        "added",
+					Line:        lineAfter,
+					Text:        text,
+					Instruction: instructionAt(dfAfter, lineAfter),
+				})
+				lineAfter++
+			}
+		}
+	}
+
+	return changes, nil
+}
+
+// fileContents returns the contents of a file in a commit, or an empty
+// string if the file doesn't exist there
+func fileContents(commit *object.Commit, path string) (string, error) {
+	file, err := commit.File(path)
+	if err == object.ErrFileNotFound {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	return file.Contents()
+}
+
+// instructionAt describes the instruction that spans the given line
+func instructionAt(df *dockerfile.Dockerfile, line int) string {
+	if df == nil {
+		return ""
+	}
+	for _, stage := range df.Stages {
+		if stage.FromLine == line {
+			return fmt.Sprintf("FROM L%d", line)
+		}
+		for _, inst := range stage.Instructions {
+			if line >= inst.StartLine && line <= inst.EndLine {
+				return inst.Keyword + " " + inst.Lines()
+			}
+		}
+	}
+	return ""
+}
+
+// WriteMarkdown writes a compact summary suitable for a pull request comment
+func (r *PRResult) WriteMarkdown(w io.Writer) error {
+	icon := "➖"
+	switch {
+	case r.SizeDiff > 0:
+		icon = "📈"
+	case r.SizeDiff < 0:
+		icon = "📉"
+	}
+
+	fmt.Fprintln(w, "## 🐳 Docker Image Impact")
+	fmt.Fprintln(w)
+	fmt.Fprintf(w, "%s **%+.2f MB** (%+.1f%%) · %+d layers · merge-base `%s` → head `%s`\n",
+		icon, r.SizeDiff, r.SizeDiffPercent, r.LayersDiff, r.MergeBase[:8], r.BranchB.Commit)
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "| | Base | Head | Diff |")
+	fmt.Fprintln(w, "|---|---|---|---|")
+	fmt.Fprintf(w, "| Size (MB) | %.2f | %.2f | %+.2f |\n", r.BranchA.SizeMB, r.BranchB.SizeMB, r.SizeDiff)
+	fmt.Fprintf(w, "| Layers | %d | %d | %+d |\n", r.BranchA.Layers, r.BranchB.Layers, r.LayersDiff)
+
+	if len(r.LayerDeltas) > 0 {
+		fmt.Fprintln(w)
+		fmt.Fprintln(w, "### Layer Changes")
+		fmt.Fprintln(w)
+		fmt.Fprintln(w, "| Layer | Base (MB) | Head (MB) | Diff |")
+		fmt.Fprintln(w, "|-------|-----------|-----------|------|")
+		for _, delta := range r.LayerDeltas {
+			fmt.Fprintf(w, "| `%s` | %s | %s | %+.2f |\n",
+				strings.ReplaceAll(truncate(delta.Command, 60), "|", "\\|"),
+				markdownLayerSize(delta.BeforeMB),
+				markdownLayerSize(delta.AfterMB),
+				delta.DiffMB)
+		}
+	}
+
+	if len(r.DockerfileChanges) > 0 {
+		fmt.Fprintln(w)
+		fmt.Fprintln(w, "<details><summary>Dockerfile changes</summary>")
+		fmt.Fprintln(w)
+		fmt.Fprintln(w, "```diff")
+		for _, change := range r.DockerfileChanges {
+			sign := "+"
+			if change.Type == "removed" {
+				sign = "-"
+			}
+			fmt.Fprintf(w, "%s %4d  %s\n", sign, change.Line, change.Text)
+		}
+		fmt.Fprintln(w, "```")
+		fmt.Fprintln(w)
+		fmt.Fprintln(w, "</details>")
+	}
+
+	return nil
+}
+
+// WriteJSON writes the full result as indented JSON
+func (r *PRResult) WriteJSON(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(r)
+}
+
+// markdownLayerSize formats a layer size in MB, using "-" for absent layers
+func markdownLayerSize(sizeMB float64) string {
+	if sizeMB < 0 {
+		return "-"
+	}
+	return fmt.Sprintf("%.2f", sizeMB)
+}
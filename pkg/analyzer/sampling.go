@@ -0,0 +1,182 @@
+package analyzer
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/schollz/progressbar/v3"
+)
+
+// defaultCoarseSamples is the number of commits built in the first pass of
+// adaptive sampling when no explicit step is given
+const defaultCoarseSamples = 10
+
+// sampleCommits reduces a newest-first list of commits according to the
+// sampling mode. Calendar modes keep the newest commit of each day, ISO week
+// or month; every > 1 keeps every Nth commit of the result.
+func sampleCommits(commits []*object.Commit, mode string, every int) ([]*object.Commit, error) {
+	var periodKey func(c *object.Commit) string
+
+	switch mode {
+	case "":
+	case "daily":
+		periodKey = func(c *object.Commit) string {
+			return c.Author.When.UTC().Format("2006-01-02")
+		}
+	case "weekly":
+		periodKey = func(c *object.Commit) string {
+			year, week := c.Author.When.UTC().ISOWeek()
+			return fmt.Sprintf("%d-W%02d", year, week)
+		}
+	case "monthly":
+		periodKey = func(c *object.Commit) string {
+			return c.Author.When.UTC().Format("2006-01")
+		}
+	default:
+		return nil, fmt.Errorf("unsupported sampling mode: %s (use daily, weekly, monthly or adaptive)", mode)
+	}
+
+	sampled := commits
+	if periodKey != nil {
+		sampled = nil
+		seen := make(map[string]bool)
+		for _, c := range commits {
+			key := periodKey(c)
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+			sampled = append(sampled, c)
+		}
+	}
+
+	if every > 1 {
+		var nth []*object.Commit
+		for i := 0; i < len(sampled); i += every {
+			nth = append(nth, sampled[i])
+		}
+		sampled = nth
+	}
+
+	return sampled, nil
+}
+
+// coarseSample returns the indices built in the first pass of adaptive
+// sampling: every step-th commit plus the oldest one, so the whole range is
+// covered
+func coarseSample(n, step int) []int {
+	if n == 0 {
+		return nil
+	}
+	if step <= 0 {
+		step = (n + defaultCoarseSamples - 1) / defaultCoarseSamples
+	}
+	if step < 1 {
+		step = 1
+	}
+
+	var indices []int
+	for i := 0; i < n; i += step {
+		indices = append(indices, i)
+	}
+	if indices[len(indices)-1] != n-1 {
+		indices = append(indices, n-1)
+	}
+	return indices
+}
+
+// runAdaptive builds a coarse sample of the commits, then repeatedly builds
+// the commit halfway between two consecutive successful builds whose sizes
+// differ by more than the threshold, until every such jump is pinned down to
+// adjacent commits or the MaxCommits budget is spent. Results are returned in
+// the original newest-first order.
+func (tm *TimeMachine) runAdaptive(ctx context.Context, commits []*object.Commit, bar *progressbar.ProgressBar) []BuildResult {
+	threshold := int64(tm.config.AdaptiveThreshold * 1024 * 1024)
+	built := make(map[int]BuildResult)
+
+	budgetLeft := func() bool {
+		return tm.config.MaxCommits <= 0 || len(built) < tm.config.MaxCommits
+	}
+
+	for _, i := range coarseSample(len(commits), tm.config.Every) {
+		if !budgetLeft() {
+			break
+		}
+		built[i] = tm.buildWithProgress(ctx, commits[i], bar)
+	}
+
+	for budgetLeft() {
+		// Successful builds in history order
+		var ok []int
+		for i, r := range built {
+			if r.Error == "" {
+				ok = append(ok, i)
+			}
+		}
+		sort.Ints(ok)
+
+		var targets []int
+		for k := 0; k+1 < len(ok); k++ {
+			a, b := ok[k], ok[k+1]
+			diff := built[a].ImageSize - built[b].ImageSize
+			if diff < 0 {
+				diff = -diff
+			}
+			if diff <= threshold {
+				continue
+			}
+			if m, found := unbuiltBetween(built, a, b); found {
+				targets = append(targets, m)
+			}
+		}
+
+		if len(targets) == 0 {
+			break
+		}
+
+		bar.ChangeMax(bar.GetMax() + len(targets))
+		for _, m := range targets {
+			if !budgetLeft() {
+				break
+			}
+			if tm.config.Verbose {
+				fmt.Fprintf(os.Stderr, "\n🔎 Refining between sampled commits at %s\n", commits[m].Hash.String()[:8])
+			}
+			built[m] = tm.buildWithProgress(ctx, commits[m], bar)
+		}
+	}
+
+	indices := make([]int, 0, len(built))
+	for i := range built {
+		indices = append(indices, i)
+	}
+	sort.Ints(indices)
+
+	results := make([]BuildResult, 0, len(indices))
+	for _, i := range indices {
+		results = append(results, built[i])
+	}
+	return results
+}
+
+// unbuiltBetween finds the commit index closest to the midpoint of (a, b)
+// that hasn't been built yet
+func unbuiltBetween(built map[int]BuildResult, a, b int) (int, bool) {
+	mid := (a + b) / 2
+	for offset := 0; mid-offset > a || mid+offset < b; offset++ {
+		if i := mid - offset; i > a {
+			if _, done := built[i]; !done {
+				return i, true
+			}
+		}
+		if i := mid + offset; i < b {
+			if _, done := built[i]; !done {
+				return i, true
+			}
+		}
+	}
+	return 0, false
+}
@@ -0,0 +1,30 @@
+package analyzer
+
+import (
+	"path/filepath"
+
+	"github.com/jtodic/docker-time-machine/pkg/sbom"
+)
+
+// SBOMs returns the SBOM documents of the successful builds, newest first.
+// Builds whose image could not be scanned are skipped.
+func (tm *TimeMachine) SBOMs() []sbom.Document {
+	name := filepath.Base(tm.config.RepoPath)
+	if abs, err := filepath.Abs(tm.config.RepoPath); err == nil {
+		name = filepath.Base(abs)
+	}
+
+	var docs []sbom.Document
+	for _, r := range tm.results {
+		if r.Error != "" || r.Result == nil {
+			continue
+		}
+		docs = append(docs, sbom.Document{
+			Image:      name,
+			Version:    r.CommitHash[:8],
+			Created:    r.Date,
+			Components: r.SBOM,
+		})
+	}
+	return docs
+}
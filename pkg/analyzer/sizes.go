@@ -0,0 +1,184 @@
+package analyzer
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/distribution/reference"
+	"github.com/jtodic/docker-time-machine/pkg/base"
+	"github.com/jtodic/docker-time-machine/pkg/dockerfile"
+	"github.com/jtodic/docker-time-machine/pkg/inspect"
+	"github.com/jtodic/docker-time-machine/pkg/report"
+)
+
+// measureCompressed gzips the layer tarballs of an exported image to find the
+// compressed size of the image and of each of its layers, as a registry would
+// store them
+func measureCompressed(archive *inspect.Archive, cache inspect.SizeCache, result *BuildResult) error {
+	compressed := make([]int64, len(archive.Layers))
+	var total int64
+	for i, layer := range archive.Layers {
+		size, err := cache.Measure(layer)
+		if err != nil {
+			return err
+		}
+		compressed[i] = size.Compressed
+		total += size.Compressed
+	}
+	result.CompressedSize = total
+
+	// The archive layers follow the non-empty entries of the config history,
+	// which LayerInfo.history indexes
+	byHistory := make(map[int]int64)
+	i := 0
+	for j, h := range archive.History {
+		if h.EmptyLayer || i >= len(compressed) {
+			continue
+		}
+		byHistory[j] = compressed[i]
+		i++
+	}
+	for k := range result.Layers {
+		result.Layers[k].CompressedSize = byHistory[result.Layers[k].history]
+	}
+
+	return nil
+}
+
+// applySizeBasis sets the sizes that drive diffs, charts and insights
+func applySizeBasis(result *BuildResult, basis report.SizeBasis) {
+	if basis != report.Compressed {
+		return
+	}
+	result.ImageSize = result.CompressedSize
+	for i := range result.Layers {
+		result.Layers[i].Size = result.Layers[i].CompressedSize
+		result.Layers[i].SizeMB = float64(result.Layers[i].CompressedSize) / 1024 / 1024
+	}
+}
+
+// baseImage is a base image resolved from the local image store or from its
+// registry
+type baseImage struct {
+	digest string   // repo or manifest digest, empty when unknown
+	layers []string // layer diff IDs, lowest first
+}
+
+// splitBase divides a build into the layers of its base image and the layers
+// of the Dockerfile. When the base image could be resolved, its layers are
+// matched against the lowest layers of the build, as in registry mode.
+// Otherwise, or when they do not match, the layers no Dockerfile instruction
+// created are taken as the base. imageLayers are the layer diff IDs of the
+// build, lowest first; resolved is nil when the base image was not found.
+func splitBase(result BuildResult, df *dockerfile.Dockerfile, imageLayers []string, resolved *baseImage) *base.Split {
+	split := &base.Split{Image: df.BaseImage()}
+	isBase := func(layer LayerInfo) bool { return layer.StartLine == 0 }
+	if resolved != nil {
+		split.Digest = resolved.digest
+		if n := base.PrefixLength(imageLayers, resolved.layers); n > 0 && hasDigests(result.Layers) {
+			prefix := make(map[string]bool)
+			for _, digest := range imageLayers[:n] {
+				prefix[digest] = true
+			}
+			isBase = func(layer LayerInfo) bool { return prefix[layer.Digest] }
+		}
+	}
+	split.Unresolved = split.Digest == "" && resolvable(split.Image)
+
+	var digests []string
+	for _, layer := range result.Layers {
+		if !isBase(layer) {
+			continue
+		}
+		split.Layers++
+		split.Base += layer.Size
+		digest := layer.Digest
+		if digest == "" {
+			digest = fmt.Sprintf("%s@%d", layer.Key, layer.Size)
+		}
+		// Layers are newest first, the ID expects lowest first
+		digests = append([]string{digest}, digests...)
+	}
+	split.ID = base.LayerID(digests)
+	split.App = result.ImageSize - split.Base
+	return split
+}
+
+// hasDigests reports whether the diff ID of every layer is known
+func hasDigests(layers []LayerInfo) bool {
+	for _, layer := range layers {
+		if layer.Digest == "" {
+			return false
+		}
+	}
+	return len(layers) > 0
+}
+
+// resolvable reports whether a FROM reference names an image that can be
+// looked up, rather than scratch or a reference built from build args
+func resolvable(image string) bool {
+	return image != "" && image != "scratch" && !strings.Contains(image, "$")
+}
+
+// resolveBase looks up a base image in the local image store and, since
+// BuildKit usually does not keep pulled base images there, otherwise in its
+// registry for the platform of the build. Lookups are cached per reference
+// and platform; nil is returned when the image could not be resolved.
+func (tm *TimeMachine) resolveBase(ctx context.Context, image, platform string) *baseImage {
+	if !resolvable(image) {
+		return nil
+	}
+	key := image + " " + platform
+	if resolved, ok := tm.bases[key]; ok {
+		return resolved
+	}
+
+	var resolved *baseImage
+	if info, err := tm.builder.GetImageInfo(ctx, image); err == nil {
+		resolved = &baseImage{digest: baseDigest(image, info.RepoDigests), layers: info.RootFS.Layers}
+	}
+	if resolved == nil || resolved.digest == "" {
+		name, tag := base.SplitReference(image)
+		metadata, err := tm.registry.GetImageMetadata(ctx, name, tag, platform)
+		if err == nil {
+			resolved = &baseImage{digest: metadata.Digest, layers: metadata.DiffIDs}
+		} else if tm.config.Verbose {
+			fmt.Fprintf(os.Stderr, "  ⚠️ Could not resolve base image %s: %v\n", image, err)
+		}
+	}
+	tm.bases[key] = resolved
+	return resolved
+}
+
+// baseDigest returns the repo digest of the base image among the repo digests
+// of the local image, comparing normalized repository names so that "node"
+// matches "docker.io/library/node" but not "myorg/mynode"
+func baseDigest(image string, repoDigests []string) string {
+	named, err := reference.ParseNormalizedNamed(image)
+	if err != nil {
+		return ""
+	}
+	for _, repoDigest := range repoDigests {
+		ref, err := reference.ParseNormalizedNamed(repoDigest)
+		if err != nil {
+			continue
+		}
+		if canonical, ok := ref.(reference.Canonical); ok && ref.Name() == named.Name() {
+			return canonical.Digest().String()
+		}
+	}
+	return ""
+}
+
+// baseVersions pairs each successful build with its base split
+func baseVersions(results []BuildResult) []base.Version {
+	var versions []base.Version
+	for _, r := range results {
+		if r.Error == "" {
+			versions = append(versions, base.Version{Name: r.CommitHash[:8], Split: r.Base})
+		}
+	}
+	return versions
+}
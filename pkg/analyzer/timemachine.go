@@ -13,7 +13,15 @@ import (
 	"github.com/go-git/go-git/v5"
 	"github.com/go-git/go-git/v5/plumbing"
 	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/jtodic/docker-time-machine/pkg/base"
 	"github.com/jtodic/docker-time-machine/pkg/docker"
+	"github.com/jtodic/docker-time-machine/pkg/dockerfile"
+	"github.com/jtodic/docker-time-machine/pkg/imgconfig"
+	"github.com/jtodic/docker-time-machine/pkg/inspect"
+	"github.com/jtodic/docker-time-machine/pkg/lint"
+	"github.com/jtodic/docker-time-machine/pkg/pull"
+	"github.com/jtodic/docker-time-machine/pkg/report"
+	"github.com/jtodic/docker-time-machine/pkg/scan"
 	"github.com/olekukonko/tablewriter"
 	"github.com/schollz/progressbar/v3"
 )
@@ -28,14 +36,54 @@ type Config struct {
 	Until          string
 	SkipFailed     bool
 	Verbose        bool
+
+	// Sampling of long histories
+	Sample            string  // "", "daily", "weekly", "monthly" or "adaptive"
+	Every             int     // analyze every Nth commit (coarse step in adaptive mode)
+	AdaptiveThreshold float64 // MB difference that triggers refinement in adaptive mode
+
+	// Scan selects the features that read the layer tarballs of each build
+	Scan scan.Options
+
+	// Lint checks the Dockerfile of each commit and ranks the issues by the
+	// size of the layers they affect
+	Lint bool
+
+	// SizeBasis selects the sizes that drive diffs, charts and insights.
+	// Compressed sizes are measured by gzipping the saved layers.
+	SizeBasis report.SizeBasis
 }
 
 // LayerInfo represents information about a single Docker image layer
 type LayerInfo struct {
-	ID        string  `json:"id,omitempty"`
-	CreatedBy string  `json:"created_by"`
-	Size      int64   `json:"size"`
-	SizeMB    float64 `json:"size_mb"`
+	ID          string  `json:"id,omitempty"`
+	Digest      string  `json:"digest,omitempty"` // layer diff ID
+	CreatedBy   string  `json:"created_by"`
+	Size        int64   `json:"size"`
+	SizeMB      float64 `json:"size_mb"`
+	Key         string  `json:"key"`                   // identity used to match the layer across commits
+	Stage       string  `json:"stage,omitempty"`       // build stage name or index
+	Instruction string  `json:"instruction,omitempty"` // Dockerfile keyword, e.g. RUN
+	StartLine   int     `json:"start_line,omitempty"`
+	EndLine     int     `json:"end_line,omitempty"`
+
+	// Size follows the size basis; both sizes are kept when known
+	CompressedSize   int64 `json:"compressed_size,omitempty"`
+	UncompressedSize int64 `json:"uncompressed_size,omitempty"`
+
+	history int // index in the image config history, oldest first
+}
+
+// Label returns a display label for the layer, prefixed with its Dockerfile
+// line range when known
+func (l LayerInfo) Label() string {
+	if l.StartLine == 0 {
+		return l.CreatedBy
+	}
+	if l.EndLine > l.StartLine {
+		return fmt.Sprintf("L%d-%d %s", l.StartLine, l.EndLine, l.CreatedBy)
+	}
+	return fmt.Sprintf("L%d %s", l.StartLine, l.CreatedBy)
 }
 
 // BuildResult represents the result of building a Docker image at a specific commit
@@ -50,20 +98,45 @@ type BuildResult struct {
 	Layers        []LayerInfo `json:"layers,omitempty"`
 	Error         string      `json:"error,omitempty"`
 	SizeDiff      int64       `json:"size_diff,omitempty"`
+
+	// ImageSize follows the size basis; both sizes are kept when known
+	CompressedSize   int64 `json:"compressed_size,omitempty"`
+	UncompressedSize int64 `json:"uncompressed_size,omitempty"`
+
+	// Base splits the image size into base image and application layers
+	Base *base.Split `json:"base,omitempty"`
+
+	// PullCost holds the bytes to download when upgrading from the previous
+	// successful build
+	PullCost *pull.Cost `json:"pull_cost,omitempty"`
+
+	// Lint holds the Dockerfile issues when linting is enabled
+	Lint []lint.Issue `json:"lint,omitempty"`
+
+	// Config holds the user, entrypoint, env and other runtime settings
+	Config *imgconfig.Config `json:"config,omitempty"`
+
+	// Result holds the layer contents findings when scanning is enabled
+	*scan.Result
 }
 
 // LayerComparison represents layer sizes across commits
 type LayerComparison struct {
+	LayerKey     string             `json:"layer_key"`
 	LayerCommand string             `json:"layer_command"`
 	SizeByCommit map[string]float64 `json:"size_by_commit"` // commit hash -> size in MB
 }
 
 // TimeMachine is the main analyzer
 type TimeMachine struct {
-	config  Config
-	repo    *git.Repository
-	builder *docker.Builder
-	results []BuildResult
+	config     Config
+	repo       *git.Repository
+	builder    *docker.Builder
+	results    []BuildResult
+	layerSizes inspect.SizeCache
+	// registry resolves base images the builder did not keep locally
+	registry *docker.RegistryClient
+	bases    map[string]*baseImage
 }
 
 // NewTimeMachine creates a new TimeMachine instance
@@ -90,10 +163,13 @@ func NewTimeMachine(config Config) (*TimeMachine, error) {
 	}
 
 	return &TimeMachine{
-		config:  config,
-		repo:    repo,
-		builder: builder,
-		results: []BuildResult{},
+		config:     config,
+		repo:       repo,
+		builder:    builder,
+		results:    []BuildResult{},
+		layerSizes: make(inspect.SizeCache),
+		registry:   docker.NewRegistryClient(),
+		bases:      make(map[string]*baseImage),
 	}, nil
 }
 
@@ -109,10 +185,27 @@ func (tm *TimeMachine) Run(ctx context.Context) error {
 		return fmt.Errorf("no commits found that modified %s", tm.config.DockerfilePath)
 	}
 
-	fmt.Fprintf(os.Stderr, "🚀 Found %d commits to analyze\n", len(commits))
+	adaptive := tm.config.Sample == "adaptive"
+	if !adaptive {
+		commits, err = sampleCommits(commits, tm.config.Sample, tm.config.Every)
+		if err != nil {
+			return err
+		}
+		if tm.config.MaxCommits > 0 && len(commits) > tm.config.MaxCommits {
+			commits = commits[:tm.config.MaxCommits]
+		}
+	}
+
+	total := len(commits)
+	if adaptive {
+		fmt.Fprintf(os.Stderr, "🚀 Found %d commits, sampling adaptively\n", len(commits))
+		total = len(coarseSample(len(commits), tm.config.Every))
+	} else {
+		fmt.Fprintf(os.Stderr, "🚀 Found %d commits to analyze\n", len(commits))
+	}
 
 	// Create progress bar
-	bar := progressbar.NewOptions(len(commits),
+	bar := progressbar.NewOptions(total,
 		progressbar.OptionEnableColorCodes(true),
 		progressbar.OptionShowCount(),
 		progressbar.OptionSetWidth(40),
@@ -131,22 +224,11 @@ func (tm *TimeMachine) Run(ctx context.Context) error {
 	}
 
 	// Analyze each commit
-	for _, commit := range commits {
-		bar.Add(1)
-
-		if tm.config.Verbose {
-			fmt.Fprintf(os.Stderr, "\n📦 Building at commit %s: %s\n",
-				commit.Hash.String()[:8],
-				strings.Split(commit.Message, "\n")[0])
-		}
-
-		result := tm.analyzeCommit(ctx, commit)
-		tm.results = append(tm.results, result)
-
-		if result.Error != "" && !tm.config.SkipFailed {
-			if tm.config.Verbose {
-				fmt.Fprintf(os.Stderr, "  ❌ Build failed: %s\n", result.Error)
-			}
+	if adaptive {
+		tm.results = tm.runAdaptive(ctx, commits, bar)
+	} else {
+		for _, commit := range commits {
+			tm.results = append(tm.results, tm.buildWithProgress(ctx, commit, bar))
 		}
 	}
 
@@ -167,6 +249,16 @@ func (tm *TimeMachine) Run(ctx context.Context) error {
 		// If no older commit found, SizeDiff remains 0 (the oldest commit has no diff)
 	}
 
+	// Give each logical layer the same key in every build
+	builds := make([][]LayerInfo, len(tm.results))
+	for i := range tm.results {
+		builds[i] = tm.results[i].Layers
+	}
+	matchLayers(builds...)
+
+	base.Diff(baseVersions(tm.results))
+	tm.computePullCosts()
+
 	// Restore original branch
 	var checkoutErr error
 	if originalRef.Name().IsBranch() {
@@ -191,7 +283,8 @@ func (tm *TimeMachine) Run(ctx context.Context) error {
 	return nil
 }
 
-// getCommits retrieves all commits
+// getCommits retrieves all commits matching the date filters, newest first.
+// Sampling and the MaxCommits limit are applied by the caller.
 func (tm *TimeMachine) getCommits() ([]*object.Commit, error) {
 	var commits []*object.Commit
 	seen := make(map[string]bool) // Track seen commit hashes to avoid duplicates
@@ -238,7 +331,6 @@ func (tm *TimeMachine) getCommits() ([]*object.Commit, error) {
 		return nil, fmt.Errorf("failed to get log: %w", err)
 	}
 
-	count := 0
 	err = commitIter.ForEach(func(c *object.Commit) error {
 		// Skip duplicate commits (can happen with merge commits in git history)
 		commitHash := c.Hash.String()
@@ -255,12 +347,7 @@ func (tm *TimeMachine) getCommits() ([]*object.Commit, error) {
 			return nil
 		}
 
-		if tm.config.MaxCommits > 0 && count >= tm.config.MaxCommits {
-			return nil
-		}
-
 		commits = append(commits, c)
-		count++
 		return nil
 	})
 
@@ -271,6 +358,27 @@ func (tm *TimeMachine) getCommits() ([]*object.Commit, error) {
 	return commits, nil
 }
 
+// buildWithProgress analyzes a commit while advancing the progress bar
+func (tm *TimeMachine) buildWithProgress(ctx context.Context, commit *object.Commit, bar *progressbar.ProgressBar) BuildResult {
+	bar.Add(1)
+
+	if tm.config.Verbose {
+		fmt.Fprintf(os.Stderr, "\n📦 Building at commit %s: %s\n",
+			commit.Hash.String()[:8],
+			strings.Split(commit.Message, "\n")[0])
+	}
+
+	result := tm.analyzeCommit(ctx, commit)
+
+	if result.Error != "" && !tm.config.SkipFailed {
+		if tm.config.Verbose {
+			fmt.Fprintf(os.Stderr, "  ❌ Build failed: %s\n", result.Error)
+		}
+	}
+
+	return result
+}
+
 // analyzeCommit checks out a commit and builds the Docker image
 func (tm *TimeMachine) analyzeCommit(ctx context.Context, commit *object.Commit) BuildResult {
 	result := BuildResult{
@@ -301,15 +409,7 @@ func (tm *TimeMachine) analyzeCommit(ctx context.Context, commit *object.Commit)
 	imageName := fmt.Sprintf("dtm-%s", commit.Hash.String()[:12])
 
 	// Determine context path and Dockerfile name
-	contextPath := tm.config.RepoPath
-	dockerfileName := tm.config.DockerfilePath
-
-	// If Dockerfile path contains directory, adjust context and dockerfile name
-	if strings.Contains(tm.config.DockerfilePath, "/") {
-		dir := filepath.Dir(tm.config.DockerfilePath)
-		contextPath = filepath.Join(tm.config.RepoPath, dir)
-		dockerfileName = filepath.Base(tm.config.DockerfilePath)
-	}
+	contextPath, dockerfileName := splitDockerfilePath(tm.config.RepoPath, tm.config.DockerfilePath)
 
 	// Build the image
 	err = tm.builder.BuildImage(ctx, contextPath, dockerfileName, imageName)
@@ -329,24 +429,40 @@ func (tm *TimeMachine) analyzeCommit(ctx context.Context, commit *object.Commit)
 	}
 
 	result.ImageSize = imageInfo.Size
+	result.UncompressedSize = imageInfo.Size
 	result.LayerCount = len(imageInfo.RootFS.Layers)
+	result.Config = imgconfig.FromImage(imageInfo.Config)
+
+	// Map history entries back to the Dockerfile instructions that created them
+	df, dfErr := dockerfile.ParseFile(filepath.Join(tm.config.RepoPath, tm.config.DockerfilePath))
+	if dfErr != nil && tm.config.Verbose {
+		fmt.Fprintf(os.Stderr, "  ⚠️ Could not parse Dockerfile, matching layers by command: %v\n", dfErr)
+	}
 
 	// Get layer history for detailed layer information
-	history, err := tm.builder.GetImageHistory(ctx, imageName)
-	if err == nil {
-		for _, layer := range history {
-			// Skip empty layers (metadata-only)
-			if layer.Size == 0 {
-				continue
-			}
-			layerInfo := LayerInfo{
-				ID:        layer.ID,
-				CreatedBy: truncateLayerCommand(layer.CreatedBy),
-				Size:      layer.Size,
-				SizeMB:    float64(layer.Size) / 1024 / 1024,
-			}
-			result.Layers = append(result.Layers, layerInfo)
+	if layers, err := collectLayers(ctx, tm.builder, imageName, df); err == nil {
+		result.Layers = layers
+	}
+
+	// Read the layer tarballs before the image is removed. Both sizes are
+	// always tracked; the size basis only selects the one that drives diffs.
+	if err := tm.readLayers(ctx, imageName, df, &result); err != nil {
+		result.Error = err.Error()
+		tm.builder.RemoveImage(ctx, imageName)
+		return result
+	}
+	applySizeBasis(&result, tm.config.SizeBasis)
+	if df != nil {
+		platform := imageInfo.Os + "/" + imageInfo.Architecture
+		if imageInfo.Variant != "" {
+			platform += "/" + imageInfo.Variant
 		}
+		resolved := tm.resolveBase(ctx, df.BaseImage(), platform)
+		result.Base = splitBase(result, df, imageInfo.RootFS.Layers, resolved)
+	}
+
+	if tm.config.Lint && df != nil {
+		result.Lint = lint.Lint(df, lintSizes(result.Layers))
 	}
 
 	// Clean up the image
@@ -355,37 +471,74 @@ func (tm *TimeMachine) analyzeCommit(ctx context.Context, commit *object.Commit)
 	return result
 }
 
-// buildLayerComparison builds layer comparison data across commits
-func (tm *TimeMachine) buildLayerComparison(validResults []BuildResult) ([]string, []LayerComparison) {
-	// Collect all unique layer commands across all commits
-	layerCommands := make([]string, 0)
-	layerCommandSet := make(map[string]bool)
+// readLayers exports a built image once to measure its compressed size and for
+// the features that read its layer tarballs. A failed scan is only reported; a
+// failed measurement of the size that drives the report fails the commit,
+// since its sizes could not be compared with the others.
+func (tm *TimeMachine) readLayers(ctx context.Context, imageName string, df *dockerfile.Dockerfile, result *BuildResult) error {
+	archive, err := exportImage(ctx, tm.builder, imageName)
+	if err != nil {
+		if tm.config.SizeBasis == report.Compressed {
+			return err
+		}
+		if tm.config.Verbose {
+			fmt.Fprintf(os.Stderr, "  ⚠️ Layer scan failed: %v\n", err)
+		}
+		return nil
+	}
+	defer archive.Close()
 
-	// Use the latest commit's layers as the base order
-	if len(validResults) > 0 {
-		for _, layer := range validResults[0].Layers {
-			if !layerCommandSet[layer.CreatedBy] {
-				layerCommands = append(layerCommands, layer.CreatedBy)
-				layerCommandSet[layer.CreatedBy] = true
+	if err := measureCompressed(archive, tm.layerSizes, result); err != nil {
+		if tm.config.SizeBasis == report.Compressed {
+			return fmt.Errorf("failed to measure compressed size: %w", err)
+		}
+		if tm.config.Verbose {
+			fmt.Fprintf(os.Stderr, "  ⚠️ Failed to measure compressed size: %v\n", err)
+		}
+	}
+
+	if tm.config.Scan.Enabled() {
+		scanned, err := scanArchive(archive, df, tm.config.Scan)
+		if err != nil {
+			if tm.config.Verbose {
+				fmt.Fprintf(os.Stderr, "  ⚠️ Layer scan failed: %v\n", err)
 			}
+		} else {
+			result.Result = scanned
 		}
 	}
 
-	// Add any layers from other commits that aren't in the latest
-	for _, result := range validResults[1:] {
+	return nil
+}
+
+// buildLayerComparison builds layer comparison data across commits.
+// Layers are matched by their Key, which is derived from the Dockerfile
+// instruction that created them when available and from the layer digest and
+// normalized command otherwise, so a layer keeps its row when the
+// instruction's text changes between commits.
+func (tm *TimeMachine) buildLayerComparison(validResults []BuildResult) ([]string, []LayerComparison) {
+	// Collect all unique layer keys across all commits
+	layerKeys := make([]string, 0)
+	layerLabels := make(map[string]string)
+
+	// Use the latest commit's layers as the base order, then add any layers
+	// from other commits that aren't in the latest
+	for _, result := range validResults {
 		for _, layer := range result.Layers {
-			if !layerCommandSet[layer.CreatedBy] {
-				layerCommands = append(layerCommands, layer.CreatedBy)
-				layerCommandSet[layer.CreatedBy] = true
+			if _, ok := layerLabels[layer.Key]; !ok {
+				layerKeys = append(layerKeys, layer.Key)
+				layerLabels[layer.Key] = layer.Label()
 			}
 		}
 	}
 
 	// Build comparison data
-	comparisons := make([]LayerComparison, 0, len(layerCommands))
-	for _, cmd := range layerCommands {
+	layerCommands := make([]string, 0, len(layerKeys))
+	comparisons := make([]LayerComparison, 0, len(layerKeys))
+	for _, key := range layerKeys {
 		comparison := LayerComparison{
-			LayerCommand: cmd,
+			LayerKey:     key,
+			LayerCommand: layerLabels[key],
 			SizeByCommit: make(map[string]float64),
 		}
 
@@ -393,7 +546,7 @@ func (tm *TimeMachine) buildLayerComparison(validResults []BuildResult) ([]strin
 			// Find this layer in the commit
 			found := false
 			for _, layer := range result.Layers {
-				if layer.CreatedBy == cmd {
+				if layer.Key == key {
 					comparison.SizeByCommit[result.CommitHash[:8]] = layer.SizeMB
 					found = true
 					break
@@ -404,6 +557,7 @@ func (tm *TimeMachine) buildLayerComparison(validResults []BuildResult) ([]strin
 			}
 		}
 
+		layerCommands = append(layerCommands, comparison.LayerCommand)
 		comparisons = append(comparisons, comparison)
 	}
 
@@ -470,12 +624,17 @@ func (tm *TimeMachine) generateTableReport(w io.Writer) error {
 
 	fmt.Fprintln(w, "\n📊 Docker Image Evolution Report")
 	fmt.Fprintln(w, "=================================")
+	fmt.Fprintln(w, tm.config.SizeBasis.Description())
 	table.Render()
 
 	// Find insights
 	if bloat := tm.findBloatCommit(); bloat != nil {
 		fmt.Fprintf(w, "\n⚠️  Biggest size increase: %s\n", bloat.CommitHash[:8])
 		fmt.Fprintf(w, "   Size increased by: %.2f MB\n", float64(bloat.SizeDiff)/1024/1024)
+		if bloat.Base != nil && bloat.Base.Changed {
+			fmt.Fprintf(w, "   Base image changed: %+.2f MB upstream, %+.2f MB from your layers\n",
+				float64(bloat.Base.BaseDiff)/1024/1024, float64(bloat.Base.AppDiff)/1024/1024)
+		}
 		fmt.Fprintf(w, "   Message: %s\n", bloat.CommitMessage)
 	}
 
@@ -487,7 +646,7 @@ func (tm *TimeMachine) generateTableReport(w io.Writer) error {
 
 	// Print layer comparison across commits
 	if len(validResults) > 0 {
-		layerCommands, _ := tm.buildLayerComparison(validResults)
+		layerCommands, comparisons := tm.buildLayerComparison(validResults)
 
 		if len(layerCommands) > 0 {
 			fmt.Fprintln(w, "\n📦 Layer Size Comparison Across Commits:")
@@ -508,21 +667,15 @@ func (tm *TimeMachine) generateTableReport(w io.Writer) error {
 			layerTable.SetAlignment(tablewriter.ALIGN_LEFT)
 
 			// For each layer command, show its size in each commit
-			for _, cmd := range layerCommands {
+			for i, cmd := range layerCommands {
 				row := []string{truncate(cmd, 40)}
 
 				for _, result := range validResults {
-					// Find this layer in the commit
-					found := false
-					for _, layer := range result.Layers {
-						if layer.CreatedBy == cmd {
-							row = append(row, fmt.Sprintf("%.2f", layer.SizeMB))
-							found = true
-							break
-						}
-					}
-					if !found {
+					size := comparisons[i].SizeByCommit[result.CommitHash[:8]]
+					if size < 0 {
 						row = append(row, "-")
+					} else {
+						row = append(row, fmt.Sprintf("%.2f", size))
 					}
 				}
 
@@ -533,14 +686,87 @@ func (tm *TimeMachine) generateTableReport(w io.Writer) error {
 		}
 	}
 
+	for _, section := range tm.sections(validResults) {
+		section.WriteTable(w)
+	}
+
 	return nil
 }
 
+// computePullCosts compares the layer digests of consecutive successful builds
+func (tm *TimeMachine) computePullCosts() {
+	var indexes []int
+	for i := range tm.results {
+		if tm.results[i].Error == "" {
+			indexes = append(indexes, i)
+		}
+	}
+
+	versions := make([]pull.Version, len(indexes))
+	for k, i := range indexes {
+		versions[k] = pullVersion(tm.results[i])
+	}
+	for k, cost := range pull.Compute(versions) {
+		tm.results[indexes[k]].PullCost = &cost
+	}
+}
+
+// pullVersion returns the layers of a build. Layers without a known digest
+// are identified by their instruction and size.
+func pullVersion(r BuildResult) pull.Version {
+	version := pull.Version{Name: r.CommitHash[:8]}
+	for _, layer := range r.Layers {
+		digest := layer.Digest
+		if digest == "" {
+			digest = fmt.Sprintf("%s@%d", layer.Key, layer.Size)
+		}
+		version.Layers = append(version.Layers, pull.Layer{Digest: digest, Size: layer.Size})
+	}
+	return version
+}
+
+// configVersions pairs each build with its image config
+func configVersions(validResults []BuildResult) []imgconfig.Version {
+	versions := make([]imgconfig.Version, 0, len(validResults))
+	for _, r := range validResults {
+		versions = append(versions, imgconfig.Version{Name: r.CommitHash[:8], Config: r.Config})
+	}
+	return versions
+}
+
+// sections returns the optional report sections for the enabled features
+func (tm *TimeMachine) sections(validResults []BuildResult) []report.Section {
+	var sections []report.Section
+	if len(validResults) > 1 {
+		versions := make([]pull.Version, 0, len(validResults))
+		costs := make([]pull.Cost, 0, len(validResults))
+		for _, r := range validResults {
+			if r.PullCost == nil {
+				continue
+			}
+			versions = append(versions, pullVersion(r))
+			costs = append(costs, *r.PullCost)
+		}
+		sections = append(sections, pull.Section(versions, costs))
+	}
+	sections = append(sections, base.Section(baseVersions(validResults)))
+	sections = append(sections, imgconfig.Section(imgconfig.CompareVersions(configVersions(validResults))))
+	sections = append(sections, scan.Sections(tm.config.Scan, scanVersions(validResults))...)
+	if tm.config.Lint && len(validResults) > 0 {
+		sections = append(sections, lint.Section(validResults[0].CommitHash[:8], validResults[0].Lint))
+	}
+	return sections
+}
+
 // JSONReport is the structure for JSON output
 type JSONReport struct {
-	Results         []BuildResult     `json:"results"`
-	LayerComparison []LayerComparison `json:"layer_comparison"`
-	CommitOrder     []string          `json:"commit_order"`
+	Results         []BuildResult           `json:"results"`
+	LayerComparison []LayerComparison       `json:"layer_comparison"`
+	CommitOrder     []string                `json:"commit_order"`
+	SizeBasis       report.SizeBasis        `json:"size_basis"`
+	ConfigDiffs     []imgconfig.VersionDiff `json:"config_diffs,omitempty"`
+
+	scan.Diffs
 }
 
 // generateJSONReport outputs results as JSON
@@ -565,6 +791,11 @@ func (tm *TimeMachine) generateJSONReport(w io.Writer) error {
 		Results:         tm.results,
 		LayerComparison: comparisons,
 		CommitOrder:     commitOrder,
+		SizeBasis:       tm.config.SizeBasis,
+		ConfigDiffs:     imgconfig.CompareVersions(configVersions(validResults)),
+	}
+	if tm.config.Scan.Enabled() {
+		report.Diffs = scan.Compare(tm.config.Scan, scanVersions(validResults))
 	}
 
 	encoder := json.NewEncoder(w)
@@ -582,6 +813,7 @@ func (tm *TimeMachine) generateCSVReport(w io.Writer) error {
 	}
 
 	// Part 1: Main results
+	fmt.Fprintf(w, "# %s\n", tm.config.SizeBasis.Description())
 	fmt.Fprintln(w, "# Commit Results")
 	fmt.Fprintln(w, "commit,date,author,size_mb,diff_mb,layers,time_s,message")
 	for _, result := range tm.results {
@@ -638,6 +870,10 @@ func (tm *TimeMachine) generateCSVReport(w io.Writer) error {
 		fmt.Fprintln(w, strings.Join(row, ","))
 	}
 
+	for _, section := range tm.sections(validResults) {
+		section.WriteCSV(w)
+	}
+
 	return nil
 }
 
@@ -646,6 +882,8 @@ func (tm *TimeMachine) generateMarkdownReport(w io.Writer) error {
 	fmt.Fprintln(w, "# Docker Image Evolution Report")
 	fmt.Fprintln(w)
 	fmt.Fprintln(w, "## Summary")
+	fmt.Fprintln(w, tm.config.SizeBasis.Description())
+	fmt.Fprintln(w)
 	fmt.Fprintf(w, "- **Commits analyzed:** %d\n", len(tm.results))
 
 	var validResults []BuildResult
@@ -723,6 +961,10 @@ func (tm *TimeMachine) generateMarkdownReport(w io.Writer) error {
 				fmt.Fprintln(w, row)
 			}
 		}
+
+		for _, section := range tm.sections(validResults) {
+			section.WriteMarkdown(w)
+		}
 	}
 
 	return nil
@@ -741,11 +983,17 @@ func (tm *TimeMachine) generateHTMLChart(w io.Writer) error {
 	// Prepare data for charts
 	var labels []string
 	var sizeData []float64
+	var downloadData []float64
 	var timeData []float64
 
 	for _, r := range validResults {
 		labels = append(labels, r.CommitHash[:8])
 		sizeData = append(sizeData, float64(r.ImageSize)/1024/1024)
+		if r.PullCost != nil {
+			downloadData = append(downloadData, float64(r.PullCost.Download)/1024/1024)
+		} else {
+			downloadData = append(downloadData, 0)
+		}
 		timeData = append(timeData, r.BuildTime)
 	}
 
@@ -812,6 +1060,11 @@ func (tm *TimeMachine) generateHTMLChart(w io.Writer) error {
 	layerTableJSON, _ := json.Marshal(layerTableData)
 
 	// Generate HTML with Chart.js
+	var sectionsHTML strings.Builder
+	for _, section := range tm.sections(validResults) {
+		sectionsHTML.WriteString(section.HTML())
+	}
+
 	html := fmt.Sprintf(`<!DOCTYPE html>
 <html>
 <head>
@@ -900,21 +1153,37 @@ func (tm *TimeMachine) generateHTMLChart(w io.Writer) error {
         .size-cell.missing {
             color: #999;
         }
+        .size-basis {
+            color: #666;
+        }
     </style>
 </head>
 <body>
     <h1>🐳 Docker Image Evolution Report</h1>
+    <p class="size-basis">%s</p>
     
     <div class="chart-container">
         <h2>📈 Image Size Over Time</h2>
         <canvas id="sizeChart"></canvas>
     </div>
+
+    <div class="chart-container" id="vulnChartContainer" style="display: none">
+        <h2>🛡️ Vulnerabilities Over Time</h2>
+        <canvas id="vulnChart"></canvas>
+        <p class="note">Matched offline against the local advisory database. Hover over bars to see the vulnerabilities introduced and fixed at each commit.</p>
+    </div>
     
     <div class="chart-container">
         <h2>📊 Image Size by Layer</h2>
         <canvas id="stackedLayerChart"></canvas>
         <p class="note">Each color represents a different layer. Hover over bars to see layer details.</p>
     </div>
+
+    <div class="chart-container" id="dirChartContainer" style="display: none">
+        <h2>📁 Image Size by Directory</h2>
+        <canvas id="dirChart"></canvas>
+        <p class="note">Size of the final filesystem by directory, including growth inside a single layer. Smaller directories are summed up as other.</p>
+    </div>
     
     <div class="chart-container">
         <h2>⏱️ Build Time Analysis</h2>
@@ -936,35 +1205,71 @@ func (tm *TimeMachine) generateHTMLChart(w io.Writer) error {
             </table>
         </div>
     </div>
-
+%s
+%s
     <script>
         const labels = %s;
         const sizeData = %s;
+        const downloadData = %s;
+        const splitData = %s;
+        const vulnData = %s;
+        const dirData = %s;
         const timeData = %s;
         const stackedDatasets = %s;
         const layerTableData = %s;
         
         // Image Size Over Time Chart
+        const sizeDatasets = [{
+            label: 'Image Size (MB)',
+            data: sizeData,
+            borderColor: 'rgb(75, 192, 192)',
+            backgroundColor: 'rgba(75, 192, 192, 0.2)',
+            tension: 0.1,
+            fill: true,
+            pointRadius: 4,
+            pointHoverRadius: 6
+        }, {
+            label: 'Upgrade Download (MB)',
+            data: downloadData,
+            borderColor: 'rgb(255, 159, 64)',
+            backgroundColor: 'rgba(255, 159, 64, 0.2)',
+            tension: 0.1,
+            fill: false,
+            pointRadius: 4,
+            pointHoverRadius: 6
+        }];
+        if (splitData) {
+            sizeDatasets.push({
+                label: 'Base Image (MB)',
+                data: splitData.base,
+                borderColor: 'rgb(153, 102, 255)',
+                backgroundColor: 'rgba(153, 102, 255, 0.2)',
+                tension: 0.1,
+                fill: false,
+                pointRadius: 4,
+                pointHoverRadius: 6
+            }, {
+                label: 'Application (MB)',
+                data: splitData.app,
+                borderColor: 'rgb(255, 99, 132)',
+                backgroundColor: 'rgba(255, 99, 132, 0.2)',
+                tension: 0.1,
+                fill: false,
+                pointRadius: 4,
+                pointHoverRadius: 6
+            });
+        }
         new Chart(document.getElementById('sizeChart'), {
             type: 'line',
             data: {
                 labels: labels,
-                datasets: [{
-                    label: 'Image Size (MB)',
-                    data: sizeData,
-                    borderColor: 'rgb(75, 192, 192)',
-                    backgroundColor: 'rgba(75, 192, 192, 0.2)',
-                    tension: 0.1,
-                    fill: true,
-                    pointRadius: 4,
-                    pointHoverRadius: 6
-                }]
+                datasets: sizeDatasets
             },
             options: {
                 responsive: true,
                 plugins: {
                     legend: {
-                        display: false
+                        display: true
                     }
                 },
                 scales: {
@@ -985,6 +1290,89 @@ func (tm *TimeMachine) generateHTMLChart(w io.Writer) error {
             }
         });
 
+        // Vulnerability Chart: severity counts next to the image size
+        if (vulnData) {
+            document.getElementById('vulnChartContainer').style.display = 'block';
+            const severityColors = {
+                CRITICAL: 'rgba(139, 0, 0, 0.8)',
+                HIGH: 'rgba(255, 99, 132, 0.8)',
+                MEDIUM: 'rgba(255, 159, 64, 0.8)',
+                LOW: 'rgba(255, 206, 86, 0.8)',
+                UNKNOWN: 'rgba(199, 199, 199, 0.8)'
+            };
+            const vulnDatasets = Object.keys(severityColors).map(severity => ({
+                type: 'bar',
+                label: severity.charAt(0) + severity.slice(1).toLowerCase(),
+                data: vulnData.counts[severity],
+                backgroundColor: severityColors[severity],
+                stack: 'vulnerabilities',
+                yAxisID: 'y'
+            }));
+            vulnDatasets.push({
+                type: 'line',
+                label: 'Image Size (MB)',
+                data: sizeData,
+                borderColor: 'rgb(75, 192, 192)',
+                backgroundColor: 'rgba(75, 192, 192, 0.2)',
+                tension: 0.1,
+                pointRadius: 4,
+                yAxisID: 'y1'
+            });
+            new Chart(document.getElementById('vulnChart'), {
+                data: {
+                    labels: labels,
+                    datasets: vulnDatasets
+                },
+                options: {
+                    responsive: true,
+                    plugins: {
+                        legend: {
+                            display: true
+                        },
+                        tooltip: {
+                            callbacks: {
+                                footer: function(items) {
+                                    const i = items[0].dataIndex;
+                                    const lines = [];
+                                    vulnData.introduced[i].forEach(id => lines.push('+ ' + id));
+                                    vulnData.fixed[i].forEach(id => lines.push('- ' + id));
+                                    return lines;
+                                }
+                            }
+                        }
+                    },
+                    scales: {
+                        x: {
+                            stacked: true,
+                            title: {
+                                display: true,
+                                text: 'Commit'
+                            }
+                        },
+                        y: {
+                            stacked: true,
+                            beginAtZero: true,
+                            title: {
+                                display: true,
+                                text: 'Vulnerabilities'
+                            }
+                        },
+                        y1: {
+                            position: 'right',
+                            beginAtZero: true,
+                            grid: {
+                                drawOnChartArea: false
+                            },
+                            title: {
+                                display: true,
+                                text: 'Size (MB)'
+                            }
+                        }
+                    }
+                }
+            });
+        }
+
         // Stacked Layer Chart
         new Chart(document.getElementById('stackedLayerChart'), {
             type: 'bar',
@@ -1033,6 +1421,73 @@ func (tm *TimeMachine) generateHTMLChart(w io.Writer) error {
             }
         });
         
+        // Directory Chart: final filesystem size by directory as stacked areas
+        if (dirData) {
+            document.getElementById('dirChartContainer').style.display = 'block';
+            const dirColors = [
+                'rgba(75, 192, 192, 0.6)',
+                'rgba(255, 99, 132, 0.6)',
+                'rgba(255, 206, 86, 0.6)',
+                'rgba(54, 162, 235, 0.6)',
+                'rgba(153, 102, 255, 0.6)',
+                'rgba(255, 159, 64, 0.6)',
+                'rgba(83, 102, 255, 0.6)',
+                'rgba(99, 255, 132, 0.6)',
+                'rgba(199, 199, 199, 0.6)'
+            ];
+            new Chart(document.getElementById('dirChart'), {
+                type: 'line',
+                data: {
+                    labels: labels,
+                    datasets: dirData.dirs.map((dir, i) => ({
+                        label: dir,
+                        data: dirData.sizes[i],
+                        borderColor: dirColors[i %% dirColors.length],
+                        backgroundColor: dirColors[i %% dirColors.length],
+                        fill: i === 0 ? 'origin' : '-1',
+                        tension: 0.1,
+                        pointRadius: 2
+                    }))
+                },
+                options: {
+                    responsive: true,
+                    interaction: {
+                        mode: 'index',
+                        intersect: false
+                    },
+                    plugins: {
+                        legend: {
+                            display: true,
+                            position: 'bottom'
+                        },
+                        tooltip: {
+                            callbacks: {
+                                label: function(context) {
+                                    return context.dataset.label + ': ' + context.raw.toFixed(2) + ' MB';
+                                }
+                            }
+                        }
+                    },
+                    scales: {
+                        x: {
+                            title: {
+                                display: true,
+                                text: 'Commit'
+                            }
+                        },
+                        y: {
+                            stacked: true,
+                            beginAtZero: true,
+                            title: {
+                                display: true,
+                                text: 'Size (MB)'
+                            }
+                        }
+                    }
+                }
+            });
+        }
+
         // Build Time Chart
         new Chart(document.getElementById('timeChart'), {
             type: 'bar',
@@ -1112,8 +1567,15 @@ func (tm *TimeMachine) generateHTMLChart(w io.Writer) error {
     </script>
 </body>
 </html>`,
+		tm.config.SizeBasis.Description(),
+		scan.TreemapHTML(tm.config.Scan, scanVersions(validResults)),
+		sectionsHTML.String(),
 		toJSONArray(labels),
 		toJSONFloatArray(sizeData),
+		toJSONFloatArray(downloadData),
+		base.ChartData(baseVersions(validResults)),
+		tm.vulnChartData(validResults),
+		tm.dirChartData(validResults),
 		toJSONFloatArray(timeData),
 		string(stackedDatasetsJSON),
 		string(layerTableJSON),
@@ -0,0 +1,180 @@
+package base
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/jtodic/docker-time-machine/pkg/report"
+)
+
+// Split divides an image into the layers inherited from its base image and the
+// application layers built on top of them
+type Split struct {
+	Image    string `json:"image,omitempty"`  // base image reference, e.g. node:20
+	Digest   string `json:"digest,omitempty"` // resolved manifest digest, when known
+	ID       string `json:"id"`               // identity of the base layers, see LayerID
+	Layers   int    `json:"layers"`           // number of base image layers
+	Base     int64  `json:"base_size"`
+	App      int64  `json:"app_size"`
+	BaseDiff int64  `json:"base_size_diff,omitempty"`
+	AppDiff  int64  `json:"app_size_diff,omitempty"`
+	// Changed is set when the base image differs from the older version,
+	// e.g. because FROM node:20 moved to a new digest
+	Changed bool `json:"changed,omitempty"`
+	// Unresolved is set when the digest of the base image could be found
+	// neither in the local image store nor in its registry
+	Unresolved bool `json:"unresolved,omitempty"`
+}
+
+// LayerID identifies a base image by the digests of its layers, lowest first.
+// Two versions built on the same base image have the same ID even when the
+// manifest digest could not be resolved.
+func LayerID(digests []string) string {
+	sum := sha256.Sum256([]byte(strings.Join(digests, "\n")))
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+// Version is an analyzed commit or tag with its split, nil when the base
+// image could not be determined
+type Version struct {
+	Name  string
+	Split *Split
+}
+
+// PrefixLength returns how many of the lowest layers of an image are the
+// layers of its base image. Both lists are ordered lowest first. It returns 0
+// when the image does not start with all layers of the base image.
+func PrefixLength(image, base []string) int {
+	if len(base) == 0 || len(base) > len(image) {
+		return 0
+	}
+	for i, digest := range base {
+		if image[i] != digest {
+			return 0
+		}
+	}
+	return len(base)
+}
+
+// Diff sets the base and application size differences of each version
+// relative to the next (older) version with a split, and flags the versions
+// whose base image changed. Versions are ordered newest first.
+func Diff(versions []Version) {
+	for i, v := range versions {
+		if v.Split == nil {
+			continue
+		}
+		for _, older := range versions[i+1:] {
+			if older.Split != nil {
+				v.Split.BaseDiff = v.Split.Base - older.Split.Base
+				v.Split.AppDiff = v.Split.App - older.Split.App
+				v.Split.Changed = v.Split.Image != older.Split.Image || v.Split.ID != older.Split.ID
+				break
+			}
+		}
+	}
+}
+
+// Section lists the base and application sizes of every version
+func Section(versions []Version) report.Section {
+	section := report.Section{
+		Title:   "🧱 Base Image vs Application",
+		Headers: []string{"Version", "Base Image", "Base Digest", "Base", "Base Diff", "App", "App Diff", "App Share"},
+	}
+	var changes, unresolved []string
+	for _, v := range versions {
+		if v.Split == nil {
+			continue
+		}
+		s := v.Split
+		image := s.Image
+		if s.Changed {
+			image = "⬆️ " + image
+			changes = append(changes, fmt.Sprintf("%s (%s upstream)", v.Name, report.FormatBytesDiff(s.BaseDiff)))
+		}
+		digest := s.Digest
+		if digest == "" {
+			digest = "layers " + s.ID
+		}
+		if s.Unresolved {
+			unresolved = append(unresolved, v.Name)
+		}
+		share := "-"
+		if total := s.Base + s.App; total > 0 {
+			share = fmt.Sprintf("%.0f%%", float64(s.App)/float64(total)*100)
+		}
+		section.Rows = append(section.Rows, []string{
+			v.Name,
+			image,
+			shortDigest(digest),
+			report.FormatBytes(s.Base),
+			report.FormatBytesDiff(s.BaseDiff),
+			report.FormatBytes(s.App),
+			report.FormatBytesDiff(s.AppDiff),
+			share,
+		})
+	}
+	var notes []string
+	if len(changes) > 0 {
+		notes = append(notes, "Base image changed in "+strings.Join(changes, ", ")+
+			". Base Diff is upstream growth; App Diff is the change of your own layers.")
+	}
+	if len(unresolved) > 0 {
+		notes = append(notes, "Base image digest could not be resolved locally or from the registry for "+
+			strings.Join(unresolved, ", ")+"; these versions are identified by their base layers.")
+	}
+	section.Note = strings.Join(notes, " ")
+	return section
+}
+
+// shortDigest shortens the hex part of a digest for display
+func shortDigest(digest string) string {
+	if idx := strings.Index(digest, "sha256:"); idx >= 0 && len(digest) > idx+7+12 {
+		return digest[:idx+7+12]
+	}
+	return digest
+}
+
+// SplitReference splits an image reference into the repository and the tag
+// or digest, e.g. "node:20" into "node" and "20". The tag defaults to latest.
+func SplitReference(ref string) (name, tag string) {
+	if at := strings.Index(ref, "@"); at >= 0 {
+		return ref[:at], ref[at+1:]
+	}
+	slash := strings.LastIndex(ref, "/")
+	if colon := strings.LastIndex(ref, ":"); colon > slash {
+		return ref[:colon], ref[colon+1:]
+	}
+	return ref, "latest"
+}
+
+// ChartData returns the base and application sizes in MB as a JSON object
+// with "base" and "app" series for the HTML charts, or null when no version
+// has a split
+func ChartData(versions []Version) string {
+	var data struct {
+		Base []*float64 `json:"base"`
+		App  []*float64 `json:"app"`
+	}
+	found := false
+	for _, v := range versions {
+		if v.Split == nil {
+			data.Base = append(data.Base, nil)
+			data.App = append(data.App, nil)
+			continue
+		}
+		found = true
+		baseMB := float64(v.Split.Base) / 1024 / 1024
+		appMB := float64(v.Split.App) / 1024 / 1024
+		data.Base = append(data.Base, &baseMB)
+		data.App = append(data.App, &appMB)
+	}
+	if !found {
+		return "null"
+	}
+	out, _ := json.Marshal(data)
+	return string(out)
+}
@@ -0,0 +1,194 @@
+package binaries
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"debug/buildinfo"
+	"debug/elf"
+	"io"
+	"sort"
+
+	"github.com/jtodic/docker-time-machine/pkg/inspect"
+)
+
+// maxBinarySize bounds the executables read into memory for analysis
+const maxBinarySize = 512 << 20
+
+// Binary is an ELF executable in the final filesystem of an image
+type Binary struct {
+	Path      string `json:"path"`
+	Size      int64  `json:"size"`
+	Arch      string `json:"arch,omitempty"`
+	Static    bool   `json:"static"`     // no dynamic loader
+	Stripped  bool   `json:"stripped"`   // no symbol table
+	DebugInfo bool   `json:"debug_info"` // carries DWARF sections
+	// Go build information, for binaries built by the Go toolchain
+	GoVersion string   `json:"go_version,omitempty"`
+	GoModule  string   `json:"go_module,omitempty"`  // main module
+	GoModules []string `json:"go_modules,omitempty"` // dependencies as path@version
+
+	layer int
+}
+
+// GoBinary is an ELF file built by the Go toolchain, including shared
+// libraries and plugins
+type GoBinary struct {
+	Path string
+	Size int64
+	Info *buildinfo.BuildInfo
+
+	layer int
+}
+
+// Collector gathers the ELF files of one image while its layers are walked.
+// It is the only visitor that reads executables, so each one is buffered once
+// however many reports use it. Pass Visit to the layer walk, then call Result
+// and GoBinaries.
+type Collector struct {
+	binaries   []Binary
+	goBinaries []GoBinary
+}
+
+// NewCollector returns an empty collector
+func NewCollector() *Collector {
+	return &Collector{}
+}
+
+// Visit analyzes the executable ELF files written by a layer and records
+// their Go build information
+func (c *Collector) Visit(layer int, p string, hdr *tar.Header, r io.Reader) error {
+	if hdr.Mode&0111 == 0 || hdr.Size < 4 || hdr.Size > maxBinarySize {
+		return nil
+	}
+
+	br := bufio.NewReader(r)
+	magic, err := br.Peek(4)
+	if err != nil || string(magic) != "\x7fELF" {
+		return nil
+	}
+
+	data, err := io.ReadAll(br)
+	if err != nil {
+		return err
+	}
+	info, err := buildinfo.Read(bytes.NewReader(data))
+	if err != nil {
+		info = nil // not a Go binary
+	} else {
+		c.goBinaries = append(c.goBinaries, GoBinary{Path: p, Size: hdr.Size, Info: info, layer: layer})
+	}
+
+	bin, ok := analyze(data, info)
+	if !ok {
+		return nil
+	}
+	bin.Path, bin.Size, bin.layer = p, hdr.Size, layer
+	c.binaries = append(c.binaries, bin)
+	return nil
+}
+
+// analyze reads the ELF headers of an executable. Shared libraries, objects
+// and core files are rejected. info is the Go build information of the file,
+// nil when it was not built by Go.
+func analyze(data []byte, info *buildinfo.BuildInfo) (Binary, bool) {
+	f, err := elf.NewFile(bytes.NewReader(data))
+	if err != nil {
+		return Binary{}, false
+	}
+	defer f.Close()
+
+	interp := false
+	for _, prog := range f.Progs {
+		if prog.Type == elf.PT_INTERP {
+			interp = true
+		}
+	}
+	switch f.Type {
+	case elf.ET_EXEC:
+	case elf.ET_DYN:
+		// Position independent executables are ET_DYN too; a library has a
+		// soname, even the runnable ones such as libc.so.6
+		if soname, _ := f.DynString(elf.DT_SONAME); len(soname) > 0 {
+			return Binary{}, false
+		}
+		if !interp && f.Entry == 0 {
+			return Binary{}, false
+		}
+	default:
+		return Binary{}, false
+	}
+
+	bin := Binary{
+		Arch:      arch(f.Machine),
+		Static:    !interp,
+		Stripped:  f.Section(".symtab") == nil,
+		DebugInfo: f.Section(".debug_info") != nil || f.Section(".zdebug_info") != nil,
+	}
+
+	if info != nil {
+		bin.GoVersion = info.GoVersion
+		bin.GoModule = info.Main.Path
+		if bin.GoModule == "" {
+			bin.GoModule = info.Path
+		}
+		for _, mod := range info.Deps {
+			if mod.Replace != nil {
+				mod = mod.Replace
+			}
+			bin.GoModules = append(bin.GoModules, mod.Path+"@"+mod.Version)
+		}
+	}
+	return bin, true
+}
+
+// arch names the machine type the way Go and Docker platforms do
+func arch(machine elf.Machine) string {
+	switch machine {
+	case elf.EM_X86_64:
+		return "amd64"
+	case elf.EM_AARCH64:
+		return "arm64"
+	case elf.EM_386:
+		return "386"
+	case elf.EM_ARM:
+		return "arm"
+	case elf.EM_PPC64:
+		return "ppc64le"
+	case elf.EM_S390:
+		return "s390x"
+	case elf.EM_RISCV:
+		return "riscv64"
+	}
+	return machine.String()
+}
+
+// Result returns the executables present in the final filesystem, largest
+// first
+func (c *Collector) Result(img *inspect.Image) []Binary {
+	var result []Binary
+	for _, bin := range c.binaries {
+		if img.Visible(bin.Path, bin.layer) {
+			result = append(result, bin)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Size != result[j].Size {
+			return result[i].Size > result[j].Size
+		}
+		return result[i].Path < result[j].Path
+	})
+	return result
+}
+
+// GoBinaries returns the Go build information of the ELF files present in the
+// final filesystem
+func (c *Collector) GoBinaries(img *inspect.Image) []GoBinary {
+	var result []GoBinary
+	for _, bin := range c.goBinaries {
+		if img.Visible(bin.Path, bin.layer) {
+			result = append(result, bin)
+		}
+	}
+	return result
+}
@@ -0,0 +1,297 @@
+package binaries
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/jtodic/docker-time-machine/pkg/report"
+)
+
+// ChangeType describes how a binary changed between two versions
+type ChangeType string
+
+const (
+	Added   ChangeType = "added"
+	Removed ChangeType = "removed"
+	Changed ChangeType = "changed" // size or build properties changed
+)
+
+// Change is a binary added, removed or changed between two versions
+type Change struct {
+	Type       ChangeType `json:"type"`
+	Path       string     `json:"path"`
+	BeforeSize int64      `json:"before_size"`
+	AfterSize  int64      `json:"after_size"`
+	SizeDiff   int64      `json:"size_diff"`
+	// Notes describe changed build properties, e.g. "now stripped"
+	Notes []string `json:"notes,omitempty"`
+}
+
+// VersionDiff lists the binary changes from one analyzed version to the next
+type VersionDiff struct {
+	From    string   `json:"from"`
+	To      string   `json:"to"`
+	Changes []Change `json:"changes"`
+}
+
+// Version is the executable inventory of an analyzed commit or tag
+type Version struct {
+	Name     string
+	Binaries []Binary
+	Scanned  bool // false when the image could not be inspected
+}
+
+// Compare returns the binaries added, removed and changed from older to
+// newer, largest size change first
+func Compare(from, to string, older, newer []Binary) VersionDiff {
+	diff := VersionDiff{From: from, To: to, Changes: []Change{}}
+
+	before := make(map[string]Binary, len(older))
+	for _, b := range older {
+		before[b.Path] = b
+	}
+	seen := make(map[string]bool, len(newer))
+	for _, b := range newer {
+		seen[b.Path] = true
+		old, ok := before[b.Path]
+		if !ok {
+			diff.Changes = append(diff.Changes, Change{
+				Type: Added, Path: b.Path, AfterSize: b.Size, SizeDiff: b.Size, Notes: []string{describe(b)},
+			})
+			continue
+		}
+		notes := propertyChanges(old, b)
+		if old.Size != b.Size || len(notes) > 0 {
+			diff.Changes = append(diff.Changes, Change{
+				Type: Changed, Path: b.Path, BeforeSize: old.Size, AfterSize: b.Size, SizeDiff: b.Size - old.Size, Notes: notes,
+			})
+		}
+	}
+	for _, b := range older {
+		if !seen[b.Path] {
+			diff.Changes = append(diff.Changes, Change{
+				Type: Removed, Path: b.Path, BeforeSize: b.Size, SizeDiff: -b.Size,
+			})
+		}
+	}
+
+	sort.SliceStable(diff.Changes, func(i, j int) bool {
+		a, b := abs(diff.Changes[i].SizeDiff), abs(diff.Changes[j].SizeDiff)
+		if a != b {
+			return a > b
+		}
+		return diff.Changes[i].Path < diff.Changes[j].Path
+	})
+	return diff
+}
+
+// propertyChanges describes how the build properties of a binary changed
+func propertyChanges(old, b Binary) []string {
+	var notes []string
+	flag := func(before, after bool, on, off string) {
+		switch {
+		case !before && after:
+			notes = append(notes, on)
+		case before && !after:
+			notes = append(notes, off)
+		}
+	}
+	flag(old.Stripped, b.Stripped, "now stripped", "no longer stripped")
+	flag(old.DebugInfo, b.DebugInfo, "debug info added", "debug info removed")
+	flag(old.Static, b.Static, "now static", "now dynamic")
+	if old.GoVersion != b.GoVersion && b.GoVersion != "" && old.GoVersion != "" {
+		notes = append(notes, fmt.Sprintf("%s → %s", old.GoVersion, b.GoVersion))
+	}
+	if added, removed := moduleChanges(old.GoModules, b.GoModules); added+removed > 0 {
+		notes = append(notes, fmt.Sprintf("modules +%d/-%d", added, removed))
+	}
+	return notes
+}
+
+// moduleChanges counts the Go module versions added and removed
+func moduleChanges(older, newer []string) (added, removed int) {
+	before := make(map[string]bool, len(older))
+	for _, m := range older {
+		before[m] = true
+	}
+	after := make(map[string]bool, len(newer))
+	for _, m := range newer {
+		after[m] = true
+		if !before[m] {
+			added++
+		}
+	}
+	for _, m := range older {
+		if !after[m] {
+			removed++
+		}
+	}
+	return added, removed
+}
+
+// describe summarizes the build properties of a binary, e.g.
+// "static, stripped, go1.22.1, 14 modules"
+func describe(b Binary) string {
+	parts := []string{b.linking(), b.symbols()}
+	if b.GoVersion != "" {
+		parts = append(parts, b.GoVersion, fmt.Sprintf("%d modules", len(b.GoModules)))
+	}
+	return strings.Join(parts, ", ")
+}
+
+func (b Binary) linking() string {
+	if b.Static {
+		return "static"
+	}
+	return "dynamic"
+}
+
+func (b Binary) symbols() string {
+	switch {
+	case b.DebugInfo:
+		return "debug info"
+	case b.Stripped:
+		return "stripped"
+	}
+	return "symbols"
+}
+
+// CompareVersions compares each scanned version with the next older scanned
+// one. Versions are ordered newest first.
+func CompareVersions(versions []Version) []VersionDiff {
+	return report.ConsecutivePairs(versions, func(v Version) bool { return v.Scanned },
+		func(older, newer Version) VersionDiff {
+			return Compare(older.Name, newer.Name, older.Binaries, newer.Binaries)
+		})
+}
+
+// Sections returns the binaries of the newest version, their sizes across
+// versions and the changes between consecutive versions
+func Sections(versions []Version, diffs []VersionDiff) []report.Section {
+	return []report.Section{InventorySection(versions), TrendSection(versions), DiffSection(diffs)}
+}
+
+// InventorySection lists the executables of the newest scanned version,
+// largest first
+func InventorySection(versions []Version) report.Section {
+	section := report.Section{
+		Headers: []string{"Binary", "Size", "Arch", "Linking", "Symbols", "Go"},
+	}
+	for _, v := range versions {
+		if !v.Scanned {
+			continue
+		}
+		section.Title = fmt.Sprintf("⚙️ Binaries (%s)", v.Name)
+		for i, b := range v.Binaries {
+			if i == report.MaxRows {
+				section.Rows = append(section.Rows, []string{fmt.Sprintf("... %d more", len(v.Binaries)-report.MaxRows), "", "", "", "", ""})
+				break
+			}
+			goInfo := "-"
+			if b.GoVersion != "" {
+				goInfo = fmt.Sprintf("%s, %d modules", b.GoVersion, len(b.GoModules))
+			}
+			section.Rows = append(section.Rows, []string{
+				b.Path, report.FormatBytes(b.Size), report.OrDash(b.Arch), b.linking(), b.symbols(), goInfo,
+			})
+		}
+		break
+	}
+	return section
+}
+
+// TrendSection shows the size of the largest binaries in every version, so
+// the growth of each executable can be followed across history
+func TrendSection(versions []Version) report.Section {
+	section := report.Section{
+		Title:   "⚙️ Binary Size Over Time",
+		Headers: []string{"Binary"},
+	}
+
+	largest := make(map[string]int64)
+	var scanned []Version
+	for _, v := range versions {
+		if !v.Scanned {
+			continue
+		}
+		scanned = append(scanned, v)
+		section.Headers = append(section.Headers, v.Name)
+		for _, b := range v.Binaries {
+			if b.Size > largest[b.Path] {
+				largest[b.Path] = b.Size
+			}
+		}
+	}
+	if len(scanned) < 2 {
+		return report.Section{}
+	}
+
+	paths := make([]string, 0, len(largest))
+	for p := range largest {
+		paths = append(paths, p)
+	}
+	sort.Slice(paths, func(i, j int) bool {
+		if largest[paths[i]] != largest[paths[j]] {
+			return largest[paths[i]] > largest[paths[j]]
+		}
+		return paths[i] < paths[j]
+	})
+	if len(paths) > report.MaxRows {
+		paths = paths[:report.MaxRows]
+	}
+
+	for _, p := range paths {
+		row := []string{p}
+		for _, v := range scanned {
+			size := "-"
+			for _, b := range v.Binaries {
+				if b.Path == p {
+					size = report.FormatBytes(b.Size)
+					break
+				}
+			}
+			row = append(row, size)
+		}
+		section.Rows = append(section.Rows, row)
+	}
+	return section
+}
+
+// DiffSection lists the binaries added, removed or changed between
+// consecutive versions
+func DiffSection(diffs []VersionDiff) report.Section {
+	section := report.Section{
+		Title:   "⚙️ Binary Changes",
+		Headers: []string{"Version", "Change", "Binary", "Before", "After", "Size Diff", "Notes"},
+	}
+	for _, d := range diffs {
+		version := fmt.Sprintf("%s → %s", d.From, d.To)
+		for i, c := range d.Changes {
+			if i == report.MaxRows {
+				section.Rows = append(section.Rows, []string{
+					version, fmt.Sprintf("... %d more", len(d.Changes)-report.MaxRows), "", "", "", "", "",
+				})
+				break
+			}
+			before, after := "-", "-"
+			if c.Type != Added {
+				before = report.FormatBytes(c.BeforeSize)
+			}
+			if c.Type != Removed {
+				after = report.FormatBytes(c.AfterSize)
+			}
+			section.Rows = append(section.Rows, []string{
+				version, string(c.Type), c.Path, before, after, report.FormatBytesDiff(c.SizeDiff), report.OrDash(strings.Join(c.Notes, "; ")),
+			})
+		}
+	}
+	return section
+}
+
+func abs(n int64) int64 {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
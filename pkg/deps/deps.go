@@ -0,0 +1,265 @@
+package deps
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/jtodic/docker-time-machine/pkg/binaries"
+	"github.com/jtodic/docker-time-machine/pkg/inspect"
+)
+
+// Ecosystems detected in the image filesystem
+const (
+	Node   = "npm"
+	Python = "pypi"
+	Go     = "go"
+)
+
+// Dependency is a language package found in an image
+type Dependency struct {
+	Ecosystem string `json:"ecosystem"`
+	Name      string `json:"name"`
+	Version   string `json:"version,omitempty"`
+	Size      int64  `json:"size"`     // on-disk size, 0 when not measurable (Go modules)
+	Location  string `json:"location"` // install directory, or the binary embedding a Go module
+	License   string `json:"license,omitempty"`
+}
+
+// key identifies a dependency across versions of an image
+func (d Dependency) key() string {
+	return d.Ecosystem + "/" + d.Name + "@" + d.Location
+}
+
+// Collector gathers the dependencies of one image while its layers are walked.
+// Pass Wanted to the layer walk, then call Result with the Go binaries found
+// by a binaries.Collector in the same walk.
+type Collector struct{}
+
+// NewCollector creates a Collector for a single image
+func NewCollector() *Collector {
+	return &Collector{}
+}
+
+// Wanted reports whether a file holds dependency metadata and must be
+// captured during the walk
+func (c *Collector) Wanted(p string) bool {
+	if path.Base(p) == "package.json" {
+		dir, ok := nodePackageDir(p)
+		return ok && dir+"/package.json" == p
+	}
+	dir := path.Base(path.Dir(p))
+	if strings.HasSuffix(dir, ".dist-info") {
+		base := path.Base(p)
+		return base == "METADATA" || base == "RECORD"
+	}
+	return false
+}
+
+// Result returns the dependencies present in the final filesystem, sorted by
+// ecosystem and name
+func (c *Collector) Result(img *inspect.Image, goBinaries []binaries.GoBinary) []Dependency {
+	var deps []Dependency
+	deps = append(deps, nodeDependencies(img)...)
+	deps = append(deps, pythonDependencies(img)...)
+
+	for _, bin := range goBinaries {
+		main := bin.Info.Main.Path
+		if main == "" {
+			main = bin.Info.Path
+		}
+		deps = append(deps, Dependency{
+			Ecosystem: Go, Name: main, Version: bin.Info.Main.Version, Size: bin.Size, Location: bin.Path,
+		})
+		for _, mod := range bin.Info.Deps {
+			if mod.Replace != nil {
+				mod = mod.Replace
+			}
+			deps = append(deps, Dependency{
+				Ecosystem: Go, Name: mod.Path, Version: mod.Version, Location: bin.Path,
+			})
+		}
+	}
+
+	sort.Slice(deps, func(i, j int) bool {
+		if deps[i].Ecosystem != deps[j].Ecosystem {
+			return deps[i].Ecosystem < deps[j].Ecosystem
+		}
+		if deps[i].Name != deps[j].Name {
+			return deps[i].Name < deps[j].Name
+		}
+		return deps[i].Location < deps[j].Location
+	})
+	return deps
+}
+
+// nodePackageDir returns the package directory of a file inside
+// node_modules, e.g. /app/node_modules/@types/node for
+// /app/node_modules/@types/node/package.json
+func nodePackageDir(p string) (string, bool) {
+	idx := strings.LastIndex(p, "/node_modules/")
+	if idx < 0 {
+		return "", false
+	}
+	rest := strings.Split(p[idx+len("/node_modules/"):], "/")
+	if strings.HasPrefix(rest[0], ".") {
+		return "", false // .bin, .package-lock.json
+	}
+	n := 1
+	if strings.HasPrefix(rest[0], "@") {
+		n = 2
+	}
+	if len(rest) <= n {
+		return "", false
+	}
+	return p[:idx+len("/node_modules/")] + strings.Join(rest[:n], "/"), true
+}
+
+// nodeDependencies finds the packages below node_modules directories. The
+// size of a package excludes its own nested node_modules.
+func nodeDependencies(img *inspect.Image) []Dependency {
+	sizes := make(map[string]int64)
+	for p, f := range img.Files {
+		if dir, ok := nodePackageDir(p); ok {
+			sizes[dir] += f.Size
+		}
+	}
+
+	var deps []Dependency
+	for dir, size := range sizes {
+		dep := Dependency{Ecosystem: Node, Size: size, Location: dir}
+		dep.Name = dir[strings.LastIndex(dir, "/node_modules/")+len("/node_modules/"):]
+
+		var manifest struct {
+			Name     string          `json:"name"`
+			Version  string          `json:"version"`
+			License  json.RawMessage `json:"license"`
+			Licenses json.RawMessage `json:"licenses"`
+		}
+		if data, ok := img.Contents[dir+"/package.json"]; ok && json.Unmarshal(data, &manifest) == nil {
+			if manifest.Name != "" {
+				dep.Name = manifest.Name
+			}
+			dep.Version = manifest.Version
+			dep.License = nodeLicense(manifest.License, manifest.Licenses)
+		}
+		deps = append(deps, dep)
+	}
+	return deps
+}
+
+// nodeLicense reads the license of a package.json: an SPDX expression, or
+// the deprecated {"type": ...} object and "licenses" array
+func nodeLicense(license, licenses json.RawMessage) string {
+	type typed struct {
+		Type string `json:"type"`
+	}
+	var expr string
+	if json.Unmarshal(license, &expr) == nil && expr != "" {
+		return expr
+	}
+	var obj typed
+	if json.Unmarshal(license, &obj) == nil && obj.Type != "" {
+		return obj.Type
+	}
+	var list []typed
+	if json.Unmarshal(licenses, &list) == nil {
+		var types []string
+		for _, l := range list {
+			if l.Type != "" {
+				types = append(types, l.Type)
+			}
+		}
+		return strings.Join(types, " OR ")
+	}
+	return ""
+}
+
+// pythonLicense picks the license of a distribution from its METADATA
+// headers: License-Expression, then a short License field, then the license
+// classifiers
+func pythonLicense(expression, license string, classifiers []string) string {
+	if expression != "" {
+		return expression
+	}
+	if license != "" && license != "UNKNOWN" && len(license) <= 64 {
+		return license
+	}
+	var names []string
+	for _, c := range classifiers {
+		if !strings.HasPrefix(c, "License ::") {
+			continue
+		}
+		parts := strings.Split(c, " :: ")
+		if name := parts[len(parts)-1]; name != "OSI Approved" {
+			names = append(names, name)
+		}
+	}
+	return strings.Join(names, " OR ")
+}
+
+// pythonDependencies reads the .dist-info directories of installed
+// distributions. The size is the sum of the files listed in RECORD.
+func pythonDependencies(img *inspect.Image) []Dependency {
+	var deps []Dependency
+	for p, data := range img.Contents {
+		if path.Base(p) != "METADATA" || !strings.HasSuffix(path.Dir(p), ".dist-info") {
+			continue
+		}
+		distInfo := path.Dir(p)
+		sitePackages := path.Dir(distInfo)
+
+		dep := Dependency{Ecosystem: Python, Location: sitePackages}
+		var expression, license string
+		var classifiers []string
+		inLicense := false
+		for _, line := range strings.Split(string(data), "\n") {
+			if line == "" {
+				break // end of the headers
+			}
+			if line[0] == ' ' || line[0] == '\t' {
+				// A continued License header holds the license text, not its name
+				if inLicense {
+					license = ""
+				}
+				continue
+			}
+			inLicense = strings.HasPrefix(line, "License: ")
+			if v, ok := strings.CutPrefix(line, "Name: "); ok {
+				dep.Name = strings.TrimSpace(v)
+			} else if v, ok := strings.CutPrefix(line, "Version: "); ok {
+				dep.Version = strings.TrimSpace(v)
+			} else if v, ok := strings.CutPrefix(line, "License-Expression: "); ok {
+				expression = strings.TrimSpace(v)
+			} else if v, ok := strings.CutPrefix(line, "License: "); ok {
+				license = strings.TrimSpace(v)
+			} else if v, ok := strings.CutPrefix(line, "Classifier: "); ok {
+				classifiers = append(classifiers, strings.TrimSpace(v))
+			}
+		}
+		dep.License = pythonLicense(expression, license, classifiers)
+		if dep.Name == "" {
+			continue
+		}
+
+		record := csv.NewReader(bytes.NewReader(img.Contents[distInfo+"/RECORD"]))
+		record.FieldsPerRecord = -1
+		records, _ := record.ReadAll()
+		for _, fields := range records {
+			if len(fields) < 3 || fields[0] == "" {
+				continue
+			}
+			if f, ok := img.Files[path.Join(sitePackages, fields[0])]; ok {
+				dep.Size += f.Size
+			} else if size, err := strconv.ParseInt(fields[len(fields)-1], 10, 64); err == nil {
+				dep.Size += size
+			}
+		}
+		deps = append(deps, dep)
+	}
+	return deps
+}
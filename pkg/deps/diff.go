@@ -0,0 +1,170 @@
+package deps
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/jtodic/docker-time-machine/pkg/report"
+)
+
+// ChangeType describes how a dependency changed between two versions
+type ChangeType string
+
+const (
+	Added   ChangeType = "added"
+	Removed ChangeType = "removed"
+	Changed ChangeType = "changed" // version changed
+	Grew    ChangeType = "grew"    // same version, larger on disk
+	Shrank  ChangeType = "shrank"  // same version, smaller on disk
+)
+
+// Change is a dependency that was added, removed or changed between two
+// versions
+type Change struct {
+	Type          ChangeType `json:"type"`
+	Ecosystem     string     `json:"ecosystem"`
+	Name          string     `json:"name"`
+	Location      string     `json:"location"`
+	BeforeVersion string     `json:"before_version,omitempty"`
+	AfterVersion  string     `json:"after_version,omitempty"`
+	BeforeSize    int64      `json:"before_size"`
+	AfterSize     int64      `json:"after_size"`
+	SizeDiff      int64      `json:"size_diff"`
+}
+
+// VersionDiff lists the dependency changes from one analyzed version to the
+// next
+type VersionDiff struct {
+	From    string   `json:"from"`
+	To      string   `json:"to"`
+	Changes []Change `json:"changes"`
+}
+
+// Version is the dependency inventory of an analyzed commit or tag
+type Version struct {
+	Name         string
+	Dependencies []Dependency
+	Scanned      bool // false when the image could not be inspected
+}
+
+// Compare returns the dependencies added, removed, version-changed or resized
+// from older to newer, largest size change first
+func Compare(from, to string, older, newer []Dependency) VersionDiff {
+	diff := VersionDiff{From: from, To: to}
+
+	before := make(map[string]Dependency)
+	for _, d := range older {
+		before[d.key()] = d
+	}
+
+	seen := make(map[string]bool)
+	for _, d := range newer {
+		seen[d.key()] = true
+		change := Change{
+			Ecosystem: d.Ecosystem, Name: d.Name, Location: d.Location,
+			AfterVersion: d.Version, AfterSize: d.Size, SizeDiff: d.Size,
+		}
+		old, ok := before[d.key()]
+		if ok {
+			change.BeforeVersion = old.Version
+			change.BeforeSize = old.Size
+			change.SizeDiff = d.Size - old.Size
+		}
+		switch {
+		case !ok:
+			change.Type = Added
+		case old.Version != d.Version:
+			change.Type = Changed
+		case change.SizeDiff > 0:
+			change.Type = Grew
+		case change.SizeDiff < 0:
+			change.Type = Shrank
+		default:
+			continue
+		}
+		diff.Changes = append(diff.Changes, change)
+	}
+
+	for _, d := range older {
+		if seen[d.key()] {
+			continue
+		}
+		diff.Changes = append(diff.Changes, Change{
+			Type: Removed, Ecosystem: d.Ecosystem, Name: d.Name, Location: d.Location,
+			BeforeVersion: d.Version, BeforeSize: d.Size, SizeDiff: -d.Size,
+		})
+	}
+
+	sort.SliceStable(diff.Changes, func(i, j int) bool {
+		a, b := abs(diff.Changes[i].SizeDiff), abs(diff.Changes[j].SizeDiff)
+		if a != b {
+			return a > b
+		}
+		if diff.Changes[i].Name != diff.Changes[j].Name {
+			return diff.Changes[i].Name < diff.Changes[j].Name
+		}
+		return diff.Changes[i].Location < diff.Changes[j].Location
+	})
+
+	return diff
+}
+
+// CompareVersions compares each scanned version with the next older scanned
+// one. Versions are ordered newest first.
+func CompareVersions(versions []Version) []VersionDiff {
+	return report.ConsecutivePairs(versions, func(v Version) bool { return v.Scanned },
+		func(older, newer Version) VersionDiff {
+			return Compare(older.Name, newer.Name, older.Dependencies, newer.Dependencies)
+		})
+}
+
+// DiffSection lists the dependency changes between consecutive versions
+func DiffSection(diffs []VersionDiff) report.Section {
+	section := report.Section{
+		Title:   "🧩 Dependency Changes",
+		Headers: []string{"Version", "Change", "Dependency", "Before", "After", "Size", "Size Diff", "Location"},
+	}
+
+	for _, d := range diffs {
+		version := fmt.Sprintf("%s → %s", d.From, d.To)
+		for i, c := range d.Changes {
+			if i == report.MaxRows {
+				section.Rows = append(section.Rows, []string{
+					version, fmt.Sprintf("... %d more", len(d.Changes)-report.MaxRows), "", "", "", "", "", "",
+				})
+				break
+			}
+			size := c.AfterSize
+			if c.Type == Removed {
+				size = c.BeforeSize
+			}
+			section.Rows = append(section.Rows, []string{
+				version,
+				string(c.Type),
+				fmt.Sprintf("%s (%s)", c.Name, c.Ecosystem),
+				report.OrDash(c.BeforeVersion),
+				report.OrDash(c.AfterVersion),
+				formatSize(size, report.FormatBytes),
+				formatSize(c.SizeDiff, report.FormatBytesDiff),
+				c.Location,
+			})
+		}
+	}
+
+	return section
+}
+
+// formatSize formats a size, or "-" for Go modules whose size is unknown
+func formatSize(n int64, format func(int64) string) string {
+	if n == 0 {
+		return "-"
+	}
+	return format(n)
+}
+
+func abs(n int64) int64 {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
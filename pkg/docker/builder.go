@@ -50,6 +50,12 @@ func (b *Builder) BuildImage(ctx context.Context, contextPath, dockerfileName, t
 		return fmt.Errorf("failed to create build context: %w", err)
 	}
 
+	return b.BuildImageFromContext(ctx, buildContext, dockerfileName, tag)
+}
+
+// BuildImageFromContext builds a Docker image from an already created build
+// context tar, e.g. one assembled from a git tree instead of the filesystem
+func (b *Builder) BuildImageFromContext(ctx context.Context, buildContext io.Reader, dockerfileName, tag string) error {
 	// Build options
 	opts := build.ImageBuildOptions{
 		Tags:           []string{tag},
@@ -187,6 +193,11 @@ func (b *Builder) GetImageHistory(ctx context.Context, imageID string) ([]image.
 	return history, nil
 }
 
+// SaveImage exports an image as a `docker save` tar archive
+func (b *Builder) SaveImage(ctx context.Context, imageID string) (io.ReadCloser, error) {
+	return b.client.ImageSave(ctx, []string{imageID})
+}
+
 // RemoveImage removes a Docker image by ID or name
 func (b *Builder) RemoveImage(ctx context.Context, imageID string) error {
 	_, err := b.client.ImageRemove(ctx, imageID, image.RemoveOptions{
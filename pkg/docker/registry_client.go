@@ -13,11 +13,15 @@ import (
 	"sort"
 	"strings"
 	"time"
+
+	dockerspec "github.com/moby/docker-image-spec/specs-go/v1"
 )
 
 // RegistryClient handles communication with container registries
 type RegistryClient struct {
 	httpClient *http.Client
+	// blobClient has no overall timeout since layer blobs can be large
+	blobClient *http.Client
 }
 
 // TagInfo contains information about a tag
@@ -44,6 +48,7 @@ func NewRegistryClient() *RegistryClient {
 		httpClient: &http.Client{
 			Timeout: 30 * time.Second,
 		},
+		blobClient: &http.Client{},
 	}
 }
 
@@ -595,6 +600,17 @@ type ImageMetadata struct {
 	Created    time.Time       `json:"created"`
 	LayerCount int             `json:"layer_count"`
 	Layers     []LayerMetadata `json:"layers"`
+	// LayerDigests lists every layer blob of the manifest, lowest first
+	LayerDigests []string `json:"layer_digests"`
+	// DiffIDs lists the uncompressed layer digests from the config, lowest
+	// first, as the local image store reports them
+	DiffIDs []string `json:"diff_ids,omitempty"`
+	// BaseImage and BaseDigest come from the OCI base image annotations of
+	// the manifest, when the builder set them
+	BaseImage  string `json:"base_image,omitempty"`
+	BaseDigest string `json:"base_digest,omitempty"`
+	// Config is the runtime configuration from the config blob
+	Config *dockerspec.DockerOCIImageConfig `json:"config,omitempty"`
 }
 
 // LayerMetadata holds layer information from registry
@@ -620,6 +636,7 @@ type ManifestResponse struct {
 		Size      int64  `json:"size"`
 		Digest    string `json:"digest"`
 	} `json:"layers"`
+	Annotations map[string]string `json:"annotations,omitempty"`
 }
 
 // ManifestListResponse represents a multi-arch manifest list
@@ -652,6 +669,8 @@ type ConfigResponse struct {
 		Type    string   `json:"type"`
 		DiffIDs []string `json:"diff_ids"`
 	} `json:"rootfs"`
+	// Config holds the user, entrypoint, env and other runtime settings
+	Config dockerspec.DockerOCIImageConfig `json:"config"`
 }
 
 // GetImageMetadata fetches image metadata from registry without pulling the full image
@@ -681,16 +700,23 @@ func (rc *RegistryClient) GetImageMetadata(ctx context.Context, imageName, tag,
 
 	// Calculate total size
 	var totalSize int64
+	var digests []string
 	for _, layer := range manifest.Layers {
 		totalSize += layer.Size
+		digests = append(digests, layer.Digest)
 	}
 
 	return &ImageMetadata{
-		Digest:     manifestDigest,
-		Size:       totalSize,
-		Created:    config.Created,
-		LayerCount: len(manifest.Layers),
-		Layers:     layers,
+		Digest:       manifestDigest,
+		Size:         totalSize,
+		Created:      config.Created,
+		LayerCount:   len(manifest.Layers),
+		Layers:       layers,
+		LayerDigests: digests,
+		DiffIDs:      config.RootFS.DiffIDs,
+		BaseImage:    manifest.Annotations["org.opencontainers.image.base.name"],
+		BaseDigest:   manifest.Annotations["org.opencontainers.image.base.digest"],
+		Config:       &config.Config,
 	}, nil
 }
 
@@ -964,3 +990,51 @@ func cleanCreatedBy(cmd string) string {
 	cmd = strings.TrimSpace(cmd)
 	return cmd
 }
+
+// FetchBlob downloads a blob, such as a compressed layer tarball, from the
+// registry. The caller must close the returned reader.
+func (rc *RegistryClient) FetchBlob(ctx context.Context, imageName, digest string) (io.ReadCloser, error) {
+	registry, repo := parseImageName(imageName)
+
+	token, err := rc.getAuthToken(ctx, registry, repo)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get auth token: %w", err)
+	}
+
+	var baseURL string
+	if registry == "docker.io" {
+		baseURL = "https://registry-1.docker.io"
+	} else {
+		baseURL = fmt.Sprintf("https://%s", registry)
+	}
+
+	url := fmt.Sprintf("%s/v2/%s/blobs/%s", baseURL, repo, digest)
+
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if token != "" {
+		if strings.HasPrefix(token, "Basic ") {
+			req.Header.Set("Authorization", token)
+		} else {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+	}
+
+	// Registries usually redirect blob downloads to object storage; the
+	// Authorization header is dropped on cross-host redirects
+	resp, err := rc.blobClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		return nil, fmt.Errorf("blob fetch failed (%d): %s", resp.StatusCode, string(body))
+	}
+
+	return resp.Body, nil
+}
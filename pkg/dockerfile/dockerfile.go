@@ -0,0 +1,226 @@
+package dockerfile
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/moby/buildkit/frontend/dockerfile/parser"
+)
+
+// Instruction is a single Dockerfile instruction and its location in the source
+type Instruction struct {
+	Stage     int    `json:"stage"`
+	StageName string `json:"stage_name,omitempty"`
+	Keyword   string `json:"keyword"`
+	Original  string `json:"original"`
+	StartLine int    `json:"start_line"`
+	EndLine   int    `json:"end_line"`
+	Ordinal   int    `json:"ordinal"` // 1-based occurrence of Keyword within its stage
+}
+
+// Stage is a build stage started by a FROM instruction
+type Stage struct {
+	Index        int           `json:"index"`
+	Name         string        `json:"name,omitempty"`
+	BaseImage    string        `json:"base_image"`
+	FromLine     int           `json:"from_line"`
+	Instructions []Instruction `json:"instructions"`
+}
+
+// Dockerfile is a parsed Dockerfile split into stages
+type Dockerfile struct {
+	Stages []Stage `json:"stages"`
+}
+
+// Parse parses a Dockerfile using the BuildKit parser
+func Parse(r io.Reader) (*Dockerfile, error) {
+	result, err := parser.Parse(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse Dockerfile: %w", err)
+	}
+
+	df := &Dockerfile{}
+	ordinals := make(map[string]int)
+
+	for _, node := range result.AST.Children {
+		keyword := strings.ToUpper(node.Value)
+
+		if keyword == "FROM" {
+			stage := Stage{
+				Index:    len(df.Stages),
+				FromLine: node.StartLine,
+			}
+			if node.Next != nil {
+				stage.BaseImage = node.Next.Value
+				// FROM <image> AS <name>
+				if as := node.Next.Next; as != nil && strings.EqualFold(as.Value, "as") && as.Next != nil {
+					stage.Name = strings.ToLower(as.Next.Value)
+				}
+			}
+			df.Stages = append(df.Stages, stage)
+			ordinals = make(map[string]int)
+			continue
+		}
+
+		// Instructions before the first FROM (global ARGs) don't belong to a stage
+		if len(df.Stages) == 0 {
+			continue
+		}
+
+		stage := &df.Stages[len(df.Stages)-1]
+		ordinals[keyword]++
+		stage.Instructions = append(stage.Instructions, Instruction{
+			Stage:     stage.Index,
+			StageName: stage.Name,
+			Keyword:   keyword,
+			Original:  node.Original,
+			StartLine: node.StartLine,
+			EndLine:   node.EndLine,
+			Ordinal:   ordinals[keyword],
+		})
+	}
+
+	if len(df.Stages) == 0 {
+		return nil, fmt.Errorf("no FROM instruction found")
+	}
+
+	return df, nil
+}
+
+// ParseFile reads and parses the Dockerfile at path
+func ParseFile(path string) (*Dockerfile, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return Parse(bytes.NewReader(data))
+}
+
+// FinalStage returns the last stage, which produces the built image
+func (d *Dockerfile) FinalStage() *Stage {
+	return &d.Stages[len(d.Stages)-1]
+}
+
+// BaseImage returns the external image the final image is built on, following
+// FROM references to earlier stages
+func (d *Dockerfile) BaseImage() string {
+	stage := d.FinalStage()
+	for {
+		parent := d.stageByName(stage.BaseImage, stage.Index)
+		if parent == nil {
+			return stage.BaseImage
+		}
+		stage = parent
+	}
+}
+
+// stageByName finds a stage by its name or numeric index
+func (d *Dockerfile) stageByName(name string, before int) *Stage {
+	name = strings.ToLower(name)
+	for i := 0; i < before; i++ {
+		if d.Stages[i].Name == name || strconv.Itoa(i) == name {
+			return &d.Stages[i]
+		}
+	}
+	return nil
+}
+
+// ImageInstructions returns the instructions that contribute history entries to
+// the final image, including those of parent stages when the final stage is
+// built FROM another stage
+func (d *Dockerfile) ImageInstructions() []Instruction {
+	var chain []*Stage
+	stage := d.FinalStage()
+	for stage != nil {
+		chain = append([]*Stage{stage}, chain...)
+		stage = d.stageByName(stage.BaseImage, stage.Index)
+	}
+
+	var instructions []Instruction
+	for _, s := range chain {
+		instructions = append(instructions, s.Instructions...)
+	}
+	return instructions
+}
+
+// Key returns an identifier for the instruction within its Dockerfile: the
+// stage, the keyword and the ordinal among the instructions of the stage with
+// the same keyword, e.g. "build/RUN#2". Adding or removing an instruction with
+// the same keyword earlier in the stage renumbers it, so across commits it
+// only breaks ties after layers were matched by digest and command.
+func (i Instruction) Key() string {
+	stage := i.StageName
+	if stage == "" {
+		stage = fmt.Sprintf("stage%d", i.Stage)
+	}
+	return fmt.Sprintf("%s/%s#%d", stage, i.Keyword, i.Ordinal)
+}
+
+// Lines returns the line range of the instruction, e.g. "L3" or "L3-7"
+func (i Instruction) Lines() string {
+	if i.EndLine > i.StartLine {
+		return fmt.Sprintf("L%d-%d", i.StartLine, i.EndLine)
+	}
+	return fmt.Sprintf("L%d", i.StartLine)
+}
+
+// MatchHistory associates image history entries with the Dockerfile
+// instructions that created them. createdBy holds the CreatedBy strings of
+// the image history ordered oldest first. The returned slice has the same
+// length; entries created by the base image are nil.
+func (d *Dockerfile) MatchHistory(createdBy []string) []*Instruction {
+	matches := make([]*Instruction, len(createdBy))
+	instructions := d.ImageInstructions()
+
+	// Every instruction of the final stage commits one history entry after
+	// those of the base image, so align both lists from the end. Entries whose
+	// keyword doesn't match (e.g. ONBUILD triggers) are skipped.
+	h := len(createdBy) - 1
+	for i := len(instructions) - 1; i >= 0 && h >= 0; i-- {
+		keyword := instructions[i].Keyword
+		if keyword == "ONBUILD" {
+			continue
+		}
+
+		for h >= 0 && HistoryKeyword(createdBy[h]) != keyword {
+			h--
+		}
+		if h < 0 {
+			break
+		}
+
+		matches[h] = &instructions[i]
+		h--
+	}
+
+	return matches
+}
+
+// HistoryKeyword extracts the Dockerfile keyword from an image history
+// CreatedBy string, handling both BuildKit and legacy builder formats
+func HistoryKeyword(createdBy string) string {
+	cmd := strings.TrimSpace(createdBy)
+
+	// Legacy builder: "/bin/sh -c #(nop) COPY ..." for metadata and copies,
+	// "/bin/sh -c apt-get ..." or "|1 FOO=bar /bin/sh -c ..." for RUN
+	if strings.HasPrefix(cmd, "|") || strings.HasPrefix(cmd, "/bin/sh -c ") || strings.HasPrefix(cmd, "#(nop)") {
+		if idx := strings.Index(cmd, "#(nop)"); idx >= 0 {
+			fields := strings.Fields(cmd[idx+len("#(nop)"):])
+			if len(fields) > 0 {
+				return strings.ToUpper(fields[0])
+			}
+			return ""
+		}
+		return "RUN"
+	}
+
+	fields := strings.Fields(cmd)
+	if len(fields) == 0 {
+		return ""
+	}
+	return strings.ToUpper(fields[0])
+}
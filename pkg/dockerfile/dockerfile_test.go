@@ -0,0 +1,142 @@
+package dockerfile
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestMatchHistory(t *testing.T) {
+	tests := []struct {
+		name       string
+		dockerfile string
+		createdBy  []string // oldest first
+		wantLines  []int    // start line of the matched instruction, 0 for none
+	}{
+		{
+			"buildkit",
+			"FROM node:20\nWORKDIR /app\nCOPY package.json .\nRUN npm ci\nENV NODE_ENV=production\nCMD [\"node\", \"index.js\"]\n",
+			[]string{
+				"/bin/sh -c #(nop) ADD file:4f1a in / ",
+				"/bin/sh -c #(nop)  CMD [\"bash\"]",
+				"WORKDIR /app",
+				"COPY package.json . # buildkit",
+				"RUN /bin/sh -c npm ci # buildkit",
+				"ENV NODE_ENV=production",
+				"CMD [\"node\" \"index.js\"]",
+			},
+			[]int{0, 0, 2, 3, 4, 5, 6},
+		},
+		{
+			"classic builder",
+			"FROM node:20\nARG VERSION\nWORKDIR /app\nCOPY . .\nRUN npm ci\nCMD [\"node\"]\n",
+			[]string{
+				"/bin/sh -c #(nop) ADD file:4f1a in / ",
+				"/bin/sh -c #(nop)  CMD [\"bash\"]",
+				"/bin/sh -c #(nop)  ARG VERSION",
+				"/bin/sh -c #(nop) WORKDIR /app",
+				"/bin/sh -c #(nop) COPY dir:9b2c in . ",
+				"|1 VERSION=1.0 /bin/sh -c npm ci",
+				"/bin/sh -c #(nop)  CMD [\"node\"]",
+			},
+			[]int{0, 0, 2, 3, 4, 5, 6},
+		},
+		{
+			"onbuild triggers of the base image",
+			"FROM node:onbuild\nCOPY . .\nRUN npm test\n",
+			[]string{
+				"/bin/sh -c #(nop) ADD file:4f1a in / ",
+				"COPY package.json /app/ # buildkit",
+				"RUN /bin/sh -c npm install # buildkit",
+				"COPY . . # buildkit",
+				"RUN /bin/sh -c npm test # buildkit",
+			},
+			[]int{0, 0, 0, 2, 3},
+		},
+		{
+			"onbuild instruction",
+			"FROM alpine\nRUN apk add git\nONBUILD COPY . /src\nCMD [\"sh\"]\n",
+			[]string{
+				"/bin/sh -c #(nop) ADD file:4f1a in / ",
+				"RUN /bin/sh -c apk add git # buildkit",
+				"ONBUILD COPY . /src",
+				"CMD [\"sh\"]",
+			},
+			[]int{0, 2, 0, 4},
+		},
+		{
+			"empty run layer",
+			"FROM alpine\nRUN mkdir -p /data\nRUN true\nCOPY app /app\n",
+			[]string{
+				"/bin/sh -c #(nop) ADD file:4f1a in / ",
+				"RUN /bin/sh -c mkdir -p /data # buildkit",
+				"RUN /bin/sh -c true # buildkit",
+				"COPY app /app # buildkit",
+			},
+			[]int{0, 2, 3, 4},
+		},
+		{
+			"multi-stage",
+			"FROM golang:1.24 AS build\nWORKDIR /src\nCOPY . .\nRUN go build -o /app\n\nFROM alpine\nCOPY --from=build /app /app\nENTRYPOINT [\"/app\"]\n",
+			[]string{
+				"/bin/sh -c #(nop) ADD file:4f1a in / ",
+				"/bin/sh -c #(nop)  CMD [\"/bin/sh\"]",
+				"COPY /app /app # buildkit",
+				"ENTRYPOINT [\"/app\"]",
+			},
+			[]int{0, 0, 7, 8},
+		},
+		{
+			"final stage built from an earlier stage",
+			"FROM python:3.12 AS base\nRUN pip install flask\n\nFROM base\nCOPY app.py .\nCMD [\"python\", \"app.py\"]\n",
+			[]string{
+				"/bin/sh -c #(nop) ADD file:4f1a in / ",
+				"RUN /bin/sh -c pip install flask # buildkit",
+				"COPY app.py . # buildkit",
+				"CMD [\"python\" \"app.py\"]",
+			},
+			[]int{0, 2, 5, 6},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			df, err := Parse(strings.NewReader(tt.dockerfile))
+			if err != nil {
+				t.Fatal(err)
+			}
+			matches := df.MatchHistory(tt.createdBy)
+			if len(matches) != len(tt.createdBy) {
+				t.Fatalf("MatchHistory returned %d entries, want %d", len(matches), len(tt.createdBy))
+			}
+			for i, match := range matches {
+				got := 0
+				if match != nil {
+					got = match.StartLine
+				}
+				if got != tt.wantLines[i] {
+					t.Errorf("entry %d (%s) matched line %d, want %d", i, tt.createdBy[i], got, tt.wantLines[i])
+				}
+			}
+		})
+	}
+}
+
+func TestHistoryKeyword(t *testing.T) {
+	tests := []struct {
+		createdBy string
+		want      string
+	}{
+		{"RUN /bin/sh -c npm ci # buildkit", "RUN"},
+		{"COPY . . # buildkit", "COPY"},
+		{"WORKDIR /app", "WORKDIR"},
+		{"/bin/sh -c #(nop) COPY dir:9b2c in . ", "COPY"},
+		{"/bin/sh -c #(nop)  CMD [\"node\"]", "CMD"},
+		{"/bin/sh -c npm ci", "RUN"},
+		{"|2 A=1 B=2 /bin/sh -c make", "RUN"},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := HistoryKeyword(tt.createdBy); got != tt.want {
+			t.Errorf("HistoryKeyword(%q) = %q, want %q", tt.createdBy, got, tt.want)
+		}
+	}
+}
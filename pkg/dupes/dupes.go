@@ -0,0 +1,99 @@
+package dupes
+
+import (
+	"archive/tar"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"sort"
+
+	"github.com/jtodic/docker-time-machine/pkg/inspect"
+)
+
+// minSize is the smallest file considered; tiny duplicates such as license
+// stubs cost next to nothing
+const minSize = 1024
+
+// Copy is one occurrence of a file content in an image
+type Copy struct {
+	Path        string `json:"path"`
+	Layer       int    `json:"layer"`
+	Instruction string `json:"instruction,omitempty"`
+	// Hidden is set when a later layer deleted or overwrote the file; its
+	// bytes are still shipped
+	Hidden bool `json:"hidden,omitempty"`
+}
+
+// Group is a file content stored more than once in the layers of an image
+type Group struct {
+	Hash   string `json:"hash"`
+	Size   int64  `json:"size"`   // size of one copy
+	Wasted int64  `json:"wasted"` // bytes of all copies but one
+	Copies []Copy `json:"copies"`
+}
+
+// Report lists the duplicate content of one image
+type Report struct {
+	Wasted int64   `json:"wasted"`
+	Groups []Group `json:"groups"`
+}
+
+// Collector hashes the files of one image while its layers are walked. Pass
+// Visit to the layer walk, then call Result.
+type Collector struct {
+	sizes  map[string]int64
+	copies map[string][]Copy
+}
+
+// NewCollector returns an empty collector
+func NewCollector() *Collector {
+	return &Collector{sizes: make(map[string]int64), copies: make(map[string][]Copy)}
+}
+
+// Visit hashes a file written by a layer, including files that a later layer
+// deletes or overwrites
+func (c *Collector) Visit(layer int, p string, hdr *tar.Header, r io.Reader) error {
+	if hdr.Size < minSize {
+		return nil
+	}
+	h := sha256.New()
+	if _, err := io.Copy(h, r); err != nil {
+		return err
+	}
+	sum := hex.EncodeToString(h.Sum(nil))
+	c.sizes[sum] = hdr.Size
+	c.copies[sum] = append(c.copies[sum], Copy{Path: p, Layer: layer})
+	return nil
+}
+
+// Result returns the contents stored more than once, most wasted bytes first
+func (c *Collector) Result(img *inspect.Image) Report {
+	var r Report
+	for sum, copies := range c.copies {
+		if len(copies) < 2 {
+			continue
+		}
+		g := Group{Hash: sum, Size: c.sizes[sum]}
+		for _, cp := range copies {
+			cp.Instruction = img.LayerLabel(cp.Layer)
+			cp.Hidden = !img.Visible(cp.Path, cp.Layer)
+			g.Copies = append(g.Copies, cp)
+		}
+		sort.Slice(g.Copies, func(i, j int) bool {
+			if g.Copies[i].Layer != g.Copies[j].Layer {
+				return g.Copies[i].Layer < g.Copies[j].Layer
+			}
+			return g.Copies[i].Path < g.Copies[j].Path
+		})
+		g.Wasted = g.Size * int64(len(g.Copies)-1)
+		r.Wasted += g.Wasted
+		r.Groups = append(r.Groups, g)
+	}
+	sort.Slice(r.Groups, func(i, j int) bool {
+		if r.Groups[i].Wasted != r.Groups[j].Wasted {
+			return r.Groups[i].Wasted > r.Groups[j].Wasted
+		}
+		return r.Groups[i].Copies[0].Path < r.Groups[j].Copies[0].Path
+	})
+	return r
+}
@@ -0,0 +1,108 @@
+package dupes
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/jtodic/docker-time-machine/pkg/report"
+)
+
+// Version is the duplicate content report of an analyzed commit or tag
+type Version struct {
+	Name   string
+	Report *Report // nil when the image could not be inspected
+}
+
+// maxGroups limits the groups listed for the newest version
+const maxGroups = 15
+
+// Sections returns the wasted bytes per version, newest first, and the
+// duplicate groups of the newest scanned version
+func Sections(versions []Version) []report.Section {
+	summary := report.Section{
+		Title:   "♊ Duplicate Content",
+		Headers: []string{"Version", "Groups", "Copies", "Wasted", "Diff"},
+	}
+
+	var latest *Version
+	for i, v := range versions {
+		if v.Report == nil {
+			continue
+		}
+		if latest == nil {
+			latest = &versions[i]
+		}
+
+		diff := ""
+		if j := report.NextOlder(versions, i, func(v Version) bool { return v.Report != nil }); j >= 0 {
+			diff = report.FormatBytesDiff(v.Report.Wasted - versions[j].Report.Wasted)
+		}
+
+		copies := 0
+		for _, g := range v.Report.Groups {
+			copies += len(g.Copies)
+		}
+		summary.Rows = append(summary.Rows, []string{
+			v.Name,
+			fmt.Sprintf("%d", len(v.Report.Groups)),
+			fmt.Sprintf("%d", copies),
+			report.FormatBytes(v.Report.Wasted),
+			diff,
+		})
+	}
+
+	if latest == nil {
+		return []report.Section{summary}
+	}
+
+	groups := report.Section{
+		Title:   fmt.Sprintf("♊ Duplicate Files in %s", latest.Name),
+		Headers: []string{"Size", "Copies", "Wasted", "Paths", "Instructions"},
+	}
+	for i, g := range latest.Report.Groups {
+		if i == maxGroups {
+			groups.Note = fmt.Sprintf("Showing the %d largest of %d groups.", maxGroups, len(latest.Report.Groups))
+			break
+		}
+		groups.Rows = append(groups.Rows, []string{
+			report.FormatBytes(g.Size),
+			fmt.Sprintf("%d", len(g.Copies)),
+			report.FormatBytes(g.Wasted),
+			paths(g.Copies),
+			instructions(g.Copies),
+		})
+	}
+
+	return []report.Section{summary, groups}
+}
+
+// paths lists the distinct paths of the copies, marking the ones hidden by a
+// later layer
+func paths(copies []Copy) string {
+	var list []string
+	seen := make(map[string]bool)
+	for _, c := range copies {
+		p := c.Path
+		if c.Hidden {
+			p += " (hidden)"
+		}
+		if !seen[p] {
+			seen[p] = true
+			list = append(list, p)
+		}
+	}
+	return strings.Join(list, ", ")
+}
+
+// instructions lists the distinct layers that wrote the copies
+func instructions(copies []Copy) string {
+	var list []string
+	seen := make(map[int]bool)
+	for _, c := range copies {
+		if !seen[c.Layer] {
+			seen[c.Layer] = true
+			list = append(list, fmt.Sprintf("%d %s", c.Layer, report.Truncate(c.Instruction, 30)))
+		}
+	}
+	return strings.Join(list, "; ")
+}
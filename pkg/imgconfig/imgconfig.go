@@ -0,0 +1,225 @@
+package imgconfig
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/jtodic/docker-time-machine/pkg/report"
+	dockerspec "github.com/moby/docker-image-spec/specs-go/v1"
+)
+
+// Config is the runtime configuration of an image, as set by USER,
+// ENTRYPOINT, CMD, ENV, EXPOSE, VOLUME, WORKDIR, LABEL and HEALTHCHECK
+type Config struct {
+	User        string            `json:"user,omitempty"`
+	Entrypoint  []string          `json:"entrypoint,omitempty"`
+	Cmd         []string          `json:"cmd,omitempty"`
+	Env         []string          `json:"env,omitempty"`
+	Ports       []string          `json:"ports,omitempty"`
+	Volumes     []string          `json:"volumes,omitempty"`
+	WorkingDir  string            `json:"workdir,omitempty"`
+	Labels      map[string]string `json:"labels,omitempty"`
+	Healthcheck string            `json:"healthcheck,omitempty"`
+}
+
+// FromImage converts the config of an image, as returned by the Docker API
+// and stored in registry config blobs. It returns nil for a nil config.
+func FromImage(c *dockerspec.DockerOCIImageConfig) *Config {
+	if c == nil {
+		return nil
+	}
+	return &Config{
+		User:        c.User,
+		Entrypoint:  c.Entrypoint,
+		Cmd:         c.Cmd,
+		Env:         c.Env,
+		Ports:       sortedKeys(c.ExposedPorts),
+		Volumes:     sortedKeys(c.Volumes),
+		WorkingDir:  c.WorkingDir,
+		Labels:      c.Labels,
+		Healthcheck: healthcheck(c.Healthcheck),
+	}
+}
+
+// healthcheck describes a HEALTHCHECK the way it is written in a Dockerfile
+func healthcheck(h *dockerspec.HealthcheckConfig) string {
+	if h == nil || len(h.Test) == 0 {
+		return ""
+	}
+	if h.Test[0] == "NONE" {
+		return "NONE"
+	}
+
+	var options []string
+	for _, o := range []struct {
+		name string
+		d    time.Duration
+	}{
+		{"interval", h.Interval},
+		{"timeout", h.Timeout},
+		{"start-period", h.StartPeriod},
+		{"start-interval", h.StartInterval},
+	} {
+		if o.d > 0 {
+			options = append(options, fmt.Sprintf("--%s=%s", o.name, o.d))
+		}
+	}
+	if h.Retries > 0 {
+		options = append(options, fmt.Sprintf("--retries=%d", h.Retries))
+	}
+
+	var cmd string
+	switch h.Test[0] {
+	case "CMD-SHELL":
+		cmd = "CMD " + strings.Join(h.Test[1:], " ")
+	case "CMD":
+		cmd = "CMD " + command(h.Test[1:])
+	default:
+		cmd = command(h.Test)
+	}
+	return strings.Join(append(options, cmd), " ")
+}
+
+// command renders an exec form command as its JSON array
+func command(args []string) string {
+	if len(args) == 0 {
+		return ""
+	}
+	out, _ := json.Marshal(args)
+	return string(out)
+}
+
+func sortedKeys(m map[string]struct{}) []string {
+	if len(m) == 0 {
+		return nil
+	}
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Change is a config setting that differs between two versions. Key names
+// the variable, port, volume or label for the settings that hold several.
+type Change struct {
+	Setting string `json:"setting"` // the Dockerfile instruction, e.g. ENV
+	Key     string `json:"key,omitempty"`
+	Before  string `json:"before,omitempty"`
+	After   string `json:"after,omitempty"`
+	// Warning flags the changes that often cause incidents
+	Warning string `json:"warning,omitempty"`
+}
+
+// VersionDiff lists the config changes from one analyzed version to the next
+type VersionDiff struct {
+	From    string   `json:"from"`
+	To      string   `json:"to"`
+	Changes []Change `json:"changes"`
+}
+
+// Version is the config of an analyzed commit or tag
+type Version struct {
+	Name   string
+	Config *Config // nil when the config could not be read
+}
+
+// Compare returns the settings that changed from older to newer, in
+// Dockerfile order
+func Compare(from, to string, older, newer *Config) VersionDiff {
+	diff := VersionDiff{From: from, To: to}
+	add := func(setting, key, before, after string) {
+		if before != after {
+			diff.Changes = append(diff.Changes, Change{Setting: setting, Key: key, Before: before, After: after})
+		}
+	}
+
+	add("USER", "", older.User, newer.User)
+	if isRoot(newer.User) && !isRoot(older.User) {
+		diff.Changes[len(diff.Changes)-1].Warning = "runs as root"
+	}
+	add("WORKDIR", "", older.WorkingDir, newer.WorkingDir)
+	add("ENTRYPOINT", "", command(older.Entrypoint), command(newer.Entrypoint))
+	add("CMD", "", command(older.Cmd), command(newer.Cmd))
+
+	for _, c := range compareMaps(envMap(older.Env), envMap(newer.Env)) {
+		add("ENV", c.Key, c.Before, c.After)
+	}
+	for _, c := range compareMaps(setMap(older.Ports), setMap(newer.Ports)) {
+		add("EXPOSE", c.Key, c.Before, c.After)
+	}
+	for _, c := range compareMaps(setMap(older.Volumes), setMap(newer.Volumes)) {
+		add("VOLUME", c.Key, c.Before, c.After)
+	}
+	for _, c := range compareMaps(older.Labels, newer.Labels) {
+		add("LABEL", c.Key, c.Before, c.After)
+	}
+
+	add("HEALTHCHECK", "", older.Healthcheck, newer.Healthcheck)
+	if hasHealthcheck(older.Healthcheck) && !hasHealthcheck(newer.Healthcheck) {
+		diff.Changes[len(diff.Changes)-1].Warning = "healthcheck dropped"
+	}
+	return diff
+}
+
+// compareMaps returns the keys added, removed or changed, sorted by key.
+// Added keys have no Before, removed keys no After.
+func compareMaps(older, newer map[string]string) []Change {
+	var changes []Change
+	for k, v := range newer {
+		if old, ok := older[k]; !ok || old != v {
+			changes = append(changes, Change{Key: k, Before: old, After: v})
+		}
+	}
+	for k, v := range older {
+		if _, ok := newer[k]; !ok {
+			changes = append(changes, Change{Key: k, Before: v})
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Key < changes[j].Key })
+	return changes
+}
+
+// envMap maps the variables of an environment to their values
+func envMap(env []string) map[string]string {
+	m := make(map[string]string, len(env))
+	for _, e := range env {
+		k, v, _ := strings.Cut(e, "=")
+		m[k] = v
+	}
+	return m
+}
+
+// setMap turns a list of ports or volumes into a map whose values show
+// presence, so added and removed entries read like the other settings
+func setMap(list []string) map[string]string {
+	m := make(map[string]string, len(list))
+	for _, s := range list {
+		m[s] = s
+	}
+	return m
+}
+
+// isRoot reports whether a USER value runs the container as root; an empty
+// user defaults to root
+func isRoot(user string) bool {
+	name, _, _ := strings.Cut(user, ":")
+	return name == "" || name == "root" || name == "0"
+}
+
+func hasHealthcheck(h string) bool {
+	return h != "" && h != "NONE"
+}
+
+// CompareVersions compares each version with the next older version whose
+// config is known. Versions are ordered newest first.
+func CompareVersions(versions []Version) []VersionDiff {
+	return report.ConsecutivePairs(versions, func(v Version) bool { return v.Config != nil },
+		func(older, newer Version) VersionDiff {
+			return Compare(older.Name, newer.Name, older.Config, newer.Config)
+		})
+}
@@ -0,0 +1,52 @@
+package imgconfig
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/jtodic/docker-time-machine/pkg/report"
+)
+
+// maxValueLen keeps long commands and label values readable in tables
+const maxValueLen = 60
+
+// Section lists the config changes between consecutive versions, newest
+// first
+func Section(diffs []VersionDiff) report.Section {
+	section := report.Section{
+		Title:   "🧾 Config Changes",
+		Headers: []string{"Version", "Setting", "Before", "After", "Warning"},
+	}
+
+	flagged := false
+	for _, d := range diffs {
+		version := fmt.Sprintf("%s → %s", d.From, d.To)
+		for _, c := range d.Changes {
+			setting := c.Setting
+			if c.Key != "" {
+				setting += " " + c.Key
+			}
+			warning := ""
+			if c.Warning != "" {
+				warning = "⚠️ " + c.Warning
+				flagged = true
+			}
+			section.Rows = append(section.Rows, []string{
+				version,
+				setting,
+				value(c.Before),
+				value(c.After),
+				warning,
+			})
+		}
+	}
+	if flagged {
+		section.Note = "Flagged changes make the container run as root or drop its healthcheck."
+	}
+	return section
+}
+
+// value formats a setting for a table cell; a dash marks an unset setting
+func value(s string) string {
+	return report.Truncate(report.OrDash(strings.ReplaceAll(s, "\n", " ")), maxValueLen)
+}
@@ -0,0 +1,231 @@
+package inspect
+
+import (
+	"archive/tar"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// Archive is an image exported with `docker save`, extracted to a temporary
+// directory so its layers can be read in order
+type Archive struct {
+	dir    string
+	Layers []LayerSource
+	// History is the build history from the image config, including
+	// entries that did not create a layer, oldest first
+	History []HistoryEntry
+}
+
+// HistoryEntry is an entry of the image config history
+type HistoryEntry struct {
+	CreatedBy  string `json:"created_by"`
+	EmptyLayer bool   `json:"empty_layer,omitempty"`
+}
+
+// imageConfig is the part of the image config blob the archive reads
+type imageConfig struct {
+	History []HistoryEntry `json:"history"`
+}
+
+// archiveManifest is an entry of manifest.json in a `docker save` archive
+type archiveManifest struct {
+	Config   string   `json:"Config"`
+	RepoTags []string `json:"RepoTags"`
+	Layers   []string `json:"Layers"`
+}
+
+// ExtractArchive extracts a `docker save` archive (legacy or OCI layout) and
+// returns its layers, lowest first. Call Close to remove the extracted files.
+func ExtractArchive(r io.Reader) (*Archive, error) {
+	dir, err := os.MkdirTemp("", "dtm-image-")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temp dir: %w", err)
+	}
+
+	archive := &Archive{dir: dir}
+	if err := archive.extract(r); err != nil {
+		archive.Close()
+		return nil, err
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, "manifest.json"))
+	if err != nil {
+		archive.Close()
+		return nil, fmt.Errorf("archive has no manifest.json: %w", err)
+	}
+
+	var manifests []archiveManifest
+	if err := json.Unmarshal(data, &manifests); err != nil {
+		archive.Close()
+		return nil, fmt.Errorf("failed to parse manifest.json: %w", err)
+	}
+	if len(manifests) == 0 {
+		archive.Close()
+		return nil, fmt.Errorf("archive contains no images")
+	}
+
+	for _, layer := range manifests[0].Layers {
+		layerPath := filepath.Join(dir, filepath.FromSlash(layer))
+		archive.Layers = append(archive.Layers, LayerSource{
+			Digest: layerDigest(layer),
+			Open: func() (io.ReadCloser, error) {
+				return os.Open(layerPath)
+			},
+		})
+	}
+
+	// Label layers with the instruction that created them. The config is
+	// optional; the layers can still be walked without it.
+	if data, err := os.ReadFile(filepath.Join(dir, filepath.FromSlash(manifests[0].Config))); err == nil {
+		var config imageConfig
+		if json.Unmarshal(data, &config) == nil {
+			archive.History = config.History
+			i := 0
+			for _, h := range config.History {
+				if h.EmptyLayer || i >= len(archive.Layers) {
+					continue
+				}
+				archive.Layers[i].CreatedBy = h.CreatedBy
+				i++
+			}
+		}
+	}
+
+	return archive, nil
+}
+
+// extract writes all regular files of the archive below the temp directory.
+// Symlinks and hard links, which the legacy exporter writes when the same
+// layer appears twice, are resolved to the files they point to.
+func (a *Archive) extract(r io.Reader) error {
+	var links []*tar.Header
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read image archive: %w", err)
+		}
+
+		switch hdr.Typeflag {
+		case tar.TypeReg:
+		case tar.TypeSymlink, tar.TypeLink:
+			// A link can precede its target in the archive
+			links = append(links, hdr)
+			continue
+		default:
+			continue
+		}
+
+		name, err := archivePath(hdr.Name)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(a.dir, name)
+		if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+			return err
+		}
+
+		f, err := os.Create(target)
+		if err != nil {
+			return err
+		}
+		_, err = io.Copy(f, tr)
+		f.Close()
+		if err != nil {
+			return fmt.Errorf("failed to extract %s: %w", hdr.Name, err)
+		}
+	}
+
+	// Links to links resolve once their target exists. Links whose target is
+	// missing are left out; a layer behind one fails when it is opened.
+	for len(links) > 0 {
+		var pending []*tar.Header
+		for _, hdr := range links {
+			err := a.link(hdr)
+			if errors.Is(err, fs.ErrNotExist) {
+				pending = append(pending, hdr)
+			} else if err != nil {
+				return err
+			}
+		}
+		if len(pending) == len(links) {
+			return nil
+		}
+		links = pending
+	}
+	return nil
+}
+
+// link creates the file of a symlink or hard link entry as a link to its
+// target below the temp directory
+func (a *Archive) link(hdr *tar.Header) error {
+	name, err := archivePath(hdr.Name)
+	if err != nil {
+		return err
+	}
+
+	// Hard link targets are relative to the archive root, symlink targets to
+	// the directory of the link
+	target := hdr.Linkname
+	if hdr.Typeflag == tar.TypeSymlink {
+		target = path.Join(path.Dir(hdr.Name), target)
+	}
+	targetName, err := archivePath(target)
+	if err != nil || path.IsAbs(hdr.Linkname) {
+		return fmt.Errorf("invalid link in image archive: %s -> %s", hdr.Name, hdr.Linkname)
+	}
+
+	linkPath := filepath.Join(a.dir, name)
+	if err := os.MkdirAll(filepath.Dir(linkPath), 0755); err != nil {
+		return err
+	}
+	targetPath := filepath.Join(a.dir, targetName)
+	info, err := os.Stat(targetPath)
+	if err != nil {
+		return err
+	}
+	// Directories cannot be hard linked
+	if info.IsDir() {
+		err = os.Symlink(targetPath, linkPath)
+	} else {
+		err = os.Link(targetPath, linkPath)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to link %s: %w", hdr.Name, err)
+	}
+	return nil
+}
+
+// archivePath returns the path of an archive entry below the temp directory,
+// rejecting entries escaping it
+func archivePath(p string) (string, error) {
+	name := filepath.Clean(filepath.FromSlash(p))
+	if filepath.IsAbs(name) || name == ".." || strings.HasPrefix(name, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("invalid path in image archive: %s", p)
+	}
+	return name, nil
+}
+
+// Close removes the extracted files
+func (a *Archive) Close() error {
+	return os.RemoveAll(a.dir)
+}
+
+// layerDigest derives a layer identifier from its path in the archive, e.g.
+// "blobs/sha256/<hex>" (OCI layout) or "<hex>/layer.tar" (legacy layout)
+func layerDigest(layerPath string) string {
+	if strings.HasPrefix(layerPath, "blobs/sha256/") {
+		return "sha256:" + strings.TrimPrefix(layerPath, "blobs/sha256/")
+	}
+	return strings.TrimSuffix(layerPath, "/layer.tar")
+}
@@ -0,0 +1,95 @@
+package inspect
+
+import (
+	"path"
+	"sort"
+)
+
+// PathDelta is the size change of a file or directory between two versions
+type PathDelta struct {
+	Path   string `json:"path"`
+	Before int64  `json:"before"`
+	After  int64  `json:"after"`
+	Diff   int64  `json:"diff"`
+}
+
+// VersionDiff lists the files and directories that grew the most from one
+// analyzed version to the next
+type VersionDiff struct {
+	From     string      `json:"from"`
+	To       string      `json:"to"`
+	TopFiles []PathDelta `json:"top_files"`
+	TopDirs  []PathDelta `json:"top_dirs"`
+}
+
+// Compare returns the topN files and directories that grew the most from
+// older to newer. A directory is left out when all of its growth comes from a
+// single subdirectory, so the most specific directory is reported.
+func Compare(from, to string, older, newer *Image, topN int) VersionDiff {
+	diff := VersionDiff{From: from, To: to}
+
+	files := make(map[string]*PathDelta)
+	for p, f := range older.Files {
+		files[p] = &PathDelta{Path: p, Before: f.Size}
+	}
+	for p, f := range newer.Files {
+		d, ok := files[p]
+		if !ok {
+			d = &PathDelta{Path: p}
+			files[p] = d
+		}
+		d.After = f.Size
+	}
+
+	dirs := make(map[string]*PathDelta)
+	for p, d := range files {
+		d.Diff = d.After - d.Before
+		for dir := path.Dir(p); dir != "/"; dir = path.Dir(dir) {
+			dd, ok := dirs[dir]
+			if !ok {
+				dd = &PathDelta{Path: dir}
+				dirs[dir] = dd
+			}
+			dd.Before += d.Before
+			dd.After += d.After
+			dd.Diff += d.Diff
+		}
+	}
+
+	// Drop directories whose growth is entirely explained by one child
+	redundant := make(map[string]bool)
+	for p, d := range dirs {
+		if parent, ok := dirs[path.Dir(p)]; ok && parent.Diff == d.Diff {
+			redundant[parent.Path] = true
+		}
+	}
+	for p := range redundant {
+		delete(dirs, p)
+	}
+
+	diff.TopFiles = topGrowing(files, topN)
+	diff.TopDirs = topGrowing(dirs, topN)
+	return diff
+}
+
+// topGrowing returns the n entries with the largest positive growth
+func topGrowing(deltas map[string]*PathDelta, n int) []PathDelta {
+	var grown []PathDelta
+	for _, d := range deltas {
+		if d.Diff > 0 {
+			grown = append(grown, *d)
+		}
+	}
+
+	sort.Slice(grown, func(i, j int) bool {
+		if grown[i].Diff != grown[j].Diff {
+			return grown[i].Diff > grown[j].Diff
+		}
+		return grown[i].Path < grown[j].Path
+	})
+
+	if n > 0 && len(grown) > n {
+		grown = grown[:n]
+	}
+	return grown
+}
@@ -0,0 +1,226 @@
+package inspect
+
+import (
+	"encoding/json"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/jtodic/docker-time-machine/pkg/report"
+)
+
+// DefaultDirDepth groups the filesystem by its first two path components,
+// e.g. /usr/lib or /app/node_modules
+const DefaultDirDepth = 2
+
+// topDirs is the number of directories charted and listed per report; the
+// others are summed up as "other"
+const topDirs = 8
+
+// otherDirs labels the directories outside the top ones
+const otherDirs = "other"
+
+// DirSize is the size of the files below a directory of the final filesystem
+type DirSize struct {
+	Path string `json:"path"`
+	Size int64  `json:"size"`
+}
+
+// DirSizes aggregates the final filesystem by directory, depth path
+// components deep, largest first. Files closer to the root count toward the
+// directory that contains them, so the directories never overlap.
+func (img *Image) DirSizes(depth int) []DirSize {
+	if depth <= 0 {
+		depth = DefaultDirDepth
+	}
+	sizes := make(map[string]int64)
+	for p, f := range img.Files {
+		sizes[dirAt(p, depth)] += f.Size
+	}
+
+	dirs := make([]DirSize, 0, len(sizes))
+	for p, size := range sizes {
+		if size > 0 {
+			dirs = append(dirs, DirSize{Path: p, Size: size})
+		}
+	}
+	sort.Slice(dirs, func(i, j int) bool {
+		if dirs[i].Size != dirs[j].Size {
+			return dirs[i].Size > dirs[j].Size
+		}
+		return dirs[i].Path < dirs[j].Path
+	})
+	return dirs
+}
+
+// dirAt returns the directory of the file at p cut to depth components
+func dirAt(p string, depth int) string {
+	dir := path.Dir(p)
+	parts := strings.Split(strings.TrimPrefix(dir, "/"), "/")
+	if len(parts) > depth {
+		parts = parts[:depth]
+	}
+	return "/" + strings.Join(parts, "/")
+}
+
+// DirVersion is the directory breakdown of an analyzed commit or tag
+type DirVersion struct {
+	Name    string
+	Dirs    []DirSize
+	Scanned bool // false when the image could not be inspected
+}
+
+// dirTrend picks the directories that were the largest in any version and
+// returns their size per version, with the remaining directories summed up as
+// the last entry. Sizes of versions that were not scanned are nil.
+func dirTrend(versions []DirVersion) ([]string, [][]*int64) {
+	peak := make(map[string]int64)
+	for _, v := range versions {
+		for _, d := range v.Dirs {
+			peak[d.Path] = max(peak[d.Path], d.Size)
+		}
+	}
+	names := make([]string, 0, len(peak))
+	for p := range peak {
+		names = append(names, p)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		if peak[names[i]] != peak[names[j]] {
+			return peak[names[i]] > peak[names[j]]
+		}
+		return names[i] < names[j]
+	})
+	if len(names) > topDirs {
+		names = append(names[:topDirs], otherDirs)
+	}
+
+	index := make(map[string]int, len(names))
+	for i, p := range names {
+		index[p] = i
+	}
+	sizes := make([][]*int64, len(names))
+	for i := range sizes {
+		sizes[i] = make([]*int64, len(versions))
+	}
+	for j, v := range versions {
+		if !v.Scanned {
+			continue
+		}
+		for i := range names {
+			sizes[i][j] = new(int64)
+		}
+		for _, d := range v.Dirs {
+			i, ok := index[d.Path]
+			if !ok {
+				i = index[otherDirs]
+			}
+			*sizes[i][j] += d.Size
+		}
+	}
+	return names, sizes
+}
+
+// DirTrendSection lists the size of the largest directories per version,
+// newest first. It needs at least two scanned versions.
+func DirTrendSection(versions []DirVersion) report.Section {
+	section := report.Section{
+		Title:   "📁 Directory Size Over Time",
+		Headers: []string{"Directory"},
+	}
+	scanned := 0
+	for _, v := range versions {
+		section.Headers = append(section.Headers, v.Name)
+		if v.Scanned {
+			scanned++
+		}
+	}
+	if scanned < 2 {
+		return report.Section{}
+	}
+
+	names, sizes := dirTrend(versions)
+	for i, p := range names {
+		row := []string{p}
+		for _, size := range sizes[i] {
+			if size == nil {
+				row = append(row, "-")
+			} else {
+				row = append(row, report.FormatBytes(*size))
+			}
+		}
+		section.Rows = append(section.Rows, row)
+	}
+
+	// Growth from the oldest to the newest scanned version
+	newest, oldest := -1, -1
+	for j, v := range versions {
+		if v.Scanned {
+			if newest < 0 {
+				newest = j
+			}
+			oldest = j
+		}
+	}
+	type change struct {
+		dir  string
+		diff int64
+	}
+	var changes []change
+	for i, p := range names {
+		if diff := *sizes[i][newest] - *sizes[i][oldest]; p != otherDirs && diff != 0 {
+			changes = append(changes, change{p, diff})
+		}
+	}
+	sort.SliceStable(changes, func(i, j int) bool {
+		return abs(changes[i].diff) > abs(changes[j].diff)
+	})
+	var notes []string
+	for i, c := range changes {
+		if i == 3 {
+			break
+		}
+		notes = append(notes, fmt.Sprintf("%s %s", c.dir, report.FormatBytesDiff(c.diff)))
+	}
+	if len(notes) > 0 {
+		section.Note = fmt.Sprintf("From %s to %s: %s.", versions[oldest].Name, versions[newest].Name, strings.Join(notes, ", "))
+	}
+	return section
+}
+
+// DirChartData returns the size in MB of the largest directories per version
+// for the HTML chart, or null when no version was scanned
+func DirChartData(versions []DirVersion) string {
+	found := false
+	for _, v := range versions {
+		found = found || v.Scanned
+	}
+	if !found {
+		return "null"
+	}
+
+	names, sizes := dirTrend(versions)
+	data := struct {
+		Dirs  []string     `json:"dirs"`
+		Sizes [][]*float64 `json:"sizes"`
+	}{Dirs: names, Sizes: make([][]*float64, len(names))}
+	for i := range names {
+		for _, size := range sizes[i] {
+			var mb *float64
+			if size != nil {
+				v := float64(*size) / 1024 / 1024
+				mb = &v
+			}
+			data.Sizes[i] = append(data.Sizes[i], mb)
+		}
+	}
+	out, _ := json.Marshal(data)
+	return string(out)
+}
+
+func abs(n int64) int64 {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
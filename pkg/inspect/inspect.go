@@ -0,0 +1,393 @@
+package inspect
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"path"
+	"sort"
+	"strings"
+)
+
+// ChangeType describes how a layer changed a file
+type ChangeType string
+
+const (
+	Added    ChangeType = "added"
+	Modified ChangeType = "modified"
+	Deleted  ChangeType = "deleted"
+)
+
+// Whiteout markers used by the OCI and Docker layer formats
+const (
+	whiteoutPrefix = ".wh."
+	opaqueWhiteout = ".wh..wh..opq"
+)
+
+// LayerSource opens the tar stream of a single layer. The stream may be
+// uncompressed or gzip-compressed.
+type LayerSource struct {
+	Digest    string
+	CreatedBy string // instruction that created the layer, if known
+	Open      func() (io.ReadCloser, error)
+}
+
+// FileChange is a file added, modified or deleted by a layer
+type FileChange struct {
+	Path string     `json:"path"`
+	Type ChangeType `json:"type"`
+	Size int64      `json:"size"` // size written by the layer, 0 for deletions
+}
+
+// LayerFiles summarizes the file changes made by a single layer
+type LayerFiles struct {
+	Index      int    `json:"index"` // position in the image, 0 is the lowest layer
+	Digest     string `json:"digest,omitempty"`
+	CreatedBy  string `json:"created_by,omitempty"`
+	Added      int    `json:"added"`
+	Modified   int    `json:"modified"`
+	Deleted    int    `json:"deleted"`
+	AddedBytes int64  `json:"added_bytes"`
+	// WastedBytes were written by this layer but overwritten or deleted by a
+	// later one, so they are shipped without being visible
+	WastedBytes int64        `json:"wasted_bytes"`
+	Changes     []FileChange `json:"-"`
+}
+
+// WastedFile is a file hidden from the final filesystem by a later layer
+type WastedFile struct {
+	Path      string `json:"path"`
+	Size      int64  `json:"size"`
+	Layer     int    `json:"layer"`      // layer that wrote the file
+	RemovedBy int    `json:"removed_by"` // layer that overwrote or deleted it
+}
+
+// File is an entry of the final image filesystem
+type File struct {
+	Size  int64 `json:"size"`
+	Layer int   `json:"layer"` // index of the layer that last wrote the file
+}
+
+// Image is the result of walking all layers of an image
+type Image struct {
+	Layers []LayerFiles `json:"layers"`
+	// WastedBytes is the total size of files hidden by later layers
+	WastedBytes int64 `json:"wasted_bytes"`
+	// Efficiency is the share of written bytes visible in the final
+	// filesystem, 1 when nothing is wasted
+	Efficiency float64 `json:"efficiency"`
+	// Waste lists the hidden files, largest first
+	Waste []WastedFile `json:"-"`
+	// Files is the final filesystem, keyed by absolute path
+	Files map[string]File `json:"-"`
+	// Contents holds the final contents of the files selected by
+	// WalkOptions.Capture, keyed by absolute path
+	Contents map[string][]byte `json:"-"`
+
+	// children indexes the paths in Files by their parent directory, so
+	// whiteouts only visit the subtree they remove
+	children map[string]map[string]bool
+}
+
+// WalkOptions controls what Walk records besides file metadata
+type WalkOptions struct {
+	// Capture selects files whose contents are kept in Image.Contents
+	Capture func(path string) bool
+	// Visit is called with the contents of every regular file a layer
+	// writes, including files a later layer overwrites or deletes. Returning
+	// an error aborts the walk.
+	Visit func(layer int, path string, hdr *tar.Header, r io.Reader) error
+}
+
+// MultiVisit combines visit functions for WalkOptions.Visit. Each file is
+// streamed to all of them at once, so every visitor can read it in full
+// while the layer is read only once. Nil functions are skipped.
+func MultiVisit(visits ...func(layer int, path string, hdr *tar.Header, r io.Reader) error) func(layer int, path string, hdr *tar.Header, r io.Reader) error {
+	var active []func(layer int, path string, hdr *tar.Header, r io.Reader) error
+	for _, visit := range visits {
+		if visit != nil {
+			active = append(active, visit)
+		}
+	}
+	switch len(active) {
+	case 0:
+		return nil
+	case 1:
+		return active[0]
+	}
+
+	return func(layer int, p string, hdr *tar.Header, r io.Reader) error {
+		writers := make([]io.Writer, len(active))
+		pipes := make([]*io.PipeWriter, len(active))
+		errs := make(chan error, len(active))
+		for i, visit := range active {
+			pr, pw := io.Pipe()
+			writers[i], pipes[i] = pw, pw
+			go func() {
+				err := visit(layer, p, hdr, pr)
+				// Drain what the visitor did not read so the others continue
+				io.Copy(io.Discard, pr)
+				errs <- err
+			}()
+		}
+
+		_, err := io.Copy(io.MultiWriter(writers...), r)
+		for _, pw := range pipes {
+			pw.CloseWithError(err)
+		}
+		for range active {
+			if visitErr := <-errs; visitErr != nil && err == nil {
+				err = visitErr
+			}
+		}
+		return err
+	}
+}
+
+// TotalSize returns the size of all files in the final filesystem
+func (img *Image) TotalSize() int64 {
+	var total int64
+	for _, f := range img.Files {
+		total += f.Size
+	}
+	return total
+}
+
+// Visible reports whether the file at path in the final filesystem is the one
+// written by layer, i.e. it was not overwritten or deleted later
+func (img *Image) Visible(path string, layer int) bool {
+	f, ok := img.Files[path]
+	return ok && f.Layer == layer
+}
+
+// LayerLabel describes a layer by its instruction, or its digest when the
+// instruction is unknown
+func (img *Image) LayerLabel(index int) string {
+	if index < 0 || index >= len(img.Layers) {
+		return "-"
+	}
+	if img.Layers[index].CreatedBy != "" {
+		return img.Layers[index].CreatedBy
+	}
+	return shortDigest(img.Layers[index].Digest)
+}
+
+// Walk reads the layers of an image, lowest first, and records which files
+// each layer adds, modifies and deletes along with the resulting filesystem
+func Walk(layers []LayerSource, opts WalkOptions) (*Image, error) {
+	img := &Image{
+		Files:    make(map[string]File),
+		Contents: make(map[string][]byte),
+		children: make(map[string]map[string]bool),
+	}
+
+	for i, source := range layers {
+		lf, err := walkLayer(img, i, source, opts)
+		if err != nil {
+			return nil, fmt.Errorf("layer %d (%s): %w", i, shortDigest(source.Digest), err)
+		}
+		img.Layers = append(img.Layers, *lf)
+	}
+
+	// Attribute the waste to the layers that wrote the hidden files
+	var written int64
+	for _, w := range img.Waste {
+		img.Layers[w.Layer].WastedBytes += w.Size
+		img.WastedBytes += w.Size
+	}
+	for _, layer := range img.Layers {
+		written += layer.AddedBytes
+	}
+	img.Efficiency = 1
+	if written > 0 {
+		img.Efficiency = 1 - float64(img.WastedBytes)/float64(written)
+	}
+	sort.Slice(img.Waste, func(i, j int) bool {
+		if img.Waste[i].Size != img.Waste[j].Size {
+			return img.Waste[i].Size > img.Waste[j].Size
+		}
+		return img.Waste[i].Path < img.Waste[j].Path
+	})
+
+	return img, nil
+}
+
+// walkLayer applies a single layer to the filesystem in img
+func walkLayer(img *Image, index int, source LayerSource, opts WalkOptions) (*LayerFiles, error) {
+	rc, err := source.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+
+	stream, err := decompress(rc)
+	if err != nil {
+		return nil, err
+	}
+
+	lf := &LayerFiles{Index: index, Digest: source.Digest, CreatedBy: source.CreatedBy}
+	tr := tar.NewReader(stream)
+
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read tar: %w", err)
+		}
+
+		name := normalizePath(hdr.Name)
+		dir, base := path.Split(name)
+
+		switch {
+		case base == opaqueWhiteout:
+			// Opaque directory: hide everything lower layers put below it
+			lf.recordDeletes(img.removeTree(path.Clean(dir), index))
+			continue
+		case strings.HasPrefix(base, whiteoutPrefix):
+			target := path.Join(dir, strings.TrimPrefix(base, whiteoutPrefix))
+			lf.recordDeletes(img.removeTree(target, index))
+			continue
+		}
+
+		switch hdr.Typeflag {
+		case tar.TypeReg, tar.TypeSymlink, tar.TypeLink:
+		default:
+			continue
+		}
+
+		change := FileChange{Path: name, Type: Added, Size: hdr.Size}
+		if old, exists := img.Files[name]; exists {
+			change.Type = Modified
+			lf.Modified++
+			if old.Layer < index && old.Size > 0 {
+				img.Waste = append(img.Waste, WastedFile{Path: name, Size: old.Size, Layer: old.Layer, RemovedBy: index})
+			}
+		} else {
+			lf.Added++
+			img.link(name)
+		}
+		lf.AddedBytes += hdr.Size
+		lf.Changes = append(lf.Changes, change)
+
+		img.Files[name] = File{Size: hdr.Size, Layer: index}
+
+		delete(img.Contents, name)
+		if hdr.Typeflag != tar.TypeReg {
+			continue
+		}
+
+		var contents io.Reader = tr
+		if opts.Capture != nil && opts.Capture(name) {
+			data, err := io.ReadAll(tr)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read %s: %w", name, err)
+			}
+			img.Contents[name] = data
+			contents = bytes.NewReader(data)
+		}
+		if opts.Visit != nil {
+			if err := opts.Visit(index, name, hdr, contents); err != nil {
+				return nil, fmt.Errorf("%s: %w", name, err)
+			}
+		}
+	}
+
+	return lf, nil
+}
+
+// recordDeletes adds deletions to the layer summary
+func (lf *LayerFiles) recordDeletes(paths []string) {
+	for _, p := range paths {
+		lf.Deleted++
+		lf.Changes = append(lf.Changes, FileChange{Path: p, Type: Deleted})
+	}
+}
+
+// link adds a path and its parent directories to the children index
+func (img *Image) link(p string) {
+	for p != "/" {
+		dir := path.Dir(p)
+		if img.children[dir][p] {
+			return
+		}
+		if img.children[dir] == nil {
+			img.children[dir] = make(map[string]bool)
+		}
+		img.children[dir][p] = true
+		p = dir
+	}
+}
+
+// unlink removes a path from the children index, along with the parent
+// directories left without children
+func (img *Image) unlink(p string) {
+	for p != "/" {
+		if _, ok := img.Files[p]; ok || len(img.children[p]) > 0 {
+			return
+		}
+		dir := path.Dir(p)
+		delete(img.children[dir], p)
+		if len(img.children[dir]) == 0 {
+			delete(img.children, dir)
+		}
+		p = dir
+	}
+}
+
+// removeTree deletes a path and everything below it that was written by a
+// lower layer, returning the removed paths in lexical order
+func (img *Image) removeTree(target string, layer int) []string {
+	var removed []string
+	var visit func(p string)
+	visit = func(p string) {
+		children := make([]string, 0, len(img.children[p]))
+		for child := range img.children[p] {
+			children = append(children, child)
+		}
+		sort.Strings(children)
+
+		if f, ok := img.Files[p]; ok && f.Layer < layer {
+			removed = append(removed, p)
+			delete(img.Files, p)
+			delete(img.Contents, p)
+			if f.Size > 0 {
+				img.Waste = append(img.Waste, WastedFile{Path: p, Size: f.Size, Layer: f.Layer, RemovedBy: layer})
+			}
+		}
+		for _, child := range children {
+			visit(child)
+		}
+		img.unlink(p)
+	}
+	visit(path.Clean(target))
+	return removed
+}
+
+// decompress transparently handles gzip-compressed layers
+func decompress(r io.Reader) (io.Reader, error) {
+	br := bufio.NewReader(r)
+	magic, err := br.Peek(2)
+	if err == nil && magic[0] == 0x1f && magic[1] == 0x8b {
+		return gzip.NewReader(br)
+	}
+	return br, nil
+}
+
+// normalizePath turns a tar entry name into an absolute, clean path
+func normalizePath(name string) string {
+	return path.Clean("/" + strings.TrimPrefix(name, "./"))
+}
+
+// shortDigest shortens a digest for messages
+func shortDigest(digest string) string {
+	digest = strings.TrimPrefix(digest, "sha256:")
+	if len(digest) > 12 {
+		return digest[:12]
+	}
+	return digest
+}
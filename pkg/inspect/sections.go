@@ -0,0 +1,110 @@
+package inspect
+
+import (
+	"fmt"
+
+	"github.com/jtodic/docker-time-machine/pkg/report"
+)
+
+// GrowthSections builds the report sections for the top growing files and
+// directories between consecutive versions
+func GrowthSections(diffs []VersionDiff) []report.Section {
+	files := report.Section{
+		Title:   "📄 Top Growing Files",
+		Headers: []string{"Version", "Path", "Before", "After", "Diff"},
+	}
+	dirs := report.Section{
+		Title:   "📁 Top Growing Directories",
+		Headers: []string{"Version", "Directory", "Before", "After", "Diff"},
+	}
+
+	for _, d := range diffs {
+		version := fmt.Sprintf("%s → %s", d.From, d.To)
+		for _, f := range d.TopFiles {
+			files.Rows = append(files.Rows, []string{
+				version, f.Path, report.FormatBytes(f.Before), report.FormatBytes(f.After), report.FormatBytesDiff(f.Diff),
+			})
+		}
+		for _, f := range d.TopDirs {
+			dirs.Rows = append(dirs.Rows, []string{
+				version, f.Path, report.FormatBytes(f.Before), report.FormatBytes(f.After), report.FormatBytesDiff(f.Diff),
+			})
+		}
+	}
+
+	return []report.Section{files, dirs}
+}
+
+// Version is an inspected image with the commit or tag it was built from
+type Version struct {
+	Name  string
+	Image *Image
+}
+
+// WasteSection tracks the bytes hidden by later layers for each version,
+// newest first. Each row names the largest waste that is new compared to the
+// previous version along with the instructions that wrote and removed it.
+func WasteSection(versions []Version) report.Section {
+	section := report.Section{
+		Title:   "🗑️ Wasted Space",
+		Headers: []string{"Version", "Wasted", "Diff", "Efficiency", "Top New Waste", "Written By", "Removed By"},
+	}
+
+	var worst *Version
+	var worstDiff int64
+	var worstFile WastedFile
+	for i, v := range versions {
+		if v.Image == nil {
+			continue
+		}
+
+		// Find the next older inspected version
+		var older *Image
+		if j := report.NextOlder(versions, i, func(v Version) bool { return v.Image != nil }); j >= 0 {
+			older = versions[j].Image
+		}
+
+		diff := ""
+		delta := v.Image.WastedBytes
+		if older != nil {
+			delta -= older.WastedBytes
+			diff = report.FormatBytesDiff(delta)
+		}
+
+		row := []string{v.Name, report.FormatBytes(v.Image.WastedBytes), diff, fmt.Sprintf("%.1f%%", v.Image.Efficiency*100), "-", "-", "-"}
+		if file, ok := newWaste(v.Image, older); ok {
+			row[4] = fmt.Sprintf("%s (%s)", file.Path, report.FormatBytes(file.Size))
+			row[5] = report.Truncate(v.Image.LayerLabel(file.Layer), 40)
+			row[6] = report.Truncate(v.Image.LayerLabel(file.RemovedBy), 40)
+			if delta > worstDiff {
+				worst, worstDiff, worstFile = &versions[i], delta, file
+			}
+		}
+		section.Rows = append(section.Rows, row)
+	}
+
+	if worst != nil {
+		section.Note = fmt.Sprintf("⚠️  Most waste introduced in %s (%s): %s is written by %q and hidden by %q",
+			worst.Name, report.FormatBytesDiff(worstDiff), worstFile.Path,
+			report.Truncate(worst.Image.LayerLabel(worstFile.Layer), 60), report.Truncate(worst.Image.LayerLabel(worstFile.RemovedBy), 60))
+	}
+
+	return section
+}
+
+// newWaste returns the largest wasted file of img that is not wasted, or
+// wasted with a smaller size, in older
+func newWaste(img, older *Image) (WastedFile, bool) {
+	previous := make(map[string]int64)
+	if older != nil {
+		for _, w := range older.Waste {
+			previous[w.Path] += w.Size
+		}
+	}
+	for _, w := range img.Waste {
+		if w.Size > previous[w.Path] {
+			return w, true
+		}
+	}
+	return WastedFile{}, false
+}
@@ -0,0 +1,112 @@
+package inspect
+
+import (
+	"archive/tar"
+	"bufio"
+	"compress/gzip"
+	"fmt"
+	"io"
+)
+
+// LayerSize holds the size of a layer as stored in a registry and as
+// extracted on disk
+type LayerSize struct {
+	Compressed int64 `json:"compressed"`
+	// Uncompressed counts the bytes of the files in the layer, as Docker
+	// reports layer sizes, without tar headers and padding
+	Uncompressed int64 `json:"uncompressed"`
+}
+
+// MeasureLayer reads a layer and returns both of its sizes. Compressed blobs,
+// such as those downloaded from a registry, are decompressed to count the
+// extracted bytes; uncompressed tarballs, such as those of a `docker save`
+// archive, are gzipped to estimate the size a registry would store.
+func MeasureLayer(layer LayerSource) (LayerSize, error) {
+	rc, err := layer.Open()
+	if err != nil {
+		return LayerSize{}, fmt.Errorf("failed to open layer %s: %w", shortDigest(layer.Digest), err)
+	}
+	defer rc.Close()
+
+	raw := &countingWriter{}
+	br := bufio.NewReader(io.TeeReader(rc, raw))
+
+	var size LayerSize
+	if magic, err := br.Peek(2); err == nil && magic[0] == 0x1f && magic[1] == 0x8b {
+		gz, err := gzip.NewReader(br)
+		if err != nil {
+			return LayerSize{}, fmt.Errorf("failed to read layer %s: %w", shortDigest(layer.Digest), err)
+		}
+		size.Uncompressed, err = fileBytes(gz)
+		if err != nil {
+			return LayerSize{}, fmt.Errorf("failed to read layer %s: %w", shortDigest(layer.Digest), err)
+		}
+		// Drain trailing bytes so the raw count covers the whole blob
+		if _, err := io.Copy(io.Discard, br); err != nil {
+			return LayerSize{}, fmt.Errorf("failed to read layer %s: %w", shortDigest(layer.Digest), err)
+		}
+		size.Compressed = raw.n
+		return size, nil
+	}
+
+	compressed := &countingWriter{}
+	gz := gzip.NewWriter(compressed)
+	size.Uncompressed, err = fileBytes(io.TeeReader(br, gz))
+	if err != nil {
+		return LayerSize{}, fmt.Errorf("failed to read layer %s: %w", shortDigest(layer.Digest), err)
+	}
+	if err := gz.Close(); err != nil {
+		return LayerSize{}, err
+	}
+	size.Compressed = compressed.n
+	return size, nil
+}
+
+// fileBytes reads a layer tarball to its end and returns the sum of the sizes
+// of its entries
+func fileBytes(r io.Reader) (int64, error) {
+	var total int64
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return 0, err
+		}
+		total += hdr.Size
+	}
+	// Read the padding after the end-of-archive marker as well, so the whole
+	// stream passes through r
+	_, err := io.Copy(io.Discard, r)
+	return total, err
+}
+
+// countingWriter counts the bytes written to it
+type countingWriter struct {
+	n int64
+}
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	w.n += int64(len(p))
+	return len(p), nil
+}
+
+// SizeCache remembers measured layers by digest, since most layers are
+// shared between the versions of an image
+type SizeCache map[string]LayerSize
+
+// Measure returns the sizes of a layer, reading it only when it has not been
+// measured before
+func (c SizeCache) Measure(layer LayerSource) (LayerSize, error) {
+	if size, ok := c[layer.Digest]; ok && layer.Digest != "" {
+		return size, nil
+	}
+	size, err := MeasureLayer(layer)
+	if err != nil {
+		return LayerSize{}, err
+	}
+	c[layer.Digest] = size
+	return size, nil
+}
@@ -0,0 +1,142 @@
+package junk
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/jtodic/docker-time-machine/pkg/inspect"
+	"github.com/jtodic/docker-time-machine/pkg/report"
+)
+
+// Finding is a group of junk files below one directory written by one layer
+type Finding struct {
+	Rule        string `json:"rule"`
+	Path        string `json:"path"`
+	Files       int    `json:"files"`
+	Size        int64  `json:"size"`
+	Layer       int    `json:"layer"`
+	Instruction string `json:"instruction,omitempty"`
+	Fix         string `json:"fix"`
+}
+
+// Report lists the junk found in one image
+type Report struct {
+	Reclaimable int64     `json:"reclaimable"`
+	Findings    []Finding `json:"findings"`
+}
+
+// Scan checks the final filesystem of an image against the rules and
+// attributes each finding to the layer that wrote the files
+func Scan(img *inspect.Image) Report {
+	type group struct {
+		rule  int
+		root  string
+		layer int
+	}
+	found := make(map[group]*Finding)
+
+	for p, f := range img.Files {
+		for i, rule := range Rules {
+			root, ok := rule.match(p)
+			if !ok {
+				continue
+			}
+			g := group{i, root, f.Layer}
+			finding, ok := found[g]
+			if !ok {
+				finding = &Finding{
+					Rule:        rule.ID,
+					Path:        root,
+					Layer:       f.Layer,
+					Instruction: img.LayerLabel(f.Layer),
+					Fix:         rule.Fix,
+				}
+				found[g] = finding
+			}
+			finding.Files++
+			finding.Size += f.Size
+			break
+		}
+	}
+
+	var r Report
+	for _, finding := range found {
+		if finding.Size == 0 {
+			continue
+		}
+		r.Reclaimable += finding.Size
+		r.Findings = append(r.Findings, *finding)
+	}
+	sort.Slice(r.Findings, func(i, j int) bool {
+		if r.Findings[i].Size != r.Findings[j].Size {
+			return r.Findings[i].Size > r.Findings[j].Size
+		}
+		return r.Findings[i].Path < r.Findings[j].Path
+	})
+	return r
+}
+
+// Version is the junk report of an analyzed commit or tag
+type Version struct {
+	Name   string
+	Report *Report // nil when the image could not be inspected
+}
+
+// maxFindings limits the findings listed for the newest version
+const maxFindings = 15
+
+// Sections returns a per-version summary of reclaimable bytes, newest first,
+// and the findings of the newest scanned version with suggested fixes
+func Sections(versions []Version) []report.Section {
+	summary := report.Section{
+		Title:   "🧹 Reclaimable Junk",
+		Headers: []string{"Version", "Reclaimable", "Diff", "Largest Finding"},
+	}
+
+	var latest *Version
+	for i, v := range versions {
+		if v.Report == nil {
+			continue
+		}
+		if latest == nil {
+			latest = &versions[i]
+		}
+
+		diff := ""
+		if j := report.NextOlder(versions, i, func(v Version) bool { return v.Report != nil }); j >= 0 {
+			diff = report.FormatBytesDiff(v.Report.Reclaimable - versions[j].Report.Reclaimable)
+		}
+
+		largest := "-"
+		if len(v.Report.Findings) > 0 {
+			f := v.Report.Findings[0]
+			largest = fmt.Sprintf("%s %s (%s)", f.Rule, f.Path, report.FormatBytes(f.Size))
+		}
+		summary.Rows = append(summary.Rows, []string{v.Name, report.FormatBytes(v.Report.Reclaimable), diff, largest})
+	}
+
+	if latest == nil {
+		return []report.Section{summary}
+	}
+
+	findings := report.Section{
+		Title:   fmt.Sprintf("🧹 Junk Findings in %s", latest.Name),
+		Headers: []string{"Rule", "Path", "Files", "Size", "Layer", "Suggested Fix"},
+	}
+	for i, f := range latest.Report.Findings {
+		if i == maxFindings {
+			findings.Note = fmt.Sprintf("Showing the %d largest of %d findings.", maxFindings, len(latest.Report.Findings))
+			break
+		}
+		findings.Rows = append(findings.Rows, []string{
+			f.Rule,
+			f.Path,
+			fmt.Sprintf("%d", f.Files),
+			report.FormatBytes(f.Size),
+			report.Truncate(f.Instruction, 40),
+			f.Fix,
+		})
+	}
+
+	return []report.Section{summary, findings}
+}
@@ -0,0 +1,148 @@
+package junk
+
+import (
+	"path"
+	"strings"
+)
+
+// Rule detects a kind of leftover file that is not needed at runtime
+type Rule struct {
+	ID   string
+	Name string
+	Fix  string // suggested Dockerfile change
+	// match returns the directory that groups the matching files, e.g. the
+	// repository root for files below a .git directory
+	match func(p string) (string, bool)
+}
+
+// Rules is the built-in rule set, checked in order; a file is reported by
+// the first matching rule only
+var Rules = []Rule{
+	{
+		ID:    "apt-lists",
+		Name:  "APT package lists",
+		Fix:   "Add `&& rm -rf /var/lib/apt/lists/*` to the RUN that calls apt-get update",
+		match: under("/var/lib/apt/lists"),
+	},
+	{
+		ID:    "apt-cache",
+		Name:  "APT package cache",
+		Fix:   "Run `apt-get clean` in the same RUN as apt-get install",
+		match: under("/var/cache/apt"),
+	},
+	{
+		ID:    "apk-cache",
+		Name:  "APK package cache",
+		Fix:   "Use `apk add --no-cache`",
+		match: under("/var/cache/apk"),
+	},
+	{
+		ID:    "yum-cache",
+		Name:  "YUM/DNF package cache",
+		Fix:   "Run `yum clean all` or `dnf clean all` in the same RUN as the install",
+		match: anyUnder("/var/cache/yum", "/var/cache/dnf"),
+	},
+	{
+		ID:    "pip-cache",
+		Name:  "pip cache",
+		Fix:   "Use `pip install --no-cache-dir`",
+		match: segment("/.cache/pip"),
+	},
+	{
+		ID:    "npm-cache",
+		Name:  "npm cache",
+		Fix:   "Run `npm cache clean --force` in the same RUN, or use `RUN --mount=type=cache,target=/root/.npm`",
+		match: segment("/.npm/_cacache"),
+	},
+	{
+		ID:    "yarn-cache",
+		Name:  "Yarn cache",
+		Fix:   "Run `yarn cache clean` in the same RUN, or use a cache mount",
+		match: segment("/.cache/yarn"),
+	},
+	{
+		ID:   "go-cache",
+		Name: "Go build and module cache",
+		Fix:  "Build in a separate stage and copy only the binary, or use cache mounts for /root/.cache/go-build and /go/pkg/mod",
+		match: func(p string) (string, bool) {
+			if root, ok := segment("/.cache/go-build")(p); ok {
+				return root, true
+			}
+			return under("/go/pkg/mod")(p)
+		},
+	},
+	{
+		ID:    "git",
+		Name:  "Git metadata",
+		Fix:   "Add `.git` to .dockerignore",
+		match: segment("/.git"),
+	},
+	{
+		ID:   "tests",
+		Name: "Tests and fixtures",
+		Fix:  "Exclude tests with .dockerignore, or prune them from dependencies (e.g. `npm prune --omit=dev`)",
+		match: func(p string) (string, bool) {
+			for _, dir := range []string{"/test", "/tests", "/__tests__", "/testdata", "/fixtures"} {
+				if root, ok := segment(dir)(p); ok {
+					return root, true
+				}
+			}
+			return "", false
+		},
+	},
+	{
+		ID:    "toolchain",
+		Name:  "Compiler toolchain",
+		Fix:   "Compile in a build stage and copy only the artifacts into the final stage",
+		match: anyUnder("/usr/lib/gcc", "/usr/libexec/gcc", "/usr/local/go", "/usr/lib/go", "/usr/local/rustup", "/usr/local/cargo"),
+	},
+	{
+		ID:    "docs",
+		Name:  "Documentation and man pages",
+		Fix:   "Use `--no-install-recommends` and remove /usr/share/doc and /usr/share/man in the same RUN",
+		match: anyUnder("/usr/share/doc", "/usr/share/man", "/usr/share/info"),
+	},
+	{
+		ID:    "tmp",
+		Name:  "Temporary files",
+		Fix:   "Remove temporary files in the same RUN that creates them",
+		match: anyUnder("/tmp", "/var/tmp"),
+	},
+	{
+		ID:    "logs",
+		Name:  "Log files",
+		Fix:   "Remove install logs in the same RUN, e.g. `rm -rf /var/log/*.log`",
+		match: under("/var/log"),
+	},
+}
+
+// under matches files below dir and groups them by dir
+func under(dir string) func(string) (string, bool) {
+	return func(p string) (string, bool) {
+		return dir, strings.HasPrefix(p, dir+"/")
+	}
+}
+
+// anyUnder matches files below any of dirs
+func anyUnder(dirs ...string) func(string) (string, bool) {
+	return func(p string) (string, bool) {
+		for _, dir := range dirs {
+			if strings.HasPrefix(p, dir+"/") {
+				return dir, true
+			}
+		}
+		return "", false
+	}
+}
+
+// segment matches files below a directory path segment anywhere in the
+// filesystem, e.g. "/.git", and groups them by the outermost such directory
+func segment(seg string) func(string) (string, bool) {
+	return func(p string) (string, bool) {
+		idx := strings.Index(p, seg+"/")
+		if idx < 0 {
+			return "", false
+		}
+		return path.Clean(p[:idx+len(seg)]), true
+	}
+}
@@ -0,0 +1,197 @@
+package layerid
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Layer is a layer of one image version as seen by the matcher
+type Layer struct {
+	Digest  string // layer digest, equal for identical layers
+	Command string // instruction that created the layer, as in the history
+	Stage   string // build stage of the layer, empty when unknown
+	// Hint names the layer within its version, such as the Dockerfile
+	// instruction that created it. It breaks ties after the digest and the
+	// command and names the layer when it matches no other version.
+	Hint string
+}
+
+// identity is a logical layer followed across versions. The fields other
+// than key describe the layer in the last version it was matched in.
+type identity struct {
+	key      string
+	hint     string
+	digest   string
+	command  string
+	keyword  string
+	stage    string
+	position int
+	claimed  int // index of the version that last claimed the identity
+}
+
+// Assign returns the key of every layer, so that the same logical layer has
+// the same key in all versions even when its instruction text changed.
+// versions are ordered newest first and the layers of each version lowest
+// first; the result has the same shape.
+//
+// A layer is matched to a layer of a newer version by, in order: its digest,
+// its normalized command within the same stage, its Hint, and finally its
+// position within the stage when the instruction keyword is the same. Each
+// layer is matched at most once per version.
+func Assign(versions [][]Layer) [][]string {
+	var identities []*identity
+	used := make(map[string]bool)
+	result := make([][]string, len(versions))
+
+	for v, layers := range versions {
+		keys := make([]string, len(layers))
+		matched := make([]*identity, len(layers))
+		commands := make([]string, len(layers))
+		positions := make([]int, len(layers))
+		inStage := make(map[string]int)
+		for i, layer := range layers {
+			commands[i] = Normalize(layer.Command)
+			positions[i] = inStage[layer.Stage]
+			inStage[layer.Stage]++
+		}
+
+		claim := func(i int, match func(i int, id *identity) bool) {
+			for _, id := range identities {
+				if id.claimed != v && match(i, id) {
+					id.claimed = v
+					matched[i] = id
+					return
+				}
+			}
+		}
+		passes := []func(i int, id *identity) bool{
+			func(i int, id *identity) bool {
+				return layers[i].Digest != "" && id.digest == layers[i].Digest
+			},
+			func(i int, id *identity) bool {
+				return id.stage == layers[i].Stage && id.command == commands[i]
+			},
+			func(i int, id *identity) bool {
+				return layers[i].Hint != "" && id.hint == layers[i].Hint
+			},
+			func(i int, id *identity) bool {
+				return id.stage == layers[i].Stage && id.position == positions[i] &&
+					id.keyword == keyword(commands[i])
+			},
+		}
+		for _, pass := range passes {
+			for i := range layers {
+				if matched[i] == nil {
+					claim(i, pass)
+				}
+			}
+		}
+
+		for i, layer := range layers {
+			id := matched[i]
+			if id == nil {
+				id = &identity{claimed: v}
+				if layer.Hint != "" && !used[layer.Hint] {
+					id.key = layer.Hint
+				} else {
+					id.key = unique(commands[i], used)
+				}
+				used[id.key] = true
+				identities = append(identities, id)
+			}
+			id.hint = layer.Hint
+			id.digest = layer.Digest
+			id.command = commands[i]
+			id.keyword = keyword(commands[i])
+			id.stage = layer.Stage
+			id.position = positions[i]
+			keys[i] = id.key
+		}
+		result[v] = keys
+	}
+	return result
+}
+
+// unique returns key, or key with the lowest "#n" suffix that is not used yet
+func unique(key string, used map[string]bool) string {
+	if !used[key] {
+		return key
+	}
+	for n := 2; ; n++ {
+		candidate := key + "#" + strconv.Itoa(n)
+		if !used[candidate] {
+			return candidate
+		}
+	}
+}
+
+// keyword returns the instruction keyword of a normalized command
+func keyword(command string) string {
+	word, _, _ := strings.Cut(command, " ")
+	return word
+}
+
+var (
+	// |2 VERSION=1.2 ARCH=amd64, the build args BuildKit and the classic
+	// builder record in front of RUN commands
+	buildArgsPrefix = regexp.MustCompile(`^\|(\d+) `)
+	// 2024-03-01, 2024-03-01T10:20:30Z, 20240301 and Unix timestamps
+	timestampPattern = regexp.MustCompile(`\b\d{4}-\d{2}-\d{2}(?:[T ]\d{2}:\d{2}(?::\d{2})?(?:\.\d+)?(?:Z|[+-]\d{2}:?\d{2})?)?\b|\b(?:19|20)\d{2}(?:0[1-9]|1[0-2])(?:0[1-9]|[12]\d|3[01])(?:\d{6})?\b|\b1\d{9}\b`)
+	// sha256:..., file:... and dir:... of the classic builder, git commits
+	hashPattern = regexp.MustCompile(`\b(?:sha256|sha512|file|dir|multi):[0-9a-f]{7,}\b|\b[0-9a-f]{7,}\b`)
+	// 1.2, v1.2.3, 1.2.3-r0, 2:1.2.3+dfsg-1, 1.1.1w
+	versionPattern = regexp.MustCompile(`(?:\b\d+:)?\bv?\d+(?:\.\d+[a-z]?)+(?:[-+~][0-9A-Za-z.+~]*)*`)
+	whitespace     = regexp.MustCompile(`\s+`)
+)
+
+// Normalize reduces an image history command to the parts that identify the
+// layer: the shell and build argument prefixes, version numbers, hashes and
+// timestamps are dropped, so that "RUN |1 V=1.2 /bin/sh -c apk add curl=8.5.0-r0"
+// and "/bin/sh -c apk add curl=8.9.1-r0" have the same form.
+func Normalize(command string) string {
+	cmd := strings.TrimSpace(command)
+	cmd = strings.TrimSuffix(cmd, "# buildkit")
+	cmd = strings.TrimSpace(cmd)
+
+	run := false
+	if rest, ok := strings.CutPrefix(cmd, "RUN "); ok {
+		run, cmd = true, strings.TrimSpace(rest)
+	}
+	if m := buildArgsPrefix.FindStringSubmatch(cmd); m != nil {
+		run = true
+		n, _ := strconv.Atoi(m[1])
+		fields := strings.Fields(cmd[len(m[0]):])
+		for n > 0 && len(fields) > 0 && strings.Contains(fields[0], "=") {
+			fields, n = fields[1:], n-1
+		}
+		cmd = strings.Join(fields, " ")
+	}
+	if rest, ok := strings.CutPrefix(cmd, "/bin/sh -c "); ok {
+		cmd = strings.TrimSpace(rest)
+		if rest, ok := strings.CutPrefix(cmd, "#(nop) "); ok {
+			// Metadata and file instructions of the classic builder
+			cmd = strings.TrimSpace(rest)
+		} else {
+			run = true
+		}
+	}
+	if run {
+		cmd = "RUN " + cmd
+	}
+
+	cmd = timestampPattern.ReplaceAllString(cmd, "<time>")
+	cmd = hashPattern.ReplaceAllStringFunc(cmd, func(s string) string {
+		// A run of hex letters alone is a word such as "deadbeef", a run of
+		// digits alone a number; hashes mix both
+		if prefix, _, ok := strings.Cut(s, ":"); ok {
+			return prefix + ":<hash>"
+		}
+		if strings.Trim(s, "0123456789") == "" || strings.Trim(s, "abcdef") == "" {
+			return s
+		}
+		return "<hash>"
+	})
+	cmd = versionPattern.ReplaceAllString(cmd, "<version>")
+	return whitespace.ReplaceAllString(strings.TrimSpace(cmd), " ")
+}
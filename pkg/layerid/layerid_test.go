@@ -0,0 +1,167 @@
+package layerid
+
+import "testing"
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		name    string
+		command string
+		want    string
+	}{
+		{"buildkit run", "RUN /bin/sh -c apk add curl=8.5.0-r0 # buildkit", "RUN apk add curl=<version>"},
+		{"classic run", "/bin/sh -c apk add curl=8.9.1-r0", "RUN apk add curl=<version>"},
+		{"build args", "RUN |2 VERSION=1.2 ARCH=amd64 /bin/sh -c make install", "RUN make install"},
+		{"build args without shell", "|1 VERSION=1.2 make", "RUN make"},
+		{"nop metadata", "/bin/sh -c #(nop)  WORKDIR /app", "WORKDIR /app"},
+		{"classic copy hash", "/bin/sh -c #(nop) COPY file:3a7bd3e2360a3d5b1c2e in /app", "COPY file:<hash> in /app"},
+		{"digest", "ADD sha256:0123456789abcdef0123 /", "ADD sha256:<hash> /"},
+		{"git commit", "RUN git checkout 3f2a9c1d", "RUN git checkout <hash>"},
+		{"hex word kept", "RUN echo deadbeef", "RUN echo deadbeef"},
+		{"number kept", "RUN sleep 1234567", "RUN sleep 1234567"},
+		{"iso timestamp", "RUN echo 2024-03-01T10:20:30Z > /built", "RUN echo <time> > /built"},
+		{"compact date", "RUN echo 20240301 > /built", "RUN echo <time> > /built"},
+		{"unix timestamp", "RUN touch -d @1709288430 /built", "RUN touch -d @<time> /built"},
+		{"epoch version", "RUN apt-get install -y libssl=1:1.1.1w-0+deb11u1", "RUN apt-get install -y libssl=<version>"},
+		{"v prefix", "RUN go install example.com/tool@v1.2.3", "RUN go install example.com/tool@<version>"},
+		{"whitespace", "RUN   apk   add\tcurl", "RUN apk add curl"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Normalize(tt.command); got != tt.want {
+				t.Errorf("Normalize(%q) = %q, want %q", tt.command, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAssign(t *testing.T) {
+	tests := []struct {
+		name     string
+		versions [][]Layer
+		// same lists pairs of {version, layer} that must share a key; apart
+		// lists pairs that must not
+		same  [][2][2]int
+		apart [][2][2]int
+	}{
+		{
+			name: "pinned version bumped",
+			versions: [][]Layer{
+				{
+					{Digest: "sha256:base", Command: "ADD file:aaaa1111 in /"},
+					{Digest: "sha256:new", Command: "RUN apk add foo=1.3", Stage: "0", Hint: "0/RUN#0"},
+				},
+				{
+					{Digest: "sha256:base", Command: "ADD file:aaaa1111 in /"},
+					{Digest: "sha256:old", Command: "RUN apk add foo=1.2", Stage: "0", Hint: "0/RUN#0"},
+				},
+			},
+			same: [][2][2]int{{{0, 0}, {1, 0}}, {{0, 1}, {1, 1}}},
+		},
+		{
+			name: "layer inserted before",
+			versions: [][]Layer{
+				{
+					{Digest: "sha256:a", Command: "RUN apk add curl", Stage: "0", Hint: "0/RUN#0"},
+					{Digest: "sha256:b", Command: "RUN apk add foo=1.3", Stage: "0", Hint: "0/RUN#1"},
+					{Digest: "sha256:c", Command: "COPY . /app", Stage: "0", Hint: "0/COPY#0"},
+				},
+				{
+					{Digest: "sha256:d", Command: "RUN apk add foo=1.2", Stage: "0", Hint: "0/RUN#0"},
+					{Digest: "sha256:e", Command: "COPY . /app", Stage: "0", Hint: "0/COPY#0"},
+				},
+			},
+			same:  [][2][2]int{{{0, 1}, {1, 0}}, {{0, 2}, {1, 1}}},
+			apart: [][2][2]int{{{0, 0}, {1, 0}}},
+		},
+		{
+			name: "digest before command",
+			versions: [][]Layer{
+				{
+					{Digest: "sha256:x", Command: "RUN make build"},
+					{Digest: "sha256:y", Command: "RUN make test"},
+				},
+				{
+					{Digest: "sha256:y", Command: "RUN make build"},
+					{Digest: "sha256:x", Command: "RUN make test"},
+				},
+			},
+			same: [][2][2]int{{{0, 0}, {1, 1}}, {{0, 1}, {1, 0}}},
+		},
+		{
+			name: "hint before position",
+			versions: [][]Layer{
+				{
+					{Command: "RUN npm ci", Stage: "0", Hint: "0/RUN#1"},
+					{Command: "RUN make all", Stage: "0", Hint: "0/RUN#0"},
+				},
+				{
+					{Command: "RUN yarn install", Stage: "0", Hint: "0/RUN#1"},
+					{Command: "RUN make build", Stage: "0", Hint: "0/RUN#0"},
+				},
+			},
+			same: [][2][2]int{{{0, 0}, {1, 0}}, {{0, 1}, {1, 1}}},
+		},
+		{
+			name: "position without hints",
+			versions: [][]Layer{
+				{{Command: "RUN npm ci"}, {Command: "COPY . /app"}},
+				{{Command: "RUN yarn install"}, {Command: "COPY . /app"}},
+			},
+			same: [][2][2]int{{{0, 0}, {1, 0}}, {{0, 1}, {1, 1}}},
+		},
+		{
+			name: "command only within its stage",
+			versions: [][]Layer{
+				{{Command: "COPY . /src", Stage: "build"}},
+				{{Command: "COPY . /src", Stage: "final"}},
+			},
+			apart: [][2][2]int{{{0, 0}, {1, 0}}},
+		},
+		{
+			name: "claimed once per version",
+			versions: [][]Layer{
+				{{Command: "RUN apt-get update"}},
+				{{Command: "RUN apt-get update"}, {Command: "RUN apt-get update"}},
+			},
+			same:  [][2][2]int{{{0, 0}, {1, 0}}},
+			apart: [][2][2]int{{{1, 0}, {1, 1}}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			keys := Assign(tt.versions)
+			for v, layers := range tt.versions {
+				if len(keys[v]) != len(layers) {
+					t.Fatalf("version %d: got %d keys for %d layers", v, len(keys[v]), len(layers))
+				}
+			}
+			key := func(p [2]int) string { return keys[p[0]][p[1]] }
+			for _, pair := range tt.same {
+				if key(pair[0]) != key(pair[1]) {
+					t.Errorf("layers %v and %v: keys %q and %q, want equal", pair[0], pair[1], key(pair[0]), key(pair[1]))
+				}
+			}
+			for _, pair := range tt.apart {
+				if key(pair[0]) == key(pair[1]) {
+					t.Errorf("layers %v and %v: both have key %q", pair[0], pair[1], key(pair[0]))
+				}
+			}
+		})
+	}
+}
+
+func TestAssignKeys(t *testing.T) {
+	keys := Assign([][]Layer{
+		{
+			{Command: "RUN apk add foo=1.3", Stage: "0", Hint: "0/RUN#0"},
+			{Command: "RUN apt-get update"},
+			{Command: "RUN apt-get update"},
+		},
+	})
+	want := []string{"0/RUN#0", "RUN apt-get update", "RUN apt-get update#2"}
+	for i, key := range keys[0] {
+		if key != want[i] {
+			t.Errorf("layer %d: key %q, want %q", i, key, want[i])
+		}
+	}
+}
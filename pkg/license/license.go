@@ -0,0 +1,171 @@
+package license
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/jtodic/docker-time-machine/pkg/deps"
+	"github.com/jtodic/docker-time-machine/pkg/packages"
+)
+
+// Unknown groups the components whose license could not be determined
+const Unknown = "UNKNOWN"
+
+// Usage lists the components of an image under one license
+type Usage struct {
+	License    string   `json:"license"`
+	Components []string `json:"components"` // e.g. "curl (deb)"
+}
+
+// Inventory groups the OS packages and language dependencies of an image by
+// license. Components under a license expression such as "MIT OR
+// Apache-2.0" are listed under each license it names.
+func Inventory(pkgs []packages.Package, dependencies []deps.Dependency) []Usage {
+	components := make(map[string]map[string]bool)
+	add := func(expression, component string) {
+		for _, l := range Split(expression) {
+			if components[l] == nil {
+				components[l] = make(map[string]bool)
+			}
+			components[l][component] = true
+		}
+	}
+	for _, p := range pkgs {
+		add(p.License, fmt.Sprintf("%s (%s)", p.Name, p.Manager))
+	}
+	for _, d := range dependencies {
+		add(d.License, fmt.Sprintf("%s (%s)", d.Name, d.Ecosystem))
+	}
+
+	usages := make([]Usage, 0, len(components))
+	for l, set := range components {
+		u := Usage{License: l}
+		for c := range set {
+			u.Components = append(u.Components, c)
+		}
+		sort.Strings(u.Components)
+		usages = append(usages, u)
+	}
+	sort.Slice(usages, func(i, j int) bool {
+		return usages[i].License < usages[j].License
+	})
+	return usages
+}
+
+var (
+	orLater              = regexp.MustCompile(`(?i)\s+or\s+later\b`)
+	expressionSeparators = regexp.MustCompile(`(?i)\s+(?:and|or)\s+|\s*[,;/|&]\s*`)
+	remark               = regexp.MustCompile(`\s*\([^()]*\)$`)
+)
+
+// Split returns the normalized licenses named by a license expression, or
+// Unknown when it names none
+func Split(expression string) []string {
+	if _, ok := aliases[strings.ToLower(strings.TrimSpace(expression))]; ok {
+		return orUnknown([]string{Normalize(expression)})
+	}
+
+	var licenses []string
+	seen := make(map[string]bool)
+	expression = orLater.ReplaceAllString(expression, " or-later")
+	for _, part := range expressionSeparators.Split(expression, -1) {
+		// Drop remarks such as "(AGPLv3+)" and the parentheses of grouping
+		if stripped := remark.ReplaceAllString(part, ""); strings.Trim(stripped, "() ") != "" {
+			part = stripped
+		}
+		l := Normalize(strings.Trim(part, "() "))
+		if l == "" || seen[l] {
+			continue
+		}
+		seen[l] = true
+		licenses = append(licenses, l)
+	}
+	return orUnknown(licenses)
+}
+
+func orUnknown(licenses []string) []string {
+	if len(licenses) == 0 || licenses[0] == "" {
+		return []string{Unknown}
+	}
+	return licenses
+}
+
+// aliases maps common license names, as found in package metadata, to SPDX
+// identifiers. Keys are lower case.
+var aliases = map[string]string{
+	"mit":                                  "MIT",
+	"mit license":                          "MIT",
+	"expat":                                "MIT",
+	"apache 2":                             "Apache-2.0",
+	"apache 2.0":                           "Apache-2.0",
+	"apache-2":                             "Apache-2.0",
+	"apache license 2.0":                   "Apache-2.0",
+	"apache license, version 2.0":          "Apache-2.0",
+	"apache license version 2.0":           "Apache-2.0",
+	"asl 2.0":                              "Apache-2.0",
+	"apache software license":              "Apache",
+	"bsd license":                          "BSD",
+	"bsd-3":                                "BSD-3-Clause",
+	"bsd 3-clause":                         "BSD-3-Clause",
+	"new bsd":                              "BSD-3-Clause",
+	"bsd-2":                                "BSD-2-Clause",
+	"bsd 2-clause":                         "BSD-2-Clause",
+	"isc license":                          "ISC",
+	"mpl 2.0":                              "MPL-2.0",
+	"mozilla public license 2.0":           "MPL-2.0",
+	"python software foundation license":   "PSF-2.0",
+	"psf":                                  "PSF-2.0",
+	"zlib":                                 "Zlib",
+	"public domain":                        "public-domain",
+	"gnu affero general public license v3": "AGPL-3.0-only",
+	"gnu affero general public license v3 or-later": "AGPL-3.0-or-later",
+	"gnu general public license v2":                 "GPL-2.0-only",
+	"gnu general public license v2 or-later":        "GPL-2.0-or-later",
+	"gnu general public license v3":                 "GPL-3.0-only",
+	"gnu general public license v3 or-later":        "GPL-3.0-or-later",
+	"gnu lesser general public license v2 or-later": "LGPL-2.0-or-later",
+	"gnu lesser general public license v3":          "LGPL-3.0-only",
+	"unknown":                                       "",
+	"none":                                          "",
+	"noassertion":                                   "",
+}
+
+// gplFamily matches the many spellings of the GNU licenses, e.g. GPLv2+,
+// GPL-2, LGPL-2.1 or AGPL-3.0-only
+var gplFamily = regexp.MustCompile(`^(?i)(a|l)?gpl\s*-?\s*v?(\d)(?:\.(\d))?(\+|-or-later|-only)?$`)
+
+// Normalize maps a single license name to its SPDX identifier where it is
+// recognized, and returns it trimmed otherwise
+func Normalize(name string) string {
+	name = strings.TrimSpace(name)
+	if spdx, ok := aliases[strings.ToLower(name)]; ok {
+		return spdx
+	}
+	if m := gplFamily.FindStringSubmatch(name); m != nil {
+		minor := m[3]
+		if minor == "" {
+			minor = "0"
+		}
+		suffix := "-only"
+		if m[4] == "+" || m[4] == "-or-later" {
+			suffix = "-or-later"
+		}
+		return strings.ToUpper(m[1]) + "GPL-" + m[2] + "." + minor + suffix
+	}
+	return name
+}
+
+// Denied reports whether a license matches one of the denied licenses. An
+// entry matches case-insensitively as a prefix, so AGPL denies
+// AGPL-3.0-only and AGPL-3.0-or-later but GPL does not deny LGPL-2.1-only.
+func Denied(license string, deny []string) bool {
+	for _, d := range deny {
+		d = strings.TrimSpace(d)
+		if d != "" && strings.HasPrefix(strings.ToLower(license), strings.ToLower(d)) {
+			return true
+		}
+	}
+	return false
+}
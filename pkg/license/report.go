@@ -0,0 +1,179 @@
+package license
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/jtodic/docker-time-machine/pkg/report"
+)
+
+// Version is the license inventory of an analyzed commit or tag
+type Version struct {
+	Name     string
+	Licenses []Usage
+	Scanned  bool // false when the image could not be inspected
+}
+
+// VersionDiff lists the licenses added and removed from one analyzed version
+// to the next
+type VersionDiff struct {
+	From    string  `json:"from"`
+	To      string  `json:"to"`
+	Added   []Usage `json:"added"`
+	Removed []Usage `json:"removed"`
+}
+
+// Violation is a denied license and the version where it first appeared
+type Violation struct {
+	License    string   `json:"license"`
+	Version    string   `json:"version"`
+	Components []string `json:"components"`
+}
+
+func (v Violation) String() string {
+	return fmt.Sprintf("denied license %s first appeared in %s (%s)", v.License, v.Version, componentList(v.Components))
+}
+
+// Compare returns the licenses added and removed from older to newer
+func Compare(from, to string, older, newer []Usage) VersionDiff {
+	diff := VersionDiff{From: from, To: to, Added: []Usage{}, Removed: []Usage{}}
+
+	before := make(map[string]bool, len(older))
+	for _, u := range older {
+		before[u.License] = true
+	}
+	after := make(map[string]bool, len(newer))
+	for _, u := range newer {
+		after[u.License] = true
+		if !before[u.License] {
+			diff.Added = append(diff.Added, u)
+		}
+	}
+	for _, u := range older {
+		if !after[u.License] {
+			diff.Removed = append(diff.Removed, u)
+		}
+	}
+	return diff
+}
+
+// CompareVersions compares each scanned version with the next older scanned
+// one. Versions are ordered newest first.
+func CompareVersions(versions []Version) []VersionDiff {
+	return report.ConsecutivePairs(versions, func(v Version) bool { return v.Scanned },
+		func(older, newer Version) VersionDiff {
+			return Compare(older.Name, newer.Name, older.Licenses, newer.Licenses)
+		})
+}
+
+// Violations walks the versions from oldest to newest and returns each denied
+// license at the version where it appears: the oldest scanned version that
+// has it, or a later version that adds it again after it was removed.
+// Versions are ordered newest first.
+func Violations(versions []Version, deny []string) []Violation {
+	if len(deny) == 0 {
+		return nil
+	}
+
+	var violations []Violation
+	present := make(map[string]bool)
+	for i := len(versions) - 1; i >= 0; i-- {
+		v := versions[i]
+		if !v.Scanned {
+			continue
+		}
+		current := make(map[string]bool)
+		for _, u := range v.Licenses {
+			if !Denied(u.License, deny) {
+				continue
+			}
+			current[u.License] = true
+			if !present[u.License] {
+				violations = append(violations, Violation{License: u.License, Version: v.Name, Components: u.Components})
+			}
+		}
+		present = current
+	}
+	return violations
+}
+
+// Sections returns the license inventory of the newest scanned version, the
+// licenses added and removed between consecutive versions and the denied
+// licenses
+func Sections(versions []Version, diffs []VersionDiff, deny []string) []report.Section {
+	sections := []report.Section{InventorySection(versions), DiffSection(diffs)}
+	if len(deny) > 0 {
+		sections = append(sections, ViolationSection(Violations(versions, deny), deny))
+	}
+	return sections
+}
+
+// InventorySection lists the licenses of the newest scanned version, most
+// used first
+func InventorySection(versions []Version) report.Section {
+	section := report.Section{
+		Headers: []string{"License", "Components", "Examples"},
+	}
+	for _, v := range versions {
+		if !v.Scanned {
+			continue
+		}
+		section.Title = fmt.Sprintf("⚖️ Licenses (%s)", v.Name)
+
+		usages := append([]Usage(nil), v.Licenses...)
+		sort.SliceStable(usages, func(i, j int) bool {
+			return len(usages[i].Components) > len(usages[j].Components)
+		})
+		for _, u := range usages {
+			section.Rows = append(section.Rows, []string{
+				u.License, strconv.Itoa(len(u.Components)), componentList(u.Components),
+			})
+		}
+		break
+	}
+	return section
+}
+
+// DiffSection lists the licenses added and removed between consecutive
+// versions with the components that brought or dropped them
+func DiffSection(diffs []VersionDiff) report.Section {
+	section := report.Section{
+		Title:   "⚖️ License Changes",
+		Headers: []string{"Version", "Change", "License", "Components"},
+	}
+	for _, d := range diffs {
+		version := fmt.Sprintf("%s → %s", d.From, d.To)
+		for _, u := range d.Added {
+			section.Rows = append(section.Rows, []string{version, "added", u.License, componentList(u.Components)})
+		}
+		for _, u := range d.Removed {
+			section.Rows = append(section.Rows, []string{version, "removed", u.License, componentList(u.Components)})
+		}
+	}
+	return section
+}
+
+// ViolationSection lists the denied licenses found and where they appeared
+func ViolationSection(violations []Violation, deny []string) report.Section {
+	section := report.Section{
+		Title:   "🚫 Denied Licenses",
+		Note:    "Denied: " + strings.Join(deny, ", "),
+		Headers: []string{"License", "First Appeared", "Components"},
+	}
+	for _, v := range violations {
+		section.Rows = append(section.Rows, []string{v.License, v.Version, componentList(v.Components)})
+	}
+	return section
+}
+
+// maxComponents limits the components named per row
+const maxComponents = 3
+
+func componentList(components []string) string {
+	if len(components) <= maxComponents {
+		return strings.Join(components, ", ")
+	}
+	return fmt.Sprintf("%s, +%d more", strings.Join(components[:maxComponents], ", "), len(components)-maxComponents)
+}
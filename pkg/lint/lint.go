@@ -0,0 +1,199 @@
+package lint
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/jtodic/docker-time-machine/pkg/dockerfile"
+	"github.com/jtodic/docker-time-machine/pkg/report"
+)
+
+// Issue is a Dockerfile pattern known to make images larger or rebuilds slower
+type Issue struct {
+	Rule      string `json:"rule"`
+	Stage     string `json:"stage"`
+	StartLine int    `json:"start_line"`
+	EndLine   int    `json:"end_line"`
+	Keyword   string `json:"keyword"`
+	Message   string `json:"message"`
+	Fix       string `json:"fix"`
+	// Cost is the size in bytes of the image layers affected by the issue,
+	// -1 when the instruction does not produce a layer of the built image
+	Cost int64 `json:"cost"`
+}
+
+// Sizes holds the measured layer sizes of the built image
+type Sizes struct {
+	// ByInstruction maps dockerfile.Instruction.Key to the layer size in bytes
+	ByInstruction map[string]int64
+	// Base is the size of the layers inherited from the base image
+	Base int64
+}
+
+var (
+	aptInstall     = regexp.MustCompile(`\bapt(-get)?\s+(-\S+\s+)*install\b`)
+	apkAdd         = regexp.MustCompile(`\bapk\s+(-\S+\s+)*add\b`)
+	yumInstall     = regexp.MustCompile(`\b(yum|dnf|microdnf)\s+(-\S+\s+)*install\b`)
+	pipInstall     = regexp.MustCompile(`\bpip3?\s+(-\S+\s+)*install\b`)
+	depsInstall    = regexp.MustCompile(`\b(npm\s+(install|ci|i)|yarn(\s+install)?\s*($|&&|;)|pnpm\s+install|pip3?\s+install\s+-r|poetry\s+install|go\s+mod\s+download|bundle\s+install|composer\s+install|mvn\s+(\S+\s+)*dependency:)`)
+	buildCommand   = regexp.MustCompile(`\b(gcc|g\+\+|make|cmake|go\s+build|cargo\s+build|mvn\s+(\S+\s+)*(package|install)|gradle\w*\s+(\S+\s+)*build|npm\s+run\s+build|yarn\s+build|build-essential)\b`)
+	copyEverything = regexp.MustCompile(`^(COPY|ADD)\s+(--\S+\s+)*\.\/?\s+\S+`)
+)
+
+// Lint checks a Dockerfile and ranks the issues by the bytes they cost in the
+// built image. sizes may be nil to lint without measurements.
+func Lint(df *dockerfile.Dockerfile, sizes *Sizes) []Issue {
+	issues := []Issue{}
+	cost := func(inst dockerfile.Instruction) int64 {
+		if sizes == nil {
+			return -1
+		}
+		if size, ok := sizes.ByInstruction[inst.Key()]; ok {
+			return size
+		}
+		return -1
+	}
+	add := func(rule string, inst dockerfile.Instruction, c int64, message, fix string) {
+		stage := inst.StageName
+		if stage == "" {
+			stage = fmt.Sprintf("%d", inst.Stage)
+		}
+		issues = append(issues, Issue{
+			Rule: rule, Stage: stage, StartLine: inst.StartLine, EndLine: inst.EndLine,
+			Keyword: inst.Keyword, Message: message, Fix: fix, Cost: c,
+		})
+	}
+
+	for si, stage := range df.Stages {
+		copiedSources := false
+		for _, inst := range stage.Instructions {
+			text := inst.Original
+
+			switch inst.Keyword {
+			case "COPY", "ADD":
+				if copyEverything.MatchString(text) {
+					copiedSources = true
+				}
+				continue
+			case "RUN":
+			default:
+				continue
+			}
+
+			if aptInstall.MatchString(text) && !strings.Contains(text, "--no-install-recommends") {
+				add("apt-recommends", inst, cost(inst),
+					"apt-get install without --no-install-recommends pulls in optional packages",
+					"Add --no-install-recommends to apt-get install")
+			}
+			if aptInstall.MatchString(text) && !strings.Contains(text, "/var/lib/apt/lists") {
+				add("apt-cleanup", inst, cost(inst),
+					"apt package lists are not removed in the same RUN",
+					"End the RUN with && rm -rf /var/lib/apt/lists/*")
+			}
+			if apkAdd.MatchString(text) && !strings.Contains(text, "--no-cache") {
+				add("apk-cache", inst, cost(inst),
+					"apk add without --no-cache keeps the package index",
+					"Use apk add --no-cache")
+			}
+			if yumInstall.MatchString(text) && !strings.Contains(text, "clean all") {
+				add("yum-cleanup", inst, cost(inst),
+					"yum/dnf cache is not cleaned in the same RUN",
+					"End the RUN with && yum clean all (or dnf clean all)")
+			}
+			if pipInstall.MatchString(text) && !strings.Contains(text, "--no-cache-dir") {
+				add("pip-cache", inst, cost(inst),
+					"pip install without --no-cache-dir keeps downloaded wheels",
+					"Use pip install --no-cache-dir")
+			}
+			if copiedSources && depsInstall.MatchString(text) {
+				add("copy-before-install", inst, cost(inst),
+					"dependencies are installed after copying the whole build context, so any source change rebuilds this layer",
+					"Copy only the dependency manifests (e.g. package.json, requirements.txt, go.mod) before installing, then copy the sources")
+			}
+			if len(df.Stages) == 1 && si == 0 && buildCommand.MatchString(text) {
+				add("single-stage-build", inst, cost(inst),
+					"build tools and intermediate files end up in the final image",
+					"Use a multi-stage build: compile in a builder stage and COPY --from it only the artifacts")
+			}
+		}
+
+		if unpinnedBase(df, si) {
+			from := dockerfile.Instruction{Stage: si, StageName: stage.Name, Keyword: "FROM", StartLine: stage.FromLine, EndLine: stage.FromLine}
+			baseCost := int64(-1)
+			if sizes != nil && si == len(df.Stages)-1 {
+				baseCost = sizes.Base
+			}
+			add("unpinned-base", from, baseCost,
+				fmt.Sprintf("base image %s is not pinned, so it can change size between builds", stage.BaseImage),
+				"Pin the base image to a version tag and digest, e.g. image:1.2.3@sha256:...")
+		}
+	}
+
+	sort.SliceStable(issues, func(i, j int) bool {
+		if issues[i].Cost != issues[j].Cost {
+			return issues[i].Cost > issues[j].Cost
+		}
+		return issues[i].StartLine < issues[j].StartLine
+	})
+	return issues
+}
+
+// unpinnedBase reports whether a stage is built from an external image
+// without a version tag or digest
+func unpinnedBase(df *dockerfile.Dockerfile, index int) bool {
+	base := df.Stages[index].BaseImage
+	if base == "" || base == "scratch" || strings.Contains(base, "$") || strings.Contains(base, "@") {
+		return false
+	}
+	for _, s := range df.Stages[:index] {
+		if strings.EqualFold(s.Name, base) {
+			return false
+		}
+	}
+
+	// The tag follows the last colon after the last slash (a registry host
+	// may carry a port)
+	name := base[strings.LastIndex(base, "/")+1:]
+	idx := strings.LastIndex(name, ":")
+	return idx < 0 || name[idx+1:] == "latest"
+}
+
+// Section lists the issues found in the Dockerfile of a version
+func Section(version string, issues []Issue) report.Section {
+	section := report.Section{
+		Title:   fmt.Sprintf("🔎 Dockerfile Lint (%s)", version),
+		Headers: []string{"Cost", "Line", "Rule", "Issue", "Fix"},
+	}
+	for i, issue := range issues {
+		if i == report.MaxRows {
+			section.Note = fmt.Sprintf("Showing %d of %d issues.", report.MaxRows, len(issues))
+			break
+		}
+		section.Rows = append(section.Rows, []string{
+			FormatCost(issue.Cost),
+			Lines(issue),
+			issue.Rule,
+			issue.Message,
+			issue.Fix,
+		})
+	}
+	return section
+}
+
+// FormatCost formats the cost of an issue
+func FormatCost(cost int64) string {
+	if cost < 0 {
+		return "-"
+	}
+	return report.FormatBytes(cost)
+}
+
+// Lines returns the line range of an issue, e.g. "L3" or "L3-7"
+func Lines(issue Issue) string {
+	if issue.EndLine > issue.StartLine {
+		return fmt.Sprintf("L%d-%d", issue.StartLine, issue.EndLine)
+	}
+	return fmt.Sprintf("L%d", issue.StartLine)
+}
@@ -0,0 +1,144 @@
+package packages
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/jtodic/docker-time-machine/pkg/report"
+)
+
+// ChangeType describes how a package changed between two versions
+type ChangeType string
+
+const (
+	Added   ChangeType = "added"
+	Removed ChangeType = "removed"
+	Changed ChangeType = "changed" // version changed
+)
+
+// Change is a package added, removed or changed between two versions
+type Change struct {
+	Type          ChangeType `json:"type"`
+	Name          string     `json:"name"`
+	Manager       string     `json:"manager"`
+	Arch          string     `json:"arch,omitempty"`
+	BeforeVersion string     `json:"before_version,omitempty"`
+	AfterVersion  string     `json:"after_version,omitempty"`
+	BeforeSize    int64      `json:"before_size"`
+	AfterSize     int64      `json:"after_size"`
+	SizeDiff      int64      `json:"size_diff"`
+}
+
+// VersionDiff lists the package changes from one analyzed version to the next
+type VersionDiff struct {
+	From    string   `json:"from"`
+	To      string   `json:"to"`
+	Changes []Change `json:"changes"`
+}
+
+// Version is the package inventory of an analyzed commit or tag
+type Version struct {
+	Name     string
+	Packages []Package
+	Scanned  bool // false when the image could not be inspected
+}
+
+// Compare returns the packages added, removed and version-changed from older
+// to newer, largest size change first
+func Compare(from, to string, older, newer []Package) VersionDiff {
+	diff := VersionDiff{From: from, To: to}
+
+	before := make(map[string]Package)
+	for _, p := range older {
+		before[p.key()] = p
+	}
+
+	seen := make(map[string]bool)
+	for _, p := range newer {
+		seen[p.key()] = true
+		old, ok := before[p.key()]
+		switch {
+		case !ok:
+			diff.Changes = append(diff.Changes, Change{
+				Type: Added, Name: p.Name, Manager: p.Manager, Arch: p.Arch,
+				AfterVersion: p.Version, AfterSize: p.Size, SizeDiff: p.Size,
+			})
+		case old.Version != p.Version:
+			diff.Changes = append(diff.Changes, Change{
+				Type: Changed, Name: p.Name, Manager: p.Manager, Arch: p.Arch,
+				BeforeVersion: old.Version, AfterVersion: p.Version,
+				BeforeSize: old.Size, AfterSize: p.Size, SizeDiff: p.Size - old.Size,
+			})
+		}
+	}
+
+	for _, p := range older {
+		if seen[p.key()] {
+			continue
+		}
+		diff.Changes = append(diff.Changes, Change{
+			Type: Removed, Name: p.Name, Manager: p.Manager, Arch: p.Arch,
+			BeforeVersion: p.Version, BeforeSize: p.Size, SizeDiff: -p.Size,
+		})
+	}
+
+	sort.SliceStable(diff.Changes, func(i, j int) bool {
+		a, b := diff.Changes[i].SizeDiff, diff.Changes[j].SizeDiff
+		if a < 0 {
+			a = -a
+		}
+		if b < 0 {
+			b = -b
+		}
+		if a != b {
+			return a > b
+		}
+		return diff.Changes[i].Name < diff.Changes[j].Name
+	})
+
+	return diff
+}
+
+// CompareVersions compares each scanned version with the next older scanned
+// one. Versions are ordered newest first.
+func CompareVersions(versions []Version) []VersionDiff {
+	return report.ConsecutivePairs(versions, func(v Version) bool { return v.Scanned },
+		func(older, newer Version) VersionDiff {
+			return Compare(older.Name, newer.Name, older.Packages, newer.Packages)
+		})
+}
+
+// DiffSection lists the package changes between consecutive versions
+func DiffSection(diffs []VersionDiff) report.Section {
+	section := report.Section{
+		Title:   "📦 OS Package Changes",
+		Headers: []string{"Version", "Change", "Package", "Before", "After", "Size", "Size Diff"},
+	}
+
+	for _, d := range diffs {
+		version := fmt.Sprintf("%s → %s", d.From, d.To)
+		for i, c := range d.Changes {
+			size := c.AfterSize
+			if c.Type == Removed {
+				size = c.BeforeSize
+			}
+			if i == report.MaxRows {
+				section.Rows = append(section.Rows, []string{
+					version, fmt.Sprintf("... %d more", len(d.Changes)-report.MaxRows), "", "", "", "", "",
+				})
+				break
+			}
+			section.Rows = append(section.Rows, []string{
+				version,
+				string(c.Type),
+				fmt.Sprintf("%s (%s)", c.Name, c.Manager),
+				report.OrDash(c.BeforeVersion),
+				report.OrDash(c.AfterVersion),
+				report.FormatBytes(size),
+				report.FormatBytesDiff(c.SizeDiff),
+			})
+		}
+	}
+
+	return section
+}
@@ -0,0 +1,51 @@
+package packages
+
+import (
+	"bufio"
+	"bytes"
+	"strings"
+)
+
+// Distro identifies the Linux distribution of an image
+type Distro struct {
+	ID        string `json:"id"`                   // e.g. debian, alpine, rhel
+	VersionID string `json:"version_id,omitempty"` // e.g. 12, 3.19.1
+	Name      string `json:"name,omitempty"`       // PRETTY_NAME
+}
+
+// osRelease lists the os-release locations; /etc/os-release is usually a
+// symlink to the second one
+var osRelease = []string{"/etc/os-release", "/usr/lib/os-release"}
+
+// ParseDistro reads the os-release file from files, which maps absolute paths
+// to file contents. It returns nil when the image has none.
+func ParseDistro(files map[string][]byte) *Distro {
+	for _, p := range osRelease {
+		data, ok := files[p]
+		if !ok {
+			continue
+		}
+
+		distro := &Distro{}
+		scanner := bufio.NewScanner(bytes.NewReader(data))
+		for scanner.Scan() {
+			key, value, ok := strings.Cut(scanner.Text(), "=")
+			if !ok {
+				continue
+			}
+			value = strings.Trim(strings.TrimSpace(value), `"'`)
+			switch strings.TrimSpace(key) {
+			case "ID":
+				distro.ID = value
+			case "VERSION_ID":
+				distro.VersionID = value
+			case "PRETTY_NAME":
+				distro.Name = value
+			}
+		}
+		if distro.ID != "" {
+			return distro
+		}
+	}
+	return nil
+}
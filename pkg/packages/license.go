@@ -0,0 +1,56 @@
+package packages
+
+import (
+	"path"
+	"strings"
+)
+
+// copyrightDir holds the Debian copyright files, one directory per package
+const copyrightDir = "/usr/share/doc"
+
+// WantedLicense reports whether a file holds the license information of a
+// Debian package and must be captured while walking the image layers. apk
+// and rpm databases carry the license themselves.
+func WantedLicense(p string) bool {
+	return path.Base(p) == "copyright" && path.Dir(path.Dir(p)) == copyrightDir
+}
+
+// debianLicenses sets the license of the Debian packages whose copyright file
+// was captured
+func debianLicenses(pkgs []Package, files map[string][]byte) {
+	for i := range pkgs {
+		if pkgs[i].Manager != Deb || pkgs[i].License != "" {
+			continue
+		}
+		name, _, _ := strings.Cut(pkgs[i].Name, ":") // multiarch suffix
+		if data, ok := files[path.Join(copyrightDir, name, "copyright")]; ok {
+			pkgs[i].License = parseCopyright(data)
+		}
+	}
+}
+
+// parseCopyright returns the licenses named by a machine-readable (DEP-5)
+// copyright file, joined with AND. Free-form copyright files name no license
+// reliably and return an empty string.
+func parseCopyright(data []byte) string {
+	lines := strings.Split(string(data), "\n")
+	if len(lines) == 0 || !strings.HasPrefix(lines[0], "Format:") {
+		return ""
+	}
+
+	var licenses []string
+	seen := make(map[string]bool)
+	for _, line := range lines {
+		value, ok := strings.CutPrefix(line, "License:")
+		if !ok {
+			continue
+		}
+		value = strings.TrimSpace(value)
+		if value == "" || seen[value] {
+			continue
+		}
+		seen[value] = true
+		licenses = append(licenses, value)
+	}
+	return strings.Join(licenses, " AND ")
+}
@@ -0,0 +1,115 @@
+package packages
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+)
+
+// Package managers whose databases are read from the image filesystem
+const (
+	Deb = "deb"
+	Apk = "apk"
+	Rpm = "rpm"
+)
+
+// Package is an OS package installed in an image
+type Package struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+	Arch    string `json:"arch,omitempty"`
+	Manager string `json:"manager"`
+	Size    int64  `json:"size"`             // installed size in bytes
+	Source  string `json:"source,omitempty"` // source package, when it differs from Name
+	License string `json:"license,omitempty"`
+}
+
+// Database locations, absolute paths in the image filesystem
+const (
+	dpkgStatus    = "/var/lib/dpkg/status"
+	dpkgStatusDir = "/var/lib/dpkg/status.d" // distroless images
+	apkInstalled  = "/lib/apk/db/installed"
+)
+
+// rpmDatabases lists the rpmdb files in the BerkeleyDB, NDB and SQLite
+// formats, in the legacy and the /usr/lib/sysimage locations
+var rpmDatabases = []string{
+	"/var/lib/rpm/Packages",
+	"/var/lib/rpm/Packages.db",
+	"/var/lib/rpm/rpmdb.sqlite",
+	"/usr/lib/sysimage/rpm/Packages",
+	"/usr/lib/sysimage/rpm/Packages.db",
+	"/usr/lib/sysimage/rpm/rpmdb.sqlite",
+}
+
+// Wanted reports whether a file is part of a package database, or the
+// os-release file naming the distribution, and must be captured while walking
+// the image layers
+func Wanted(p string) bool {
+	if p == dpkgStatus || p == apkInstalled || path.Dir(p) == dpkgStatusDir {
+		return true
+	}
+	for _, f := range osRelease {
+		if p == f {
+			return true
+		}
+	}
+	for _, db := range rpmDatabases {
+		if p == db {
+			return true
+		}
+	}
+	return false
+}
+
+// Parse reads every package database found in files, which maps absolute
+// paths to file contents, and returns the installed packages sorted by
+// manager and name
+func Parse(files map[string][]byte) ([]Package, error) {
+	var pkgs []Package
+
+	if data, ok := files[dpkgStatus]; ok {
+		pkgs = append(pkgs, parseDpkg(data)...)
+	}
+	for p, data := range files {
+		if path.Dir(p) == dpkgStatusDir {
+			pkgs = append(pkgs, parseDpkg(data)...)
+		}
+	}
+
+	if data, ok := files[apkInstalled]; ok {
+		pkgs = append(pkgs, parseApk(data)...)
+	}
+
+	for _, db := range rpmDatabases {
+		data, ok := files[db]
+		if !ok {
+			continue
+		}
+		rpms, err := parseRpm(path.Base(db), data)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", db, err)
+		}
+		pkgs = append(pkgs, rpms...)
+		break
+	}
+
+	debianLicenses(pkgs, files)
+
+	sort.Slice(pkgs, func(i, j int) bool {
+		if pkgs[i].Manager != pkgs[j].Manager {
+			return pkgs[i].Manager < pkgs[j].Manager
+		}
+		if pkgs[i].Name != pkgs[j].Name {
+			return pkgs[i].Name < pkgs[j].Name
+		}
+		return pkgs[i].Arch < pkgs[j].Arch
+	})
+	return pkgs, nil
+}
+
+// key identifies a package across versions of an image
+func (p Package) key() string {
+	return strings.Join([]string{p.Manager, p.Name, p.Arch}, "/")
+}
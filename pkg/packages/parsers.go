@@ -0,0 +1,98 @@
+package packages
+
+import (
+	"bufio"
+	"bytes"
+	"strconv"
+	"strings"
+)
+
+// parseDpkg parses a dpkg status file. Only installed packages are returned.
+func parseDpkg(data []byte) []Package {
+	var pkgs []Package
+	for _, stanza := range stanzas(data, ": ") {
+		status := stanza["Status"]
+		if status != "" && !strings.HasSuffix(status, " installed") {
+			continue
+		}
+		if stanza["Package"] == "" {
+			continue
+		}
+		pkg := Package{
+			Name:    stanza["Package"],
+			Version: stanza["Version"],
+			Arch:    stanza["Architecture"],
+			Manager: Deb,
+		}
+		// Source may carry the source version: "openssl (3.0.11-1)"
+		if source, _, _ := strings.Cut(stanza["Source"], " "); source != pkg.Name {
+			pkg.Source = source
+		}
+		// Installed-Size is in KiB
+		if kb, err := strconv.ParseInt(stanza["Installed-Size"], 10, 64); err == nil {
+			pkg.Size = kb * 1024
+		}
+		pkgs = append(pkgs, pkg)
+	}
+	return pkgs
+}
+
+// parseApk parses the apk installed database, which uses single letter keys
+func parseApk(data []byte) []Package {
+	var pkgs []Package
+	for _, stanza := range stanzas(data, ":") {
+		if stanza["P"] == "" {
+			continue
+		}
+		pkg := Package{
+			Name:    stanza["P"],
+			Version: stanza["V"],
+			Arch:    stanza["A"],
+			Manager: Apk,
+			License: stanza["L"],
+		}
+		// o: is the origin, the aport the package was built from
+		if origin := stanza["o"]; origin != pkg.Name {
+			pkg.Source = origin
+		}
+		if size, err := strconv.ParseInt(stanza["I"], 10, 64); err == nil {
+			pkg.Size = size
+		}
+		pkgs = append(pkgs, pkg)
+	}
+	return pkgs
+}
+
+// stanzas splits a database made of blank-line separated "Key<sep>Value"
+// blocks. Continuation lines starting with a space are skipped.
+func stanzas(data []byte, sep string) []map[string]string {
+	var result []map[string]string
+	current := make(map[string]string)
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.TrimSpace(line) == "" {
+			if len(current) > 0 {
+				result = append(result, current)
+				current = make(map[string]string)
+			}
+			continue
+		}
+		if line[0] == ' ' || line[0] == '\t' {
+			continue
+		}
+		key, value, ok := strings.Cut(line, sep)
+		if !ok {
+			continue
+		}
+		if _, exists := current[key]; !exists {
+			current[key] = strings.TrimSpace(value)
+		}
+	}
+	if len(current) > 0 {
+		result = append(result, current)
+	}
+	return result
+}
@@ -0,0 +1,73 @@
+package packages
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	_ "github.com/glebarez/go-sqlite" // SQLite driver for rpmdb.sqlite
+	rpmdb "github.com/knqyf263/go-rpmdb/pkg"
+)
+
+// parseRpm reads an rpm database. The reader needs a file on disk, so the
+// database is written to a temporary directory under its original name,
+// which selects the format.
+func parseRpm(name string, data []byte) ([]Package, error) {
+	dir, err := os.MkdirTemp("", "dtm-rpmdb-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+
+	dbPath := filepath.Join(dir, name)
+	if err := os.WriteFile(dbPath, data, 0600); err != nil {
+		return nil, err
+	}
+
+	db, err := rpmdb.Open(dbPath)
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	infos, err := db.ListPackages()
+	if err != nil {
+		return nil, err
+	}
+
+	pkgs := make([]Package, 0, len(infos))
+	for _, info := range infos {
+		version := fmt.Sprintf("%s-%s", info.Version, info.Release)
+		if info.Epoch != nil && *info.Epoch != 0 {
+			version = fmt.Sprintf("%d:%s", *info.Epoch, version)
+		}
+		pkg := Package{
+			Name:    info.Name,
+			Version: version,
+			Arch:    info.Arch,
+			Manager: Rpm,
+			Size:    int64(info.Size),
+			License: info.License,
+		}
+		if source := sourceRpmName(info.SourceRpm); source != pkg.Name {
+			pkg.Source = source
+		}
+		pkgs = append(pkgs, pkg)
+	}
+	return pkgs, nil
+}
+
+// sourceRpmName returns the package name of a source rpm file name, e.g.
+// openssl for openssl-3.0.7-1.el9.src.rpm
+func sourceRpmName(file string) string {
+	name := strings.TrimSuffix(file, ".src.rpm")
+	for i := 0; i < 2; i++ {
+		idx := strings.LastIndex(name, "-")
+		if idx <= 0 {
+			return ""
+		}
+		name = name[:idx]
+	}
+	return name
+}
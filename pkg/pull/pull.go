@@ -0,0 +1,111 @@
+package pull
+
+import (
+	"fmt"
+
+	"github.com/jtodic/docker-time-machine/pkg/report"
+)
+
+// Layer is a layer of an image version, identified by its digest
+type Layer struct {
+	Digest string
+	Size   int64
+}
+
+// Version is an analyzed commit or tag with its layers
+type Version struct {
+	Name   string
+	Layers []Layer
+}
+
+// Cost is what a host pays in downloads to pull a version, by digest
+type Cost struct {
+	// Size counts every distinct layer of the version once
+	Size int64 `json:"size"`
+	// Download is the size of the layers missing from the previous (older)
+	// version, i.e. what a host upgrading from it has to fetch
+	Download int64 `json:"download"`
+	// Shared is the size of the layers already present in the previous version
+	Shared int64 `json:"shared"`
+	// Unique is the size of the layers found in no other analyzed version
+	Unique int64 `json:"unique"`
+	// Fresh is set for the oldest version, which has no previous version to
+	// upgrade from; Download is then a full pull
+	Fresh bool `json:"fresh,omitempty"`
+}
+
+// Compute returns the pull cost of every version. Versions are ordered newest
+// first and each one is compared with the next (older) version.
+func Compute(versions []Version) []Cost {
+	sets := make([]map[string]int64, len(versions))
+	owners := make(map[string]int)
+	for i, v := range versions {
+		sets[i] = layerSet(v.Layers)
+		for digest := range sets[i] {
+			owners[digest]++
+		}
+	}
+
+	costs := make([]Cost, len(versions))
+	for i, layers := range sets {
+		cost := &costs[i]
+		var previous map[string]int64
+		if i+1 < len(sets) {
+			previous = sets[i+1]
+		} else {
+			cost.Fresh = true
+		}
+
+		for digest, size := range layers {
+			cost.Size += size
+			if _, ok := previous[digest]; ok {
+				cost.Shared += size
+			} else {
+				cost.Download += size
+			}
+			if owners[digest] == 1 {
+				cost.Unique += size
+			}
+		}
+	}
+	return costs
+}
+
+// layerSet maps the digests of a version to their sizes. A layer that appears
+// twice in an image is only downloaded once.
+func layerSet(layers []Layer) map[string]int64 {
+	set := make(map[string]int64, len(layers))
+	for _, layer := range layers {
+		set[layer.Digest] = layer.Size
+	}
+	return set
+}
+
+// Section lists the upgrade download size of every version
+func Section(versions []Version, costs []Cost) report.Section {
+	section := report.Section{
+		Title:   "⬇️ Upgrade Download Size",
+		Note:    "Bytes a host that already has the previous version must download, by layer digest.",
+		Headers: []string{"Version", "Size", "Download", "Shared", "Unique", "Reused"},
+	}
+	for i, v := range versions {
+		cost := costs[i]
+		download := report.FormatBytes(cost.Download)
+		if cost.Fresh {
+			download += " (full pull)"
+		}
+		reused := "-"
+		if !cost.Fresh && cost.Size > 0 {
+			reused = fmt.Sprintf("%.0f%%", float64(cost.Shared)/float64(cost.Size)*100)
+		}
+		section.Rows = append(section.Rows, []string{
+			v.Name,
+			report.FormatBytes(cost.Size),
+			download,
+			report.FormatBytes(cost.Shared),
+			report.FormatBytes(cost.Unique),
+			reused,
+		})
+	}
+	return section
+}
@@ -0,0 +1,34 @@
+package report
+
+import "fmt"
+
+// SizeBasis selects which image size drives diffs, charts and insights
+type SizeBasis string
+
+const (
+	// Compressed is the size of the layer blobs as stored in a registry and
+	// downloaded on pull
+	Compressed SizeBasis = "compressed"
+	// Uncompressed is the size of the extracted layers on disk: the bytes of
+	// their files, as Docker reports them
+	Uncompressed SizeBasis = "uncompressed"
+)
+
+// ParseSizeBasis validates a --size-basis value. An empty value selects def.
+func ParseSizeBasis(value string, def SizeBasis) (SizeBasis, error) {
+	switch SizeBasis(value) {
+	case "":
+		return def, nil
+	case Compressed, Uncompressed:
+		return SizeBasis(value), nil
+	}
+	return "", fmt.Errorf("unsupported size basis: %s (use compressed or uncompressed)", value)
+}
+
+// Description explains the basis in report headers
+func (b SizeBasis) Description() string {
+	if b == Compressed {
+		return "Sizes are compressed layer sizes, as stored in the registry and downloaded on pull."
+	}
+	return "Sizes are uncompressed layer sizes: the bytes of the files in each layer, as extracted on disk."
+}
@@ -0,0 +1,174 @@
+package report
+
+import (
+	"fmt"
+	"html"
+	"io"
+	"strings"
+
+	"github.com/olekukonko/tablewriter"
+)
+
+// Section is a titled table that both the analyze and registry reports can
+// render in every text format. Features that add a report section build a
+// Section once instead of formatting each output format themselves.
+type Section struct {
+	Title   string     // heading, may start with an emoji
+	Note    string     // optional explanation shown below the heading
+	Headers []string   // column headers
+	Rows    [][]string // cell values, already formatted
+}
+
+// WriteTable renders the section as a plain text table
+func (s Section) WriteTable(w io.Writer) {
+	if len(s.Rows) == 0 {
+		return
+	}
+
+	fmt.Fprintf(w, "\n%s:\n", s.Title)
+	fmt.Fprintln(w, strings.Repeat("-", len([]rune(s.Title))+1))
+	if s.Note != "" {
+		fmt.Fprintln(w, s.Note)
+	}
+
+	table := tablewriter.NewWriter(w)
+	table.SetHeader(s.Headers)
+	table.SetBorder(false)
+	table.SetAutoWrapText(false)
+	table.SetColumnSeparator(" ")
+	table.SetHeaderAlignment(tablewriter.ALIGN_LEFT)
+	table.SetAlignment(tablewriter.ALIGN_LEFT)
+	for _, row := range s.Rows {
+		table.Append(row)
+	}
+	table.Render()
+}
+
+// WriteMarkdown renders the section as a markdown table
+func (s Section) WriteMarkdown(w io.Writer) {
+	if len(s.Rows) == 0 {
+		return
+	}
+
+	fmt.Fprintln(w)
+	fmt.Fprintf(w, "## %s\n", stripEmoji(s.Title))
+	fmt.Fprintln(w)
+	if s.Note != "" {
+		fmt.Fprintln(w, s.Note)
+		fmt.Fprintln(w)
+	}
+
+	fmt.Fprintf(w, "| %s |\n", strings.Join(s.Headers, " | "))
+	separator := make([]string, len(s.Headers))
+	for i := range separator {
+		separator[i] = "---"
+	}
+	fmt.Fprintf(w, "|%s|\n", strings.Join(separator, "|"))
+	for _, row := range s.Rows {
+		cells := make([]string, len(row))
+		for i, cell := range row {
+			cells[i] = strings.ReplaceAll(cell, "|", "\\|")
+		}
+		fmt.Fprintf(w, "| %s |\n", strings.Join(cells, " | "))
+	}
+}
+
+// WriteCSV renders the section as a commented CSV block
+func (s Section) WriteCSV(w io.Writer) {
+	if len(s.Rows) == 0 {
+		return
+	}
+
+	fmt.Fprintln(w)
+	fmt.Fprintf(w, "# %s\n", stripEmoji(s.Title))
+
+	header := make([]string, len(s.Headers))
+	for i, h := range s.Headers {
+		header[i] = csvField(strings.ToLower(strings.ReplaceAll(h, " ", "_")))
+	}
+	fmt.Fprintln(w, strings.Join(header, ","))
+
+	for _, row := range s.Rows {
+		cells := make([]string, len(row))
+		for i, cell := range row {
+			cells[i] = csvField(cell)
+		}
+		fmt.Fprintln(w, strings.Join(cells, ","))
+	}
+}
+
+// HTML renders the section as a card for the HTML chart reports
+func (s Section) HTML() string {
+	if len(s.Rows) == 0 {
+		return ""
+	}
+
+	var b strings.Builder
+	b.WriteString("\n    <div class=\"chart-container\">\n")
+	fmt.Fprintf(&b, "        <h2>%s</h2>\n", html.EscapeString(s.Title))
+	if s.Note != "" {
+		fmt.Fprintf(&b, "        <p class=\"note\">%s</p>\n", html.EscapeString(s.Note))
+	}
+	b.WriteString("        <div class=\"layer-table-container\">\n            <table class=\"layer-table\">\n                <thead><tr>")
+	for _, h := range s.Headers {
+		fmt.Fprintf(&b, "<th>%s</th>", html.EscapeString(h))
+	}
+	b.WriteString("</tr></thead>\n                <tbody>\n")
+	for _, row := range s.Rows {
+		b.WriteString("                <tr>")
+		for _, cell := range row {
+			fmt.Fprintf(&b, "<td title=\"%s\">%s</td>", html.EscapeString(cell), html.EscapeString(cell))
+		}
+		b.WriteString("</tr>\n")
+	}
+	b.WriteString("                </tbody>\n            </table>\n        </div>\n    </div>\n")
+	return b.String()
+}
+
+// FormatBytes formats a byte count with a binary unit, e.g. "1.5 MB"
+func FormatBytes(n int64) string {
+	sign := ""
+	if n < 0 {
+		sign = "-"
+		n = -n
+	}
+
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%s%d B", sign, n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit && exp < 4; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%s%.1f %cB", sign, float64(n)/float64(div), "KMGTP"[exp])
+}
+
+// FormatBytesDiff formats a byte delta with an explicit sign
+func FormatBytesDiff(n int64) string {
+	if n > 0 {
+		return "+" + FormatBytes(n)
+	}
+	return FormatBytes(n)
+}
+
+// csvField quotes a CSV field when needed
+func csvField(s string) string {
+	if strings.ContainsAny(s, ",\"\n") {
+		return "\"" + strings.ReplaceAll(s, "\"", "\"\"") + "\""
+	}
+	return s
+}
+
+// stripEmoji removes a leading emoji and space from a title, which markdown
+// and CSV headings don't carry
+func stripEmoji(title string) string {
+	if idx := strings.Index(title, " "); idx > 0 {
+		first := []rune(title[:idx])
+		if len(first) > 0 && first[0] > 0x2000 {
+			return title[idx+1:]
+		}
+	}
+	return title
+}
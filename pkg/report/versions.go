@@ -0,0 +1,52 @@
+package report
+
+import "unicode/utf8"
+
+// MaxRows limits the rows a section shows per version pair or table in text
+// reports; JSON reports always list every entry
+const MaxRows = 20
+
+// ConsecutivePairs compares each version for which ok reports true with the
+// next older such version and returns the results, newest pair first.
+// Versions are ordered newest first, as every report lists them.
+func ConsecutivePairs[V, D any](versions []V, ok func(V) bool, compare func(older, newer V) D) []D {
+	var diffs []D
+	for i, v := range versions {
+		if !ok(v) {
+			continue
+		}
+		if j := NextOlder(versions, i, ok); j >= 0 {
+			diffs = append(diffs, compare(versions[j], v))
+		}
+	}
+	return diffs
+}
+
+// NextOlder returns the index of the first version after i for which ok
+// reports true, or -1 when there is none
+func NextOlder[V any](versions []V, i int, ok func(V) bool) int {
+	for j := i + 1; j < len(versions); j++ {
+		if ok(versions[j]) {
+			return j
+		}
+	}
+	return -1
+}
+
+// OrDash returns s, or a dash for an empty table cell
+func OrDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+// Truncate shortens s to maxLen characters, ending it with "..." when cut.
+// It cuts on rune boundaries, so multi-byte characters are never split.
+func Truncate(s string, maxLen int) string {
+	if utf8.RuneCountInString(s) <= maxLen {
+		return s
+	}
+	runes := []rune(s)
+	return string(runes[:max(maxLen-3, 0)]) + "..."
+}
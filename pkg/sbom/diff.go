@@ -0,0 +1,101 @@
+package sbom
+
+import (
+	"fmt"
+
+	"github.com/jtodic/docker-time-machine/pkg/report"
+)
+
+// VersionDiff lists the components added and removed from one analyzed
+// version to the next. Components are compared by package URL, so an upgrade
+// shows up as the old version removed and the new one added.
+type VersionDiff struct {
+	From    string      `json:"from"`
+	To      string      `json:"to"`
+	Added   []Component `json:"added"`
+	Removed []Component `json:"removed"`
+}
+
+// Version is the component list of an analyzed commit or tag
+type Version struct {
+	Name       string
+	Components []Component
+	Scanned    bool // false when the image could not be inspected
+}
+
+// Compare returns the components added and removed from older to newer.
+// Both lists must be sorted by package URL, as returned by Components.
+func Compare(from, to string, older, newer []Component) VersionDiff {
+	diff := VersionDiff{From: from, To: to, Added: []Component{}, Removed: []Component{}}
+
+	before := make(map[string]bool, len(older))
+	for _, c := range older {
+		before[c.PURL] = true
+	}
+	after := make(map[string]bool, len(newer))
+	for _, c := range newer {
+		after[c.PURL] = true
+		if !before[c.PURL] {
+			diff.Added = append(diff.Added, c)
+		}
+	}
+	for _, c := range older {
+		if !after[c.PURL] {
+			diff.Removed = append(diff.Removed, c)
+		}
+	}
+	return diff
+}
+
+// CompareVersions compares each scanned version with the next older scanned
+// one. Versions are ordered newest first.
+func CompareVersions(versions []Version) []VersionDiff {
+	return report.ConsecutivePairs(versions, func(v Version) bool { return v.Scanned },
+		func(older, newer Version) VersionDiff {
+			return Compare(older.Name, newer.Name, older.Components, newer.Components)
+		})
+}
+
+// DiffSection lists the components added and removed between consecutive
+// versions
+func DiffSection(diffs []VersionDiff) report.Section {
+	section := report.Section{
+		Title:   "📜 SBOM Changes",
+		Headers: []string{"Version", "Change", "Component", "Type"},
+	}
+
+	for _, d := range diffs {
+		version := fmt.Sprintf("%s → %s", d.From, d.To)
+		rows := 0
+		total := len(d.Added) + len(d.Removed)
+		for _, change := range []struct {
+			kind       string
+			components []Component
+		}{{"added", d.Added}, {"removed", d.Removed}} {
+			for _, c := range change.components {
+				if rows == report.MaxRows {
+					break
+				}
+				section.Rows = append(section.Rows, []string{
+					version, change.kind, c.label(), c.Type,
+				})
+				rows++
+			}
+		}
+		if rows < total {
+			section.Rows = append(section.Rows, []string{
+				version, fmt.Sprintf("... %d more", total-rows), "", "",
+			})
+		}
+	}
+
+	return section
+}
+
+// label returns name@version, or the name alone when the version is unknown
+func (c Component) label() string {
+	if c.Version == "" {
+		return c.Name
+	}
+	return c.Name + "@" + c.Version
+}
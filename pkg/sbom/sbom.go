@@ -0,0 +1,174 @@
+package sbom
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/jtodic/docker-time-machine/pkg/deps"
+	"github.com/jtodic/docker-time-machine/pkg/packages"
+)
+
+// SBOM output formats
+const (
+	SPDX      = "spdx"
+	CycloneDX = "cyclonedx"
+)
+
+// Component is a package or dependency found in an image
+type Component struct {
+	Name    string `json:"name"`
+	Version string `json:"version,omitempty"`
+	Type    string `json:"type"` // package manager or ecosystem, e.g. deb, npm
+	PURL    string `json:"purl"`
+}
+
+// Document describes the components of one analyzed version of an image
+type Document struct {
+	Image      string // image or repository name
+	Version    string // commit or tag
+	Created    time.Time
+	Components []Component
+}
+
+// Components builds the component list of an image from its OS packages and
+// language dependencies. Components are identified by package URL, so a
+// dependency installed in several places is listed once.
+func Components(distro *packages.Distro, pkgs []packages.Package, dependencies []deps.Dependency) []Component {
+	seen := make(map[string]bool)
+	var components []Component
+	add := func(c Component) {
+		if seen[c.PURL] {
+			return
+		}
+		seen[c.PURL] = true
+		components = append(components, c)
+	}
+
+	for _, p := range pkgs {
+		add(Component{Name: p.Name, Version: p.Version, Type: p.Manager, PURL: packagePURL(distro, p)})
+	}
+	for _, d := range dependencies {
+		add(Component{Name: d.Name, Version: d.Version, Type: d.Ecosystem, PURL: dependencyPURL(d)})
+	}
+
+	sort.Slice(components, func(i, j int) bool {
+		return components[i].PURL < components[j].PURL
+	})
+	return components
+}
+
+// packagePURL returns the package URL of an OS package, e.g.
+// pkg:deb/debian/curl@7.88.1-10?arch=amd64&distro=debian-12
+func packagePURL(distro *packages.Distro, p packages.Package) string {
+	namespace := map[string]string{packages.Deb: "debian", packages.Apk: "alpine", packages.Rpm: "redhat"}[p.Manager]
+	var qualifiers []string
+	if p.Arch != "" {
+		qualifiers = append(qualifiers, "arch="+url.QueryEscape(p.Arch))
+	}
+	if distro != nil {
+		namespace = distro.ID
+		if distro.VersionID != "" {
+			qualifiers = append(qualifiers, "distro="+url.QueryEscape(distro.ID+"-"+distro.VersionID))
+		}
+	}
+	return purl(p.Manager, namespace, p.Name, p.Version, qualifiers)
+}
+
+// dependencyPURL returns the package URL of a language dependency
+func dependencyPURL(d deps.Dependency) string {
+	switch d.Ecosystem {
+	case deps.Node:
+		// Scoped packages keep their scope as namespace
+		if scope, name, ok := strings.Cut(d.Name, "/"); ok && strings.HasPrefix(scope, "@") {
+			return purl("npm", scope, name, d.Version, nil)
+		}
+		return purl("npm", "", d.Name, d.Version, nil)
+	case deps.Python:
+		// PyPI names are case-insensitive and treat _ and - alike
+		name := strings.ToLower(strings.ReplaceAll(d.Name, "_", "-"))
+		return purl("pypi", "", name, d.Version, nil)
+	case deps.Go:
+		namespace, name := "", d.Name
+		if idx := strings.LastIndex(d.Name, "/"); idx >= 0 {
+			namespace, name = d.Name[:idx], d.Name[idx+1:]
+		}
+		return purl("golang", namespace, name, d.Version, nil)
+	}
+	return purl(d.Ecosystem, "", d.Name, d.Version, nil)
+}
+
+// purl formats a package URL, escaping each segment
+func purl(kind, namespace, name, version string, qualifiers []string) string {
+	var b strings.Builder
+	b.WriteString("pkg:" + kind + "/")
+	if namespace != "" {
+		for _, segment := range strings.Split(namespace, "/") {
+			b.WriteString(escapePURL(segment) + "/")
+		}
+	}
+	b.WriteString(escapePURL(name))
+	if version != "" {
+		b.WriteString("@" + escapePURL(version))
+	}
+	if len(qualifiers) > 0 {
+		b.WriteString("?" + strings.Join(qualifiers, "&"))
+	}
+	return b.String()
+}
+
+// escapePURL percent-encodes a package URL segment. The @ of npm scopes and
+// the + of Debian versions must be encoded as well.
+func escapePURL(segment string) string {
+	return strings.NewReplacer("@", "%40", "+", "%2B").Replace(url.PathEscape(segment))
+}
+
+// Write writes the document in the given format
+func Write(w io.Writer, format string, doc Document) error {
+	switch format {
+	case SPDX:
+		return WriteSPDX(w, doc)
+	case CycloneDX:
+		return WriteCycloneDX(w, doc)
+	}
+	return ValidateFormat(format)
+}
+
+// ValidateFormat returns an error when format is not a supported SBOM format
+func ValidateFormat(format string) error {
+	if format != SPDX && format != CycloneDX {
+		return fmt.Errorf("unsupported SBOM format: %s (use spdx or cyclonedx)", format)
+	}
+	return nil
+}
+
+var unsafeFileChars = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// FileName returns the file name of the SBOM of a version, e.g.
+// sbom-1a2b3c4d.spdx.json
+func FileName(version, format string) string {
+	ext := ".spdx.json"
+	if format == CycloneDX {
+		ext = ".cdx.json"
+	}
+	return "sbom-" + unsafeFileChars.ReplaceAllString(version, "_") + ext
+}
+
+// uuid derives a stable, UUID-formatted identifier from the document, so the
+// same image version always gets the same SBOM identifier
+func (d Document) uuid() string {
+	h := sha256.New()
+	fmt.Fprintf(h, "%s\n%s\n", d.Image, d.Version)
+	for _, c := range d.Components {
+		fmt.Fprintln(h, c.PURL)
+	}
+	sum := h.Sum(nil)
+	sum[6] = (sum[6] & 0x0f) | 0x50 // version 5 style, name based
+	sum[8] = (sum[8] & 0x3f) | 0x80 // RFC 4122 variant
+	return fmt.Sprintf("%x-%x-%x-%x-%x", sum[0:4], sum[4:6], sum[6:8], sum[8:10], sum[10:16])
+}
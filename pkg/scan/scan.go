@@ -0,0 +1,450 @@
+package scan
+
+import (
+	"archive/tar"
+	"fmt"
+	"io"
+
+	"github.com/jtodic/docker-time-machine/pkg/binaries"
+	"github.com/jtodic/docker-time-machine/pkg/deps"
+	"github.com/jtodic/docker-time-machine/pkg/dupes"
+	"github.com/jtodic/docker-time-machine/pkg/inspect"
+	"github.com/jtodic/docker-time-machine/pkg/junk"
+	"github.com/jtodic/docker-time-machine/pkg/license"
+	"github.com/jtodic/docker-time-machine/pkg/packages"
+	"github.com/jtodic/docker-time-machine/pkg/report"
+	"github.com/jtodic/docker-time-machine/pkg/sbom"
+	"github.com/jtodic/docker-time-machine/pkg/secrets"
+	"github.com/jtodic/docker-time-machine/pkg/treemap"
+	"github.com/jtodic/docker-time-machine/pkg/vuln"
+)
+
+// topFiles is the number of files and directories listed per version pair
+const topFiles = 10
+
+// Options selects the layer content features to run. Every feature reads
+// the layer tarballs of each analyzed version in a single pass.
+type Options struct {
+	Files        bool // top growing files and directories
+	Waste        bool // bytes hidden by later layers
+	Packages     bool // OS package inventory
+	Dependencies bool // language ecosystem dependencies
+	Junk         bool // leftover caches and build files
+	SBOM         bool // software bill of materials from packages and dependencies
+	Licenses     bool // licenses of packages and dependencies
+	Binaries     bool // ELF executables and their build properties
+	Secrets      bool // keys, tokens and passwords in any layer
+	Duplicates   bool // file contents stored more than once
+	Dirs         bool // final filesystem size by directory
+	Treemap      bool // zoomable treemap of each version in the HTML report
+
+	// Vulns is the advisory database the packages are matched against, nil
+	// when vulnerability matching is off
+	Vulns *vuln.DB
+
+	// DenyLicenses lists the licenses that must not appear, see license.Denied
+	DenyLicenses []string
+
+	// DirDepth is the number of path components directories are grouped by,
+	// inspect.DefaultDirDepth when 0
+	DirDepth int
+}
+
+// Enabled reports whether any feature needs the layer contents
+func (o Options) Enabled() bool {
+	return o.Files || o.Waste || o.Packages || o.Dependencies || o.Junk || o.SBOM || o.Licenses || o.Binaries || o.Secrets || o.Duplicates || o.Dirs || o.Treemap || o.Vulns != nil
+}
+
+// Result holds what was found in the layers of one image. It is embedded in
+// the per-version results of both the analyze and registry modes.
+type Result struct {
+	// Files holds the per-layer file changes
+	Files *inspect.Image `json:"files,omitempty"`
+	// Packages lists the installed OS packages
+	Packages []packages.Package `json:"packages,omitempty"`
+	// Dependencies lists the language ecosystem dependencies
+	Dependencies []deps.Dependency `json:"dependencies,omitempty"`
+	// Junk lists leftover caches and build files
+	Junk *junk.Report `json:"junk,omitempty"`
+	// Distro identifies the Linux distribution of the image
+	Distro *packages.Distro `json:"distro,omitempty"`
+	// SBOM lists the components of the image
+	SBOM []sbom.Component `json:"sbom,omitempty"`
+	// Vulnerabilities lists the known vulnerabilities of the installed packages
+	Vulnerabilities []vuln.Finding `json:"vulnerabilities,omitempty"`
+	// Licenses groups the packages and dependencies by license
+	Licenses []license.Usage `json:"licenses,omitempty"`
+	// Binaries lists the ELF executables, largest first
+	Binaries []binaries.Binary `json:"binaries,omitempty"`
+	// Secrets lists the secrets in files written by any layer, including
+	// files deleted later
+	Secrets []secrets.Finding `json:"secrets,omitempty"`
+	// Duplicates lists the file contents stored more than once
+	Duplicates *dupes.Report `json:"duplicates,omitempty"`
+	// Dirs is the size of the final filesystem by directory, largest first
+	Dirs []inspect.DirSize `json:"dirs,omitempty"`
+}
+
+// Run walks the layers of an image, lowest first, and runs the enabled features
+func Run(layers []inspect.LayerSource, opts Options) (*Result, error) {
+	// The SBOM, the vulnerability matching and the license inventory are
+	// built from the package databases and the dependencies
+	inventory := opts.SBOM || opts.Vulns != nil || opts.Licenses
+	readPackages := opts.Packages || inventory
+	var collector *deps.Collector
+	var binCollector *binaries.Collector
+	var secretCollector *secrets.Collector
+	var dupeCollector *dupes.Collector
+	walk := inspect.WalkOptions{
+		Capture: func(path string) bool {
+			return (readPackages && packages.Wanted(path)) ||
+				(opts.Licenses && packages.WantedLicense(path)) ||
+				(collector != nil && collector.Wanted(path))
+		},
+	}
+	var visits []func(layer int, path string, hdr *tar.Header, r io.Reader) error
+	if opts.Dependencies || inventory {
+		collector = deps.NewCollector()
+	}
+	// The binaries collector also finds the Go binaries for the dependencies
+	if opts.Binaries || collector != nil {
+		binCollector = binaries.NewCollector()
+		visits = append(visits, binCollector.Visit)
+	}
+	if opts.Secrets {
+		secretCollector = secrets.NewCollector()
+		visits = append(visits, secretCollector.Visit)
+	}
+	if opts.Duplicates {
+		dupeCollector = dupes.NewCollector()
+		visits = append(visits, dupeCollector.Visit)
+	}
+	walk.Visit = inspect.MultiVisit(visits...)
+
+	img, err := inspect.Walk(layers, walk)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &Result{}
+	var pkgs []packages.Package
+	if readPackages {
+		pkgs, err = packages.Parse(img.Contents)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read package database: %w", err)
+		}
+		result.Distro = packages.ParseDistro(img.Contents)
+	}
+	if opts.Packages {
+		result.Packages = pkgs
+	}
+	var dependencies []deps.Dependency
+	if collector != nil {
+		dependencies = collector.Result(img, binCollector.GoBinaries(img))
+	}
+	if opts.Dependencies {
+		result.Dependencies = dependencies
+	}
+	if opts.SBOM {
+		result.SBOM = sbom.Components(result.Distro, pkgs, dependencies)
+	}
+	if opts.Vulns != nil {
+		result.Vulnerabilities = opts.Vulns.Match(result.Distro, pkgs, dependencies)
+	}
+	if opts.Licenses {
+		result.Licenses = license.Inventory(pkgs, dependencies)
+	}
+	if opts.Binaries {
+		result.Binaries = binCollector.Result(img)
+	}
+	if secretCollector != nil {
+		result.Secrets = secretCollector.Result(img)
+	}
+	if dupeCollector != nil {
+		dupeReport := dupeCollector.Result(img)
+		result.Duplicates = &dupeReport
+	}
+	if opts.Dirs {
+		result.Dirs = img.DirSizes(opts.DirDepth)
+	}
+	if opts.Junk {
+		junkReport := junk.Scan(img)
+		result.Junk = &junkReport
+	}
+
+	// Captured contents are only needed while the result is built
+	img.Contents = nil
+	result.Files = img
+	return result, nil
+}
+
+// Version is the scan result of an analyzed commit or tag. Result is nil when
+// the version was not scanned.
+type Version struct {
+	Name   string
+	Result *Result
+}
+
+// Diffs holds the changes between consecutive versions for the JSON reports
+type Diffs struct {
+	FileDiffs       []inspect.VersionDiff  `json:"file_diffs,omitempty"`
+	PackageDiffs    []packages.VersionDiff `json:"package_diffs,omitempty"`
+	DependencyDiffs []deps.VersionDiff     `json:"dependency_diffs,omitempty"`
+	SBOMDiffs       []sbom.VersionDiff     `json:"sbom_diffs,omitempty"`
+	VulnDiffs       []vuln.VersionDiff     `json:"vulnerability_diffs,omitempty"`
+	LicenseDiffs    []license.VersionDiff  `json:"license_diffs,omitempty"`
+	BinaryDiffs     []binaries.VersionDiff `json:"binary_diffs,omitempty"`
+
+	// LicenseViolations lists the denied licenses and where they appeared
+	LicenseViolations []license.Violation `json:"license_violations,omitempty"`
+	// SecretLeaks lists the secrets and where they first appeared
+	SecretLeaks []secrets.Leak `json:"secret_leaks,omitempty"`
+}
+
+// Compare computes the changes between consecutive versions, newest first,
+// for the enabled features
+func Compare(opts Options, versions []Version) Diffs {
+	var diffs Diffs
+	if opts.Files {
+		diffs.FileDiffs = fileDiffs(versions)
+	}
+	if opts.Packages {
+		diffs.PackageDiffs = packages.CompareVersions(packageVersions(versions))
+	}
+	if opts.Dependencies {
+		diffs.DependencyDiffs = deps.CompareVersions(dependencyVersions(versions))
+	}
+	if opts.SBOM {
+		diffs.SBOMDiffs = sbom.CompareVersions(sbomVersions(versions))
+	}
+	if opts.Vulns != nil {
+		diffs.VulnDiffs = vuln.CompareVersions(VulnVersions(versions))
+	}
+	if opts.Licenses {
+		diffs.LicenseDiffs = license.CompareVersions(licenseVersions(versions))
+		diffs.LicenseViolations = LicenseViolations(opts, versions)
+	}
+	if opts.Binaries {
+		diffs.BinaryDiffs = binaries.CompareVersions(binaryVersions(versions))
+	}
+	if opts.Secrets {
+		diffs.SecretLeaks = SecretLeaks(opts, versions)
+	}
+	return diffs
+}
+
+// Sections returns the report sections of the enabled features
+func Sections(opts Options, versions []Version) []report.Section {
+	diffs := Compare(opts, versions)
+
+	var sections []report.Section
+	if opts.Files {
+		sections = append(sections, inspect.GrowthSections(diffs.FileDiffs)...)
+	}
+	if opts.Waste {
+		sections = append(sections, inspect.WasteSection(imageVersions(versions)))
+	}
+	if opts.Dirs {
+		sections = append(sections, inspect.DirTrendSection(DirVersions(versions)))
+	}
+	if opts.Packages {
+		sections = append(sections, packages.DiffSection(diffs.PackageDiffs))
+	}
+	if opts.Dependencies {
+		sections = append(sections, deps.DiffSection(diffs.DependencyDiffs))
+	}
+	if opts.Junk {
+		sections = append(sections, junk.Sections(junkVersions(versions))...)
+	}
+	if opts.SBOM {
+		sections = append(sections, sbom.DiffSection(diffs.SBOMDiffs))
+	}
+	if opts.Vulns != nil {
+		sections = append(sections, vuln.Sections(VulnVersions(versions), diffs.VulnDiffs)...)
+	}
+	if opts.Licenses {
+		sections = append(sections, license.Sections(licenseVersions(versions), diffs.LicenseDiffs, opts.DenyLicenses)...)
+	}
+	if opts.Binaries {
+		sections = append(sections, binaries.Sections(binaryVersions(versions), diffs.BinaryDiffs)...)
+	}
+	if opts.Secrets {
+		sections = append(sections, secrets.LeakSection(diffs.SecretLeaks))
+	}
+	if opts.Duplicates {
+		sections = append(sections, dupes.Sections(dupeVersions(versions))...)
+	}
+	return sections
+}
+
+// fileDiffs compares the filesystem of each scanned version with the next
+// older one
+func fileDiffs(versions []Version) []inspect.VersionDiff {
+	var diffs []inspect.VersionDiff
+	for i := 0; i+1 < len(versions); i++ {
+		newer, older := versions[i], versions[i+1]
+		if newer.Result == nil || older.Result == nil {
+			continue
+		}
+		diffs = append(diffs, inspect.Compare(older.Name, newer.Name, older.Result.Files, newer.Result.Files, topFiles))
+	}
+	return diffs
+}
+
+func imageVersions(versions []Version) []inspect.Version {
+	result := make([]inspect.Version, 0, len(versions))
+	for _, v := range versions {
+		iv := inspect.Version{Name: v.Name}
+		if v.Result != nil {
+			iv.Image = v.Result.Files
+		}
+		result = append(result, iv)
+	}
+	return result
+}
+
+// TreemapHTML returns the treemap block of the HTML report, or "" when the
+// treemap is off
+func TreemapHTML(opts Options, versions []Version) string {
+	if !opts.Treemap {
+		return ""
+	}
+	return treemap.HTML(imageVersions(versions))
+}
+
+// DirVersions pairs each version with its directory sizes
+func DirVersions(versions []Version) []inspect.DirVersion {
+	result := make([]inspect.DirVersion, 0, len(versions))
+	for _, v := range versions {
+		dv := inspect.DirVersion{Name: v.Name, Scanned: v.Result != nil}
+		if v.Result != nil {
+			dv.Dirs = v.Result.Dirs
+		}
+		result = append(result, dv)
+	}
+	return result
+}
+
+func packageVersions(versions []Version) []packages.Version {
+	result := make([]packages.Version, 0, len(versions))
+	for _, v := range versions {
+		pv := packages.Version{Name: v.Name, Scanned: v.Result != nil}
+		if v.Result != nil {
+			pv.Packages = v.Result.Packages
+		}
+		result = append(result, pv)
+	}
+	return result
+}
+
+func dependencyVersions(versions []Version) []deps.Version {
+	result := make([]deps.Version, 0, len(versions))
+	for _, v := range versions {
+		dv := deps.Version{Name: v.Name, Scanned: v.Result != nil}
+		if v.Result != nil {
+			dv.Dependencies = v.Result.Dependencies
+		}
+		result = append(result, dv)
+	}
+	return result
+}
+
+func junkVersions(versions []Version) []junk.Version {
+	result := make([]junk.Version, 0, len(versions))
+	for _, v := range versions {
+		jv := junk.Version{Name: v.Name}
+		if v.Result != nil {
+			jv.Report = v.Result.Junk
+		}
+		result = append(result, jv)
+	}
+	return result
+}
+
+func sbomVersions(versions []Version) []sbom.Version {
+	result := make([]sbom.Version, 0, len(versions))
+	for _, v := range versions {
+		sv := sbom.Version{Name: v.Name, Scanned: v.Result != nil}
+		if v.Result != nil {
+			sv.Components = v.Result.SBOM
+		}
+		result = append(result, sv)
+	}
+	return result
+}
+
+// VulnVersions pairs each version with its vulnerability findings
+func VulnVersions(versions []Version) []vuln.Version {
+	result := make([]vuln.Version, 0, len(versions))
+	for _, v := range versions {
+		vv := vuln.Version{Name: v.Name, Scanned: v.Result != nil}
+		if v.Result != nil {
+			vv.Findings = v.Result.Vulnerabilities
+		}
+		result = append(result, vv)
+	}
+	return result
+}
+
+// LicenseViolations returns the denied licenses found in the versions, at
+// the version where each appeared
+func LicenseViolations(opts Options, versions []Version) []license.Violation {
+	return license.Violations(licenseVersions(versions), opts.DenyLicenses)
+}
+
+func licenseVersions(versions []Version) []license.Version {
+	result := make([]license.Version, 0, len(versions))
+	for _, v := range versions {
+		lv := license.Version{Name: v.Name, Scanned: v.Result != nil}
+		if v.Result != nil {
+			lv.Licenses = v.Result.Licenses
+		}
+		result = append(result, lv)
+	}
+	return result
+}
+
+func binaryVersions(versions []Version) []binaries.Version {
+	result := make([]binaries.Version, 0, len(versions))
+	for _, v := range versions {
+		bv := binaries.Version{Name: v.Name, Scanned: v.Result != nil}
+		if v.Result != nil {
+			bv.Binaries = v.Result.Binaries
+		}
+		result = append(result, bv)
+	}
+	return result
+}
+
+// SecretLeaks returns the secrets found in the versions, at the version where
+// each first appeared, or nil when secret scanning is off
+func SecretLeaks(opts Options, versions []Version) []secrets.Leak {
+	if !opts.Secrets {
+		return nil
+	}
+	return secrets.Leaks(secretVersions(versions))
+}
+
+func secretVersions(versions []Version) []secrets.Version {
+	result := make([]secrets.Version, 0, len(versions))
+	for _, v := range versions {
+		sv := secrets.Version{Name: v.Name, Scanned: v.Result != nil}
+		if v.Result != nil {
+			sv.Findings = v.Result.Secrets
+		}
+		result = append(result, sv)
+	}
+	return result
+}
+
+func dupeVersions(versions []Version) []dupes.Version {
+	result := make([]dupes.Version, 0, len(versions))
+	for _, v := range versions {
+		dv := dupes.Version{Name: v.Name}
+		if v.Result != nil {
+			dv.Report = v.Result.Duplicates
+		}
+		result = append(result, dv)
+	}
+	return result
+}
@@ -0,0 +1,104 @@
+package secrets
+
+import (
+	"fmt"
+
+	"github.com/jtodic/docker-time-machine/pkg/report"
+)
+
+// Version is the secret scan of an analyzed commit or tag
+type Version struct {
+	Name     string
+	Findings []Finding
+	Scanned  bool // false when the image could not be inspected
+}
+
+// Leak is a secret at the version where it first appeared
+type Leak struct {
+	Finding
+	Version string `json:"version"` // oldest scanned version that ships the secret
+	// Versions counts the scanned versions that ship the secret
+	Versions int `json:"versions"`
+	// Latest is set when the newest scanned version still ships the secret
+	Latest bool `json:"latest"`
+}
+
+func (l Leak) String() string {
+	state := "visible"
+	if l.Hidden {
+		state = "deleted by a later layer but still shipped"
+	}
+	return fmt.Sprintf("%s in %s:%d first appeared in %s, layer %d (%s), %s",
+		l.Rule, l.Path, l.Line, l.Version, l.Layer, report.Truncate(l.Instruction, 60), state)
+}
+
+// Leaks walks the versions from oldest to newest and returns every secret
+// once, as found in the oldest version that ships it. Versions are ordered
+// newest first.
+func Leaks(versions []Version) []Leak {
+	newest := -1
+	for i, v := range versions {
+		if v.Scanned {
+			newest = i
+			break
+		}
+	}
+
+	var leaks []Leak
+	index := make(map[string]int)
+	for i := len(versions) - 1; i >= 0; i-- {
+		v := versions[i]
+		if !v.Scanned {
+			continue
+		}
+		seen := make(map[string]bool)
+		for _, f := range v.Findings {
+			if seen[f.Fingerprint] {
+				continue
+			}
+			seen[f.Fingerprint] = true
+			n, ok := index[f.Fingerprint]
+			if !ok {
+				n = len(leaks)
+				index[f.Fingerprint] = n
+				leaks = append(leaks, Leak{Finding: f, Version: v.Name})
+			}
+			leaks[n].Versions++
+			leaks[n].Latest = i == newest
+		}
+	}
+	return leaks
+}
+
+// LeakSection lists the secrets with the layer and version that introduced
+// them
+func LeakSection(leaks []Leak) report.Section {
+	section := report.Section{
+		Title:   "🔑 Secrets",
+		Headers: []string{"Rule", "File", "Secret", "First Version", "Layer", "Versions", "State"},
+	}
+	hidden := false
+	for _, l := range leaks {
+		state := "visible"
+		if l.Hidden {
+			state = "deleted later"
+			hidden = true
+		}
+		if !l.Latest {
+			state = "removed"
+		}
+		section.Rows = append(section.Rows, []string{
+			l.Rule,
+			fmt.Sprintf("%s:%d", l.Path, l.Line),
+			l.Secret,
+			l.Version,
+			fmt.Sprintf("%d %s", l.Layer, report.Truncate(l.Instruction, 40)),
+			fmt.Sprintf("%d", l.Versions),
+			state,
+		})
+	}
+	if hidden {
+		section.Note = "Files deleted by a later layer are still shipped in the layer that wrote them; rotate the secret and keep it out of the build context or use a secret mount."
+	}
+	return section
+}
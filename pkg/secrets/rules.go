@@ -0,0 +1,174 @@
+package secrets
+
+import (
+	"math"
+	"regexp"
+	"strings"
+)
+
+// Rule detects a kind of secret in file contents
+type Rule struct {
+	ID   string
+	Name string
+	// pattern matches the secret; when it has a capture group, the first
+	// group is the secret and the rest is context such as a variable name
+	pattern *regexp.Regexp
+	// minEntropy is the Shannon entropy in bits per character the secret must
+	// reach, which rejects placeholders such as "changeme"; 0 accepts any
+	// match
+	minEntropy float64
+}
+
+// Rules is the built-in rule set. Provider tokens with a fixed format come
+// first; the generic rules only report values that look random.
+var Rules = []Rule{
+	{
+		ID:      "private-key",
+		Name:    "Private key",
+		pattern: regexp.MustCompile(`(?s)-----BEGIN (?:[A-Z0-9]+ )*PRIVATE KEY(?: BLOCK)?-----.*?-----END (?:[A-Z0-9]+ )*PRIVATE KEY(?: BLOCK)?-----`),
+	},
+	{
+		ID:      "aws-access-key",
+		Name:    "AWS access key ID",
+		pattern: regexp.MustCompile(`\b((?:AKIA|ASIA)[0-9A-Z]{16})\b`),
+	},
+	{
+		ID:         "aws-secret-key",
+		Name:       "AWS secret access key",
+		pattern:    regexp.MustCompile(`(?i)aws_?secret_?access_?key["']?\s*[:=]\s*["']?([A-Za-z0-9/+=]{40})\b`),
+		minEntropy: 4,
+	},
+	{
+		ID:      "github-token",
+		Name:    "GitHub token",
+		pattern: regexp.MustCompile(`\b((?:ghp|gho|ghu|ghs|ghr)_[A-Za-z0-9]{36}|github_pat_[A-Za-z0-9_]{82})\b`),
+	},
+	{
+		ID:      "gitlab-token",
+		Name:    "GitLab token",
+		pattern: regexp.MustCompile(`\b(glpat-[A-Za-z0-9_-]{20})\b`),
+	},
+	{
+		ID:      "slack-token",
+		Name:    "Slack token",
+		pattern: regexp.MustCompile(`\b(xox[abposr]-[A-Za-z0-9-]{10,})\b`),
+	},
+	{
+		ID:      "stripe-key",
+		Name:    "Stripe live key",
+		pattern: regexp.MustCompile(`\b((?:sk|rk)_live_[A-Za-z0-9]{24,})\b`),
+	},
+	{
+		ID:      "google-api-key",
+		Name:    "Google API key",
+		pattern: regexp.MustCompile(`\b(AIza[0-9A-Za-z_-]{35})\b`),
+	},
+	{
+		ID:      "npm-token",
+		Name:    "npm token",
+		pattern: regexp.MustCompile(`\b(npm_[A-Za-z0-9]{36})\b|_authToken\s*=\s*([^\s$][^\s]{15,})`),
+	},
+	{
+		ID:      "pypi-token",
+		Name:    "PyPI token",
+		pattern: regexp.MustCompile(`\b(pypi-AgEIcHlwaS5vcmc[A-Za-z0-9_-]{50,})`),
+	},
+	{
+		ID:         "docker-auth",
+		Name:       "Docker registry credentials",
+		pattern:    regexp.MustCompile(`"auth"\s*:\s*"([A-Za-z0-9+/]{12,}={0,2})"`),
+		minEntropy: 3,
+	},
+	{
+		ID:         "jwt",
+		Name:       "JSON Web Token",
+		pattern:    regexp.MustCompile(`\b(eyJ[A-Za-z0-9_-]{10,}\.eyJ[A-Za-z0-9_-]{10,}\.[A-Za-z0-9_-]{10,})`),
+		minEntropy: 4,
+	},
+	{
+		ID:         "url-credentials",
+		Name:       "Password in URL",
+		pattern:    regexp.MustCompile(`\b[a-z][a-z0-9+.-]*://[^/\s:@"']+:([^/\s:@"'$]{8,})@`),
+		minEntropy: 3,
+	},
+	{
+		ID:         "generic-secret",
+		Name:       "High entropy secret",
+		pattern:    regexp.MustCompile(`(?i)\b[a-z0-9_.-]*(?:password|passwd|secret|token|api_?key|access_?key|private_?key|credentials?)[a-z0-9_]*["']?\s*[:=]\s*["']?([A-Za-z0-9/+_=.~-]{20,})`),
+		minEntropy: 4,
+	},
+}
+
+// find returns the secrets matched by the rule with their offsets in data
+func (r Rule) find(data string) (secrets []string, offsets []int) {
+	for _, m := range r.pattern.FindAllStringSubmatchIndex(data, -1) {
+		start, end := m[0], m[1]
+		// The secret is the first group that matched, if any
+		for g := 2; g+1 < len(m); g += 2 {
+			if m[g] >= 0 {
+				start, end = m[g], m[g+1]
+				break
+			}
+		}
+		secret := data[start:end]
+		if r.minEntropy > 0 && entropy(secret) < r.minEntropy {
+			continue
+		}
+		secrets = append(secrets, secret)
+		offsets = append(offsets, start)
+	}
+	return secrets, offsets
+}
+
+// entropy returns the Shannon entropy of s in bits per character
+func entropy(s string) float64 {
+	if s == "" {
+		return 0
+	}
+	counts := make(map[rune]int)
+	n := 0
+	for _, c := range s {
+		counts[c]++
+		n++
+	}
+	var h float64
+	for _, count := range counts {
+		p := float64(count) / float64(n)
+		h -= p * math.Log2(p)
+	}
+	return h
+}
+
+// ignoredPaths lists directories whose files are documentation or test
+// fixtures, which ship sample keys that are not secrets
+var ignoredPaths = []string{
+	"/usr/share/doc/",
+	"/usr/share/man/",
+	"/usr/share/locale/",
+	"/usr/share/ca-certificates/",
+	"/etc/ssl/certs/",
+}
+
+// ignoredSegments lists directory names of test suites in any location
+var ignoredSegments = []string{
+	"/test/",
+	"/tests/",
+	"/testdata/",
+	"/__tests__/",
+	"/fixtures/",
+}
+
+// ignored reports whether the file at p is not scanned
+func ignored(p string) bool {
+	for _, prefix := range ignoredPaths {
+		if strings.HasPrefix(p, prefix) {
+			return true
+		}
+	}
+	for _, segment := range ignoredSegments {
+		if strings.Contains(p, segment) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,122 @@
+package secrets
+
+import (
+	"archive/tar"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/jtodic/docker-time-machine/pkg/inspect"
+)
+
+// maxFileSize bounds the files scanned; configuration files and key files
+// are small, larger files are data or binaries
+const maxFileSize = 1 << 20
+
+// Finding is a secret found in a file written by a layer
+type Finding struct {
+	Rule        string `json:"rule"`
+	Path        string `json:"path"`
+	Line        int    `json:"line"`
+	Secret      string `json:"secret"` // redacted
+	Layer       int    `json:"layer"`
+	Instruction string `json:"instruction,omitempty"`
+	// Hidden is set when a later layer deleted or overwrote the file. The
+	// secret is still shipped in the layer that wrote it.
+	Hidden bool `json:"hidden"`
+	// Fingerprint identifies the secret across files and versions without
+	// revealing it
+	Fingerprint string `json:"fingerprint"`
+}
+
+// Collector scans the files of one image for secrets while its layers are
+// walked. Pass Visit to the layer walk, then call Result.
+type Collector struct {
+	findings []Finding
+}
+
+// NewCollector returns an empty collector
+func NewCollector() *Collector {
+	return &Collector{}
+}
+
+// Visit scans a file written by a layer, including files that a later layer
+// deletes or overwrites
+func (c *Collector) Visit(layer int, p string, hdr *tar.Header, r io.Reader) error {
+	if hdr.Size == 0 || hdr.Size > maxFileSize || ignored(p) {
+		return nil
+	}
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	// Binary files are not scanned
+	if bytes.IndexByte(data[:min(len(data), 8000)], 0) >= 0 {
+		return nil
+	}
+
+	text := string(data)
+	seen := make(map[string]bool)
+	for _, rule := range Rules {
+		secrets, offsets := rule.find(text)
+		for i, secret := range secrets {
+			fingerprint := fingerprint(secret)
+			// A secret is reported once per file, by the first rule
+			if seen[fingerprint] {
+				continue
+			}
+			seen[fingerprint] = true
+			c.findings = append(c.findings, Finding{
+				Rule:        rule.ID,
+				Path:        p,
+				Line:        1 + strings.Count(text[:offsets[i]], "\n"),
+				Secret:      redact(secret),
+				Layer:       layer,
+				Fingerprint: fingerprint,
+			})
+		}
+	}
+	return nil
+}
+
+// Result returns the findings ordered by layer, path and line, with the
+// instruction of each layer and whether the file is still visible
+func (c *Collector) Result(img *inspect.Image) []Finding {
+	result := make([]Finding, len(c.findings))
+	for i, f := range c.findings {
+		f.Instruction = img.LayerLabel(f.Layer)
+		f.Hidden = !img.Visible(f.Path, f.Layer)
+		result[i] = f
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Layer != result[j].Layer {
+			return result[i].Layer < result[j].Layer
+		}
+		if result[i].Path != result[j].Path {
+			return result[i].Path < result[j].Path
+		}
+		return result[i].Line < result[j].Line
+	})
+	return result
+}
+
+// fingerprint returns a short hash of the secret
+func fingerprint(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:8])
+}
+
+// redact keeps enough of a secret to recognize it: the header line of a
+// private key, or the first four characters of a token
+func redact(secret string) string {
+	if line, _, ok := strings.Cut(secret, "\n"); ok {
+		return strings.TrimSpace(line)
+	}
+	if len(secret) <= 8 {
+		return strings.Repeat("*", len(secret))
+	}
+	return secret[:4] + strings.Repeat("*", min(len(secret)-4, 12))
+}
@@ -0,0 +1,222 @@
+package treemap
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/jtodic/docker-time-machine/pkg/inspect"
+	"github.com/jtodic/docker-time-machine/pkg/report"
+)
+
+// version is the tree of one version as embedded in the report
+type version struct {
+	Name string `json:"name"`
+	// Prev names the version the tree is compared with, empty for the oldest
+	Prev string `json:"prev,omitempty"`
+	Tree *Node  `json:"tree"`
+}
+
+// HTML returns a self-contained block for the HTML reports with a zoomable
+// treemap of every scanned version, or "" when no version was scanned.
+// Versions are ordered newest first; each is compared with the next older
+// scanned version.
+func HTML(versions []inspect.Version) string {
+	var trees []version
+	for i, v := range versions {
+		if v.Image == nil {
+			continue
+		}
+		tv := version{Name: v.Name}
+		var prev *inspect.Image
+		if j := report.NextOlder(versions, i, func(v inspect.Version) bool { return v.Image != nil }); j >= 0 {
+			prev = versions[j].Image
+			tv.Prev = versions[j].Name
+		}
+		tv.Tree = Build(v.Image, prev)
+		trees = append(trees, tv)
+	}
+	if len(trees) == 0 {
+		return ""
+	}
+
+	// json.Marshal escapes <, > and &, so file names cannot end the script
+	data, _ := json.Marshal(trees)
+	return fmt.Sprintf(treemapTemplate, string(data))
+}
+
+const treemapTemplate = `
+    <div class="chart-container">
+        <h2>🗺️ Image Contents</h2>
+        <div class="treemap-controls">
+            <label>Version <select id="treemapVersion"></select></label>
+            <label><input type="radio" name="treemapMode" value="size" checked> Size</label>
+            <label><input type="radio" name="treemapMode" value="diff"> Diff against previous</label>
+        </div>
+        <div class="treemap-path" id="treemapPath"></div>
+        <div class="treemap" id="treemap"></div>
+        <p class="note" id="treemapNote">Click a directory to zoom in, click the path above to zoom out. Small entries are merged.</p>
+    </div>
+    <style>
+        .treemap-controls { display: flex; gap: 20px; align-items: center; margin-bottom: 10px; }
+        .treemap-path { font-family: 'Monaco', 'Menlo', monospace; font-size: 0.9em; margin-bottom: 8px; }
+        .treemap-path span { cursor: pointer; color: #0366d6; }
+        .treemap-path span:hover { text-decoration: underline; }
+        .treemap { position: relative; width: 100%%; height: 520px; background: #f8f9fa; }
+        .treemap div { position: absolute; box-sizing: border-box; border: 1px solid white; overflow: hidden;
+            font-size: 11px; padding: 2px 4px; color: #222; white-space: nowrap; text-overflow: ellipsis; }
+        .treemap div.dir { cursor: zoom-in; font-weight: 600; }
+    </style>
+    <script>
+    (function() {
+        const versions = %s;
+        const palette = ['#8ecae6', '#ffb703', '#90be6d', '#f4a261', '#b8b8ff', '#e5989b', '#80ed99', '#ffd6a5', '#a0c4ff', '#cdb4db'];
+        const select = document.getElementById('treemapVersion');
+        const container = document.getElementById('treemap');
+        const pathBar = document.getElementById('treemapPath');
+        let current = 0;
+        let path = [];
+        let mode = 'size';
+
+        versions.forEach((v, i) => {
+            const option = document.createElement('option');
+            option.value = i;
+            option.textContent = v.name;
+            select.appendChild(option);
+        });
+        select.addEventListener('change', () => { current = +select.value; render(); });
+        document.querySelectorAll('input[name="treemapMode"]').forEach(input => {
+            input.addEventListener('change', () => { mode = input.value; render(); });
+        });
+
+        function formatBytes(n) {
+            const units = ['B', 'KB', 'MB', 'GB'];
+            let i = 0;
+            let value = Math.abs(n);
+            while (value >= 1024 && i < units.length - 1) { value /= 1024; i++; }
+            return (n < 0 ? '-' : '') + (i === 0 ? value : value.toFixed(1)) + ' ' + units[i];
+        }
+
+        // worst returns the largest aspect ratio of a row of areas laid out
+        // along a side
+        function worst(areas, start, end, sum, side) {
+            let max = 0, min = Infinity;
+            for (let i = start; i < end; i++) {
+                max = Math.max(max, areas[i]);
+                min = Math.min(min, areas[i]);
+            }
+            const s2 = side * side, sum2 = sum * sum;
+            return Math.max(s2 * max / sum2, sum2 / (s2 * min));
+        }
+
+        // squarify lays out values, largest first, in rows that keep the
+        // rectangles close to squares
+        function squarify(values, x, y, w, h) {
+            const total = values.reduce((a, b) => a + b, 0);
+            const rects = [];
+            if (total <= 0) return rects;
+            const areas = values.map(v => v * w * h / total);
+            let start = 0;
+            while (start < areas.length) {
+                const side = Math.min(w, h);
+                let end = start + 1;
+                let rowSum = areas[start];
+                let best = worst(areas, start, end, rowSum, side);
+                while (end < areas.length) {
+                    const next = worst(areas, start, end + 1, rowSum + areas[end], side);
+                    if (next > best) break;
+                    best = next;
+                    rowSum += areas[end];
+                    end++;
+                }
+                const thickness = side > 0 ? rowSum / side : 0;
+                let offset = 0;
+                for (let i = start; i < end; i++) {
+                    const length = thickness > 0 ? areas[i] / thickness : 0;
+                    rects[i] = w >= h
+                        ? { x: x, y: y + offset, w: thickness, h: length }
+                        : { x: x + offset, y: y, w: length, h: thickness };
+                    offset += length;
+                }
+                if (w >= h) { x += thickness; w -= thickness; } else { y += thickness; h -= thickness; }
+                start = end;
+            }
+            return rects;
+        }
+
+        function diffColor(node, hasPrev) {
+            if (!hasPrev) return '#d0d0d0';
+            if (node.p === undefined) return '#d62828';
+            const diff = node.s - node.p;
+            if (diff === 0) return '#d0d0d0';
+            const ratio = Math.min(1, Math.abs(diff) / Math.max(node.p, 1));
+            const alpha = 0.25 + 0.75 * ratio;
+            return diff > 0 ? 'rgba(247, 127, 0, ' + alpha + ')' : 'rgba(42, 157, 143, ' + alpha + ')';
+        }
+
+        function describe(node, fullPath, hasPrev) {
+            let text = fullPath + '\n' + formatBytes(node.s);
+            if (hasPrev) {
+                if (node.p === undefined) {
+                    text += ' (new)';
+                } else if (node.s !== node.p) {
+                    text += ' (' + (node.s > node.p ? '+' : '') + formatBytes(node.s - node.p) + ' from ' + formatBytes(node.p) + ')';
+                }
+            }
+            return text;
+        }
+
+        function render() {
+            const version = versions[current];
+            const hasPrev = !!version.prev;
+
+            // Follow the zoom path as far as it exists in this version
+            let node = version.tree;
+            const found = [];
+            for (const name of path) {
+                const child = (node.c || []).find(c => c.n === name && c.c);
+                if (!child) break;
+                node = child;
+                found.push(name);
+            }
+            path = found;
+
+            pathBar.innerHTML = '';
+            ['/'].concat(path).forEach((name, i) => {
+                const span = document.createElement('span');
+                span.textContent = i === 0 ? '/' : name + '/';
+                span.addEventListener('click', () => { path = path.slice(0, i); render(); });
+                pathBar.appendChild(span);
+            });
+            document.getElementById('treemapNote').textContent = mode === 'diff'
+                ? (hasPrev ? 'Compared with ' + version.prev + ': orange grew, green shrank, red is new, gray is unchanged.' : 'This is the oldest version, there is nothing to compare with.')
+                : 'Click a directory to zoom in, click the path above to zoom out. Small entries are merged.';
+
+            container.innerHTML = '';
+            const children = (node.c || []).filter(c => c.s > 0);
+            const rects = squarify(children.map(c => c.s), 0, 0, container.clientWidth, container.clientHeight);
+            const prefix = '/' + path.map(p => p + '/').join('');
+            children.forEach((child, i) => {
+                const r = rects[i];
+                const div = document.createElement('div');
+                div.style.left = r.x + 'px';
+                div.style.top = r.y + 'px';
+                div.style.width = r.w + 'px';
+                div.style.height = r.h + 'px';
+                div.style.background = mode === 'diff' ? diffColor(child, hasPrev) : palette[i %% palette.length];
+                div.title = describe(child, prefix + child.n, hasPrev);
+                if (r.w > 40 && r.h > 14) {
+                    div.textContent = child.n + (child.c ? '/' : '') + ' ' + formatBytes(child.s);
+                }
+                if (child.c) {
+                    div.className = 'dir';
+                    div.addEventListener('click', () => { path.push(child.n); render(); });
+                }
+                container.appendChild(div);
+            });
+        }
+
+        window.addEventListener('resize', render);
+        render();
+    })();
+    </script>
+`
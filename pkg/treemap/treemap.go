@@ -0,0 +1,167 @@
+package treemap
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/jtodic/docker-time-machine/pkg/inspect"
+)
+
+// Limits that keep the embedded tree small: entries below minShare of the
+// image size, and the entries of a directory beyond maxChildren, are merged
+// into one "smaller entries" node
+const (
+	minShare    = 0.001
+	maxChildren = 40
+)
+
+// Node is a directory or file of the treemap. The short JSON names keep the
+// report small.
+type Node struct {
+	Name string `json:"n"`
+	Size int64  `json:"s"`
+	// Prev is the size of the path in the previous version, nil when the
+	// path is new or there is no previous version
+	Prev     *int64  `json:"p,omitempty"`
+	Children []*Node `json:"c,omitempty"`
+}
+
+// Build returns the tree of the final filesystem of img. When prev is not
+// nil, every node carries its size in prev.
+func Build(img, prev *inspect.Image) *Node {
+	// A path can be a file in one layer and a directory in a later one; the
+	// directory wins
+	isDir := make(map[string]bool)
+	for p := range img.Files {
+		for dir := parentDir(p); dir != "/" && !isDir[dir]; dir = parentDir(dir) {
+			isDir[dir] = true
+		}
+	}
+
+	root := &Node{Name: "/"}
+	dirs := map[string]*Node{"/": root}
+	for p, f := range img.Files {
+		if isDir[p] {
+			continue
+		}
+		parent := root
+		dir := "/"
+		parts := strings.Split(strings.TrimPrefix(p, "/"), "/")
+		for _, part := range parts[:len(parts)-1] {
+			dir = joinPath(dir, part)
+			node, ok := dirs[dir]
+			if !ok {
+				node = &Node{Name: part}
+				dirs[dir] = node
+				parent.Children = append(parent.Children, node)
+			}
+			parent = node
+		}
+		parent.Children = append(parent.Children, &Node{Name: parts[len(parts)-1], Size: f.Size})
+	}
+	sum(root)
+
+	var prevSizes map[string]int64
+	if prev != nil {
+		prevSizes = pathSizes(prev)
+	}
+	prune(root, "/", int64(float64(root.Size)*minShare), prevSizes)
+	return root
+}
+
+// sum sets the size of every directory to the total of its children
+func sum(n *Node) int64 {
+	if len(n.Children) == 0 {
+		return n.Size
+	}
+	n.Size = 0
+	for _, c := range n.Children {
+		n.Size += sum(c)
+	}
+	return n.Size
+}
+
+// pathSizes returns the size of every file and directory of an image
+func pathSizes(img *inspect.Image) map[string]int64 {
+	sizes := map[string]int64{"/": 0}
+	for p, f := range img.Files {
+		sizes[p] += f.Size
+		for dir := parentDir(p); ; dir = parentDir(dir) {
+			sizes[dir] += f.Size
+			if dir == "/" {
+				break
+			}
+		}
+	}
+	return sizes
+}
+
+// prune sorts the children of n largest first, merges the small ones and
+// records the previous sizes
+func prune(n *Node, p string, minSize int64, prev map[string]int64) {
+	if prev != nil {
+		if size, ok := prev[p]; ok {
+			n.Prev = &size
+		}
+	}
+	if len(n.Children) == 0 {
+		return
+	}
+
+	sort.Slice(n.Children, func(i, j int) bool {
+		if n.Children[i].Size != n.Children[j].Size {
+			return n.Children[i].Size > n.Children[j].Size
+		}
+		return n.Children[i].Name < n.Children[j].Name
+	})
+
+	var kept, small []*Node
+	for _, c := range n.Children {
+		if len(kept) < maxChildren && c.Size >= minSize && c.Size > 0 {
+			kept = append(kept, c)
+		} else {
+			small = append(small, c)
+		}
+	}
+	// Merging a single entry saves nothing
+	if len(small) == 1 {
+		kept = append(kept, small...)
+		small = nil
+	}
+	for _, c := range kept {
+		prune(c, joinPath(p, c.Name), minSize, prev)
+	}
+
+	merged := &Node{}
+	var mergedPrev int64
+	for _, c := range small {
+		merged.Size += c.Size
+		if prev != nil {
+			mergedPrev += prev[joinPath(p, c.Name)]
+		}
+	}
+	if merged.Size > 0 {
+		merged.Name = fmt.Sprintf("(%d smaller entries)", len(small))
+		if prev != nil {
+			merged.Prev = &mergedPrev
+		}
+		kept = append(kept, merged)
+	}
+	n.Children = kept
+}
+
+func joinPath(dir, name string) string {
+	if dir == "/" {
+		return "/" + name
+	}
+	return dir + "/" + name
+}
+
+func parentDir(p string) string {
+	i := strings.LastIndex(p, "/")
+	if i <= 0 {
+		return "/"
+	}
+	return p[:i]
+}
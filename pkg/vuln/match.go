@@ -0,0 +1,353 @@
+package vuln
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/jtodic/docker-time-machine/pkg/deps"
+	"github.com/jtodic/docker-time-machine/pkg/packages"
+)
+
+// Finding is a vulnerability affecting a package installed in an image
+type Finding struct {
+	ID        string   `json:"id"` // CVE when the advisory has one
+	Aliases   []string `json:"aliases,omitempty"`
+	Summary   string   `json:"summary,omitempty"`
+	Severity  Severity `json:"severity"`
+	Package   string   `json:"package"`
+	Version   string   `json:"version"`
+	Ecosystem string   `json:"ecosystem"`
+	FixedIn   string   `json:"fixed_in,omitempty"`
+}
+
+// key identifies a finding across versions of an image
+func (f Finding) key() string {
+	return f.ID + "|" + f.Ecosystem + "/" + f.Package
+}
+
+// DB is an in-memory OSV advisory database indexed by ecosystem and package
+type DB struct {
+	entries map[string][]entry
+	count   int
+}
+
+// entry is one affected package of an advisory
+type entry struct {
+	advisory *advisory
+	affected *affected
+	release  string // e.g. 12 for Debian:12, empty when not release specific
+}
+
+func newDB() *DB {
+	return &DB{entries: make(map[string][]entry)}
+}
+
+// Advisories returns the number of advisories loaded
+func (db *DB) Advisories() int {
+	return db.count
+}
+
+// add parses one OSV JSON document
+func (db *DB) add(name string, data []byte) error {
+	adv := &advisory{}
+	if err := json.Unmarshal(data, adv); err != nil {
+		return fmt.Errorf("%s: %w", name, err)
+	}
+	if adv.ID == "" || adv.Withdrawn != "" {
+		return nil
+	}
+	db.count++
+	for i := range adv.Affected {
+		aff := &adv.Affected[i]
+		eco, release, _ := strings.Cut(aff.Package.Ecosystem, ":")
+		key := indexKey(eco, aff.Package.Name)
+		db.entries[key] = append(db.entries[key], entry{advisory: adv, affected: aff, release: release})
+	}
+	return nil
+}
+
+// indexKey normalizes names where the ecosystem treats them case-insensitively
+func indexKey(ecosystem, name string) string {
+	if ecosystem == "PyPI" {
+		name = strings.ToLower(strings.NewReplacer("_", "-", ".", "-").Replace(name))
+	}
+	return ecosystem + "|" + name
+}
+
+// target is a package to match, in OSV terms
+type target struct {
+	ecosystem string   // OSV ecosystem without release, e.g. Debian
+	release   string   // release as it appears in OSV ecosystems, e.g. v3.19
+	names     []string // binary and source package names
+	version   string
+	compare   compareFunc
+}
+
+// osTarget maps the distributions dtm reads package databases from to their
+// OSV ecosystems and release naming
+func osTarget(distro *packages.Distro, p packages.Package) (target, bool) {
+	t := target{names: []string{p.Name}, version: p.Version}
+	if p.Source != "" {
+		t.names = append(t.names, p.Source)
+	}
+	id, versionID := "", ""
+	if distro != nil {
+		id, versionID = distro.ID, distro.VersionID
+	}
+	major, _, _ := strings.Cut(versionID, ".")
+
+	switch p.Manager {
+	case packages.Deb:
+		t.compare = compareDebian
+		switch id {
+		case "ubuntu":
+			t.ecosystem, t.release = "Ubuntu", versionID
+		case "debian", "":
+			t.ecosystem, t.release = "Debian", major
+		default:
+			return t, false
+		}
+	case packages.Apk:
+		t.compare = compareAlpine
+		t.ecosystem = "Alpine"
+		if parts := strings.SplitN(versionID, ".", 3); len(parts) >= 2 {
+			t.release = "v" + parts[0] + "." + parts[1]
+		}
+	case packages.Rpm:
+		t.compare = compareRPM
+		switch id {
+		case "rhel":
+			t.ecosystem, t.release = "Red Hat", "enterprise_linux:"+major
+		case "rocky":
+			t.ecosystem, t.release = "Rocky Linux", major
+		case "almalinux":
+			t.ecosystem, t.release = "AlmaLinux", major
+		case "opensuse-leap", "opensuse-tumbleweed", "sles":
+			t.ecosystem = "SUSE"
+		default:
+			return t, false
+		}
+	default:
+		return t, false
+	}
+	return t, true
+}
+
+// dependencyTarget maps a language dependency to its OSV ecosystem
+func dependencyTarget(d deps.Dependency) (target, bool) {
+	t := target{names: []string{d.Name}, version: d.Version}
+	switch d.Ecosystem {
+	case deps.Node:
+		t.ecosystem, t.compare = "npm", compareSemver
+	case deps.Python:
+		t.ecosystem, t.compare = "PyPI", comparePython
+	case deps.Go:
+		t.ecosystem, t.compare = "Go", compareSemver
+	default:
+		return t, false
+	}
+	return t, d.Version != ""
+}
+
+// Match returns the vulnerabilities affecting the OS packages and language
+// dependencies of an image, most severe first. A vulnerability reported by
+// several advisories (e.g. a distribution advisory and a CVE record) is
+// listed once per package.
+func (db *DB) Match(distro *packages.Distro, pkgs []packages.Package, dependencies []deps.Dependency) []Finding {
+	var targets []target
+	for _, p := range pkgs {
+		if t, ok := osTarget(distro, p); ok {
+			targets = append(targets, t)
+		}
+	}
+	for _, d := range dependencies {
+		if t, ok := dependencyTarget(d); ok {
+			targets = append(targets, t)
+		}
+	}
+
+	findings := []Finding{}
+	seen := make(map[string]int) // finding key -> index in findings
+	for _, t := range targets {
+		for _, name := range t.names {
+			for _, e := range db.entries[indexKey(t.ecosystem, name)] {
+				if !releaseMatches(e.release, t.release) {
+					continue
+				}
+				fixed, ok := e.affects(t.version, t.compare)
+				if !ok {
+					continue
+				}
+				f := Finding{
+					ID:        canonicalID(e.advisory),
+					Aliases:   append([]string(nil), e.advisory.Aliases...),
+					Summary:   e.advisory.Summary,
+					Severity:  severityOf(e.advisory, e.affected),
+					Package:   t.names[0],
+					Version:   t.version,
+					Ecosystem: t.ecosystem,
+					FixedIn:   fixed,
+				}
+				if f.ID != e.advisory.ID {
+					f.Aliases = append([]string{e.advisory.ID}, without(e.advisory.Aliases, f.ID)...)
+				}
+				if i, ok := seen[f.key()]; ok {
+					findings[i].merge(f)
+					continue
+				}
+				seen[f.key()] = len(findings)
+				findings = append(findings, f)
+			}
+		}
+	}
+
+	sortFindings(findings)
+	return findings
+}
+
+// merge completes a finding with what another advisory for the same
+// vulnerability knows
+func (f *Finding) merge(other Finding) {
+	if f.Severity == Unknown {
+		f.Severity = other.Severity
+	}
+	if f.Summary == "" {
+		f.Summary = other.Summary
+	}
+	if f.FixedIn == "" {
+		f.FixedIn = other.FixedIn
+	}
+	for _, alias := range other.Aliases {
+		if alias != f.ID && !contains(f.Aliases, alias) {
+			f.Aliases = append(f.Aliases, alias)
+		}
+	}
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+// releaseMatches reports whether an advisory for an ecosystem release, e.g.
+// "22.04:LTS" or "enterprise_linux:9::appstream", applies to the image
+func releaseMatches(advisory, image string) bool {
+	if advisory == "" || image == "" {
+		return true
+	}
+	return advisory == image || strings.HasPrefix(advisory, image+":")
+}
+
+// canonicalID prefers the CVE identifier, so the same vulnerability published
+// by several databases is recognized
+func canonicalID(adv *advisory) string {
+	if strings.HasPrefix(adv.ID, "CVE-") {
+		return adv.ID
+	}
+	for _, alias := range adv.Aliases {
+		if strings.HasPrefix(alias, "CVE-") {
+			return alias
+		}
+	}
+	return adv.ID
+}
+
+func without(list []string, s string) []string {
+	var result []string
+	for _, item := range list {
+		if item != s {
+			result = append(result, item)
+		}
+	}
+	return result
+}
+
+// affects evaluates the affected versions and ranges of an entry. It returns
+// the first fixed version above the installed one, if any.
+func (e entry) affects(version string, compare compareFunc) (string, bool) {
+	for _, v := range e.affected.Versions {
+		if v == version {
+			return "", true
+		}
+	}
+
+	for _, r := range e.affected.Ranges {
+		if r.Type != "ECOSYSTEM" && r.Type != "SEMVER" {
+			continue
+		}
+		if fixed, ok := inRange(r.Events, version, compare); ok {
+			return fixed, true
+		}
+	}
+	return "", false
+}
+
+// inRange walks the events of a range in version order: introduced opens an
+// affected interval, fixed, limit and last_affected close it
+func inRange(events []event, version string, compare compareFunc) (string, bool) {
+	eventVersion := func(e event) string {
+		for _, v := range []string{e.Introduced, e.Fixed, e.LastAffected, e.Limit} {
+			if v != "" {
+				return v
+			}
+		}
+		return ""
+	}
+	sorted := append([]event(nil), events...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		a, b := eventVersion(sorted[i]), eventVersion(sorted[j])
+		if a == "0" || b == "0" {
+			return a == "0" && b != "0"
+		}
+		return compare(a, b) < 0
+	})
+
+	affected := false
+	fixed := ""
+	for _, e := range sorted {
+		switch {
+		case e.Introduced != "":
+			if e.Introduced == "0" || compare(version, e.Introduced) >= 0 {
+				affected = true
+			}
+		case e.Fixed != "":
+			if compare(version, e.Fixed) >= 0 {
+				affected = false
+			} else if affected && fixed == "" {
+				fixed = e.Fixed
+			}
+		case e.LastAffected != "":
+			if compare(version, e.LastAffected) > 0 {
+				affected = false
+			}
+		case e.Limit != "":
+			if compare(version, e.Limit) >= 0 {
+				affected = false
+			}
+		}
+	}
+	if !affected {
+		return "", false
+	}
+	return fixed, true
+}
+
+// sortFindings orders findings by severity, then by identifier and package
+func sortFindings(findings []Finding) {
+	sort.SliceStable(findings, func(i, j int) bool {
+		a, b := findings[i], findings[j]
+		if a.Severity.rank() != b.Severity.rank() {
+			return a.Severity.rank() < b.Severity.rank()
+		}
+		if a.ID != b.ID {
+			return a.ID > b.ID // newer CVEs first
+		}
+		return a.Package < b.Package
+	})
+}
@@ -0,0 +1,68 @@
+package vuln
+
+import "testing"
+
+func TestInRange(t *testing.T) {
+	tests := []struct {
+		name      string
+		events    []event
+		version   string
+		affected  bool
+		wantFixed string
+	}{
+		{"introduced zero", []event{{Introduced: "0"}, {Fixed: "1.2.0"}}, "1.1.0", true, "1.2.0"},
+		{"fixed version", []event{{Introduced: "0"}, {Fixed: "1.2.0"}}, "1.2.0", false, ""},
+		{"before introduced", []event{{Introduced: "1.0.0"}, {Fixed: "1.2.0"}}, "0.9.0", false, ""},
+		{"last affected", []event{{Introduced: "1.0.0"}, {LastAffected: "1.4.0"}}, "1.4.0", true, ""},
+		{"after last affected", []event{{Introduced: "1.0.0"}, {LastAffected: "1.4.0"}}, "1.4.1", false, ""},
+		{"below limit", []event{{Introduced: "1.0.0"}, {Limit: "2.0.0"}}, "1.9.9", true, ""},
+		{"at limit", []event{{Introduced: "1.0.0"}, {Limit: "2.0.0"}}, "2.0.0", false, ""},
+		{"open ended", []event{{Introduced: "1.0.0"}}, "9.0.0", true, ""},
+		{
+			"second interval",
+			[]event{{Introduced: "0"}, {Fixed: "1.0.0"}, {Introduced: "2.0.0"}, {Fixed: "2.1.0"}},
+			"2.0.5", true, "2.1.0",
+		},
+		{
+			"between intervals",
+			[]event{{Introduced: "0"}, {Fixed: "1.0.0"}, {Introduced: "2.0.0"}, {Fixed: "2.1.0"}},
+			"1.5.0", false, "",
+		},
+		{"unsorted events", []event{{Fixed: "2.1.0"}, {Introduced: "2.0.0"}}, "2.0.5", true, "2.1.0"},
+		{"pre-release before fix", []event{{Introduced: "0"}, {Fixed: "2.0.0"}}, "2.0.0-rc.1", true, "2.0.0"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fixed, ok := inRange(tt.events, tt.version, compareSemver)
+			if ok != tt.affected || fixed != tt.wantFixed {
+				t.Errorf("inRange(%s) = %q, %v, want %q, %v", tt.version, fixed, ok, tt.wantFixed, tt.affected)
+			}
+		})
+	}
+}
+
+func TestAffects(t *testing.T) {
+	e := entry{affected: &affected{
+		Versions: []string{"1:2.36-9"},
+		Ranges: []versionRange{
+			{Type: "GIT", Events: []event{{Introduced: "0"}}},
+			{Type: "ECOSYSTEM", Events: []event{{Introduced: "0"}, {Fixed: "2.36-9+deb12u3"}}},
+		},
+	}}
+	tests := []struct {
+		version   string
+		affected  bool
+		wantFixed string
+	}{
+		{"1:2.36-9", true, ""},
+		{"2.36-9", true, "2.36-9+deb12u3"},
+		{"2.36-9+deb12u3", false, ""},
+		{"2.36-10", false, ""},
+	}
+	for _, tt := range tests {
+		fixed, ok := e.affects(tt.version, compareDebian)
+		if ok != tt.affected || fixed != tt.wantFixed {
+			t.Errorf("affects(%s) = %q, %v, want %q, %v", tt.version, fixed, ok, tt.wantFixed, tt.affected)
+		}
+	}
+}
@@ -0,0 +1,175 @@
+package vuln
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// advisory is the subset of the OSV schema used for matching, see
+// https://ossf.github.io/osv-schema/
+type advisory struct {
+	ID               string           `json:"id"`
+	Aliases          []string         `json:"aliases"`
+	Summary          string           `json:"summary"`
+	Withdrawn        string           `json:"withdrawn"`
+	Severity         []osvSeverity    `json:"severity"`
+	Affected         []affected       `json:"affected"`
+	DatabaseSpecific *specificDetails `json:"database_specific"`
+}
+
+type osvSeverity struct {
+	Type  string `json:"type"`
+	Score string `json:"score"`
+}
+
+type affected struct {
+	Package struct {
+		Ecosystem string `json:"ecosystem"`
+		Name      string `json:"name"`
+	} `json:"package"`
+	Severity          []osvSeverity    `json:"severity"`
+	Ranges            []versionRange   `json:"ranges"`
+	Versions          []string         `json:"versions"`
+	EcosystemSpecific *specificDetails `json:"ecosystem_specific"`
+	DatabaseSpecific  *specificDetails `json:"database_specific"`
+}
+
+// specificDetails holds the severity label some databases add, e.g. GitHub's
+// "MODERATE" or Ubuntu's "medium"
+type specificDetails struct {
+	Severity json.RawMessage `json:"severity"`
+}
+
+type versionRange struct {
+	Type   string  `json:"type"` // SEMVER, ECOSYSTEM or GIT
+	Events []event `json:"events"`
+}
+
+type event struct {
+	Introduced   string `json:"introduced,omitempty"`
+	Fixed        string `json:"fixed,omitempty"`
+	LastAffected string `json:"last_affected,omitempty"`
+	Limit        string `json:"limit,omitempty"`
+}
+
+// Load reads an OSV advisory database from a directory of JSON files, as
+// extracted from the osv.dev exports, or from a .zip, .tar or .tar.gz
+// archive of them. Nothing is fetched over the network.
+func Load(path string) (*DB, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open vulnerability database: %w", err)
+	}
+
+	db := newDB()
+	switch {
+	case info.IsDir():
+		err = loadDir(db, path)
+	case strings.HasSuffix(path, ".zip"):
+		err = loadZip(db, path)
+	case strings.HasSuffix(path, ".tar"), strings.HasSuffix(path, ".tar.gz"), strings.HasSuffix(path, ".tgz"):
+		err = loadTar(db, path)
+	case strings.HasSuffix(path, ".json"):
+		err = loadFile(db, path)
+	default:
+		return nil, fmt.Errorf("unsupported vulnerability database %s: use a directory, .zip, .tar.gz or .json file", path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load vulnerability database: %w", err)
+	}
+	return db, nil
+}
+
+func loadDir(db *DB, dir string) error {
+	return filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || !strings.HasSuffix(p, ".json") {
+			return nil
+		}
+		return loadFile(db, p)
+	})
+}
+
+func loadFile(db *DB, p string) error {
+	data, err := os.ReadFile(p)
+	if err != nil {
+		return err
+	}
+	return db.add(p, data)
+}
+
+func loadZip(db *DB, p string) error {
+	zr, err := zip.OpenReader(p)
+	if err != nil {
+		return err
+	}
+	defer zr.Close()
+
+	for _, f := range zr.File {
+		if f.FileInfo().IsDir() || !strings.HasSuffix(f.Name, ".json") {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return err
+		}
+		data, err := io.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			return err
+		}
+		if err := db.add(f.Name, data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func loadTar(db *DB, p string) error {
+	f, err := os.Open(p)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	var r io.Reader = f
+	if !strings.HasSuffix(p, ".tar") {
+		gz, err := gzip.NewReader(f)
+		if err != nil {
+			return err
+		}
+		defer gz.Close()
+		r = gz
+	}
+
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if hdr.Typeflag != tar.TypeReg || !strings.HasSuffix(hdr.Name, ".json") {
+			continue
+		}
+		data, err := io.ReadAll(tr)
+		if err != nil {
+			return err
+		}
+		if err := db.add(hdr.Name, data); err != nil {
+			return err
+		}
+	}
+}
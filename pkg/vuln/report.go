@@ -0,0 +1,196 @@
+package vuln
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"github.com/jtodic/docker-time-machine/pkg/report"
+)
+
+// Version is the vulnerability scan of an analyzed commit or tag
+type Version struct {
+	Name     string
+	Findings []Finding
+	Scanned  bool // false when the image could not be inspected
+}
+
+// VersionDiff lists the vulnerabilities introduced and fixed from one
+// analyzed version to the next
+type VersionDiff struct {
+	From       string    `json:"from"`
+	To         string    `json:"to"`
+	Introduced []Finding `json:"introduced"`
+	Fixed      []Finding `json:"fixed"`
+}
+
+// Counts returns the number of findings per severity
+func Counts(findings []Finding) map[Severity]int {
+	counts := make(map[Severity]int, len(Severities))
+	for _, f := range findings {
+		counts[f.Severity]++
+	}
+	return counts
+}
+
+// Compare returns the vulnerabilities introduced and fixed from older to
+// newer, most severe first
+func Compare(from, to string, older, newer []Finding) VersionDiff {
+	diff := VersionDiff{From: from, To: to, Introduced: []Finding{}, Fixed: []Finding{}}
+
+	before := make(map[string]bool, len(older))
+	for _, f := range older {
+		before[f.key()] = true
+	}
+	after := make(map[string]bool, len(newer))
+	for _, f := range newer {
+		after[f.key()] = true
+		if !before[f.key()] {
+			diff.Introduced = append(diff.Introduced, f)
+		}
+	}
+	for _, f := range older {
+		if !after[f.key()] {
+			diff.Fixed = append(diff.Fixed, f)
+		}
+	}
+	return diff
+}
+
+// CompareVersions compares each scanned version with the next older scanned
+// one. Versions are ordered newest first.
+func CompareVersions(versions []Version) []VersionDiff {
+	return report.ConsecutivePairs(versions, func(v Version) bool { return v.Scanned },
+		func(older, newer Version) VersionDiff {
+			return Compare(older.Name, newer.Name, older.Findings, newer.Findings)
+		})
+}
+
+// Sections returns the vulnerability counts per version and the
+// vulnerabilities introduced and fixed between consecutive versions
+func Sections(versions []Version, diffs []VersionDiff) []report.Section {
+	return []report.Section{CountSection(versions), DiffSection(diffs)}
+}
+
+// CountSection lists the vulnerability counts by severity per version
+func CountSection(versions []Version) report.Section {
+	section := report.Section{
+		Title:   "🛡️ Vulnerabilities",
+		Note:    "Matched offline against the local advisory database",
+		Headers: []string{"Version", "Critical", "High", "Medium", "Low", "Unknown", "Total"},
+	}
+	for _, v := range versions {
+		if !v.Scanned {
+			continue
+		}
+		counts := Counts(v.Findings)
+		row := []string{v.Name}
+		for _, sev := range Severities {
+			row = append(row, strconv.Itoa(counts[sev]))
+		}
+		row = append(row, strconv.Itoa(len(v.Findings)))
+		section.Rows = append(section.Rows, row)
+	}
+	return section
+}
+
+// DiffSection lists the vulnerabilities introduced and fixed between
+// consecutive versions, naming the version where each one appeared or went
+// away
+func DiffSection(diffs []VersionDiff) report.Section {
+	section := report.Section{
+		Title:   "🛡️ Vulnerability Changes",
+		Headers: []string{"Version", "Change", "Vulnerability", "Severity", "Package", "Fixed In"},
+	}
+
+	for _, d := range diffs {
+		version := fmt.Sprintf("%s → %s", d.From, d.To)
+		rows := 0
+		total := len(d.Introduced) + len(d.Fixed)
+		for _, change := range []struct {
+			kind     string
+			findings []Finding
+		}{{"introduced", d.Introduced}, {"fixed", d.Fixed}} {
+			for _, f := range change.findings {
+				if rows == report.MaxRows {
+					break
+				}
+				section.Rows = append(section.Rows, []string{
+					version,
+					change.kind,
+					f.ID,
+					string(f.Severity),
+					fmt.Sprintf("%s %s (%s)", f.Package, f.Version, f.Ecosystem),
+					report.OrDash(f.FixedIn),
+				})
+				rows++
+			}
+		}
+		if rows < total {
+			section.Rows = append(section.Rows, []string{
+				version, fmt.Sprintf("... %d more", total-rows), "", "", "", "",
+			})
+		}
+	}
+
+	return section
+}
+
+// maxChartIDs limits the vulnerability identifiers listed per chart tooltip
+const maxChartIDs = 5
+
+// ChartData returns the per-version severity counts for the HTML charts as
+// JSON, aligned with the chart labels, or null when no version was scanned.
+// Each version also lists the vulnerabilities introduced and fixed since the
+// previous one.
+func ChartData(versions []Version, diffs []VersionDiff) string {
+	data := struct {
+		Counts     map[Severity][]*int `json:"counts"`
+		Introduced [][]string          `json:"introduced"`
+		Fixed      [][]string          `json:"fixed"`
+	}{Counts: make(map[Severity][]*int)}
+
+	byVersion := make(map[string]VersionDiff, len(diffs))
+	for _, d := range diffs {
+		byVersion[d.To] = d
+	}
+
+	found := false
+	for _, v := range versions {
+		counts := Counts(v.Findings)
+		for _, sev := range Severities {
+			var n *int
+			if v.Scanned {
+				count := counts[sev]
+				n = &count
+			}
+			data.Counts[sev] = append(data.Counts[sev], n)
+		}
+		found = found || v.Scanned
+
+		d := byVersion[v.Name]
+		data.Introduced = append(data.Introduced, chartIDs(d.Introduced))
+		data.Fixed = append(data.Fixed, chartIDs(d.Fixed))
+	}
+	if !found {
+		return "null"
+	}
+	out, _ := json.Marshal(data)
+	return string(out)
+}
+
+func chartIDs(findings []Finding) []string {
+	ids := []string{}
+	seen := make(map[string]bool)
+	for _, f := range findings {
+		if !seen[f.ID] {
+			seen[f.ID] = true
+			ids = append(ids, f.ID+" ("+string(f.Severity)+")")
+		}
+	}
+	if len(ids) > maxChartIDs {
+		more := len(ids) - maxChartIDs
+		ids = append(ids[:maxChartIDs], fmt.Sprintf("+%d more", more))
+	}
+	return ids
+}
@@ -0,0 +1,170 @@
+package vuln
+
+import (
+	"encoding/json"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Severity is the qualitative rating of a vulnerability
+type Severity string
+
+const (
+	Critical Severity = "CRITICAL"
+	High     Severity = "HIGH"
+	Medium   Severity = "MEDIUM"
+	Low      Severity = "LOW"
+	Unknown  Severity = "UNKNOWN"
+)
+
+// Severities lists the ratings from most to least severe
+var Severities = []Severity{Critical, High, Medium, Low, Unknown}
+
+// rank orders severities, most severe first
+func (s Severity) rank() int {
+	for i, sev := range Severities {
+		if s == sev {
+			return i
+		}
+	}
+	return len(Severities)
+}
+
+// severityOf rates an affected package of an advisory. A CVSS v3 vector is
+// preferred; otherwise the label of the source database is used.
+func severityOf(adv *advisory, aff *affected) Severity {
+	for _, list := range [][]osvSeverity{aff.Severity, adv.Severity} {
+		for _, s := range list {
+			if !strings.HasPrefix(s.Type, "CVSS_V3") {
+				continue
+			}
+			if score, ok := cvss3Score(s.Score); ok {
+				return ratingOf(score)
+			}
+		}
+	}
+
+	for _, details := range []*specificDetails{aff.EcosystemSpecific, aff.DatabaseSpecific, adv.DatabaseSpecific} {
+		if details == nil {
+			continue
+		}
+		if sev := parseLabel(details.Severity); sev != Unknown {
+			return sev
+		}
+	}
+	for _, list := range [][]osvSeverity{aff.Severity, adv.Severity} {
+		for _, s := range list {
+			// Ubuntu rates with its own labels, others may give a plain score
+			if score, err := strconv.ParseFloat(s.Score, 64); err == nil {
+				return ratingOf(score)
+			}
+			if sev := label(s.Score); sev != Unknown {
+				return sev
+			}
+		}
+	}
+	return Unknown
+}
+
+// parseLabel reads a severity label, which databases give as a string
+func parseLabel(raw json.RawMessage) Severity {
+	var s string
+	if len(raw) == 0 || json.Unmarshal(raw, &s) != nil {
+		return Unknown
+	}
+	return label(s)
+}
+
+func label(s string) Severity {
+	switch strings.ToUpper(strings.TrimSpace(s)) {
+	case "CRITICAL":
+		return Critical
+	case "HIGH", "IMPORTANT":
+		return High
+	case "MEDIUM", "MODERATE":
+		return Medium
+	case "LOW", "NEGLIGIBLE":
+		return Low
+	}
+	return Unknown
+}
+
+// ratingOf maps a CVSS score to its qualitative rating
+func ratingOf(score float64) Severity {
+	switch {
+	case score >= 9:
+		return Critical
+	case score >= 7:
+		return High
+	case score >= 4:
+		return Medium
+	case score > 0:
+		return Low
+	}
+	return Unknown
+}
+
+// cvss3Score computes the base score of a CVSS v3.x vector, e.g.
+// CVSS:3.1/AV:N/AC:L/PR:N/UI:N/S:U/C:H/I:H/A:H
+func cvss3Score(vector string) (float64, bool) {
+	metrics := make(map[string]string)
+	for _, part := range strings.Split(vector, "/") {
+		if key, value, ok := strings.Cut(part, ":"); ok {
+			metrics[key] = value
+		}
+	}
+
+	weights := map[string]map[string]float64{
+		"AV": {"N": 0.85, "A": 0.62, "L": 0.55, "P": 0.2},
+		"AC": {"L": 0.77, "H": 0.44},
+		"UI": {"N": 0.85, "R": 0.62},
+		"C":  {"H": 0.56, "L": 0.22, "N": 0},
+		"I":  {"H": 0.56, "L": 0.22, "N": 0},
+		"A":  {"H": 0.56, "L": 0.22, "N": 0},
+	}
+	value := make(map[string]float64)
+	for metric, options := range weights {
+		w, ok := options[metrics[metric]]
+		if !ok {
+			return 0, false
+		}
+		value[metric] = w
+	}
+
+	changed := metrics["S"] == "C"
+	if !changed && metrics["S"] != "U" {
+		return 0, false
+	}
+	privileges := map[string]float64{"N": 0.85, "L": 0.62, "H": 0.27}
+	if changed {
+		privileges = map[string]float64{"N": 0.85, "L": 0.68, "H": 0.5}
+	}
+	pr, ok := privileges[metrics["PR"]]
+	if !ok {
+		return 0, false
+	}
+
+	iss := 1 - (1-value["C"])*(1-value["I"])*(1-value["A"])
+	impact := 6.42 * iss
+	if changed {
+		impact = 7.52*(iss-0.029) - 3.25*math.Pow(iss-0.02, 15)
+	}
+	if impact <= 0 {
+		return 0, true
+	}
+	exploitability := 8.22 * value["AV"] * value["AC"] * pr * value["UI"]
+	if changed {
+		return roundUp(math.Min(1.08*(impact+exploitability), 10)), true
+	}
+	return roundUp(math.Min(impact+exploitability, 10)), true
+}
+
+// roundUp rounds up to one decimal as defined by CVSS v3.1
+func roundUp(x float64) float64 {
+	n := int64(math.Round(x * 100000))
+	if n%10000 == 0 {
+		return float64(n) / 100000
+	}
+	return float64(n/10000+1) / 10
+}
@@ -0,0 +1,360 @@
+package vuln
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// compareFunc orders two versions of one ecosystem: negative when a < b,
+// zero when equal and positive when a > b
+type compareFunc func(a, b string) int
+
+// compareDebian implements the dpkg version ordering:
+// [epoch:]upstream[-revision], where ~ sorts before everything, even the end
+// of the string
+func compareDebian(a, b string) int {
+	ea, ua, ra := splitVersion(a)
+	eb, ub, rb := splitVersion(b)
+	if ea != eb {
+		return sign(ea - eb)
+	}
+	if c := verrevcmp(ua, ub); c != 0 {
+		return c
+	}
+	return verrevcmp(ra, rb)
+}
+
+// splitVersion splits [epoch:]version[-release] as used by dpkg and rpm
+func splitVersion(v string) (epoch int, version, release string) {
+	if e, rest, ok := strings.Cut(v, ":"); ok {
+		if n, err := strconv.Atoi(e); err == nil {
+			epoch, v = n, rest
+		}
+	}
+	if idx := strings.LastIndex(v, "-"); idx >= 0 {
+		return epoch, v[:idx], v[idx+1:]
+	}
+	return epoch, v, ""
+}
+
+// debianOrder is the weight of a character in the non-digit parts of a
+// dpkg version
+func debianOrder(s string, i int) int {
+	if i >= len(s) {
+		return 0
+	}
+	c := s[i]
+	switch {
+	case isDigit(c):
+		return 0
+	case isLetter(c):
+		return int(c)
+	case c == '~':
+		return -1
+	}
+	return int(c) + 256
+}
+
+func verrevcmp(a, b string) int {
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		for (i < len(a) && !isDigit(a[i])) || (j < len(b) && !isDigit(b[j])) {
+			ac, bc := debianOrder(a, i), debianOrder(b, j)
+			if ac != bc {
+				return sign(ac - bc)
+			}
+			i++
+			j++
+		}
+		for i < len(a) && a[i] == '0' {
+			i++
+		}
+		for j < len(b) && b[j] == '0' {
+			j++
+		}
+		firstDiff := 0
+		for i < len(a) && isDigit(a[i]) && j < len(b) && isDigit(b[j]) {
+			if firstDiff == 0 {
+				firstDiff = int(a[i]) - int(b[j])
+			}
+			i++
+			j++
+		}
+		if i < len(a) && isDigit(a[i]) {
+			return 1
+		}
+		if j < len(b) && isDigit(b[j]) {
+			return -1
+		}
+		if firstDiff != 0 {
+			return sign(firstDiff)
+		}
+	}
+	return 0
+}
+
+var apkPreRelease = regexp.MustCompile(`_(alpha|beta|pre|rc)`)
+
+// compareAlpine orders apk versions, e.g. 3.1.4-r5. Pre-release suffixes sort
+// before the release, like ~ in dpkg.
+func compareAlpine(a, b string) int {
+	return compareDebian(apkPreRelease.ReplaceAllString(a, "~$1"), apkPreRelease.ReplaceAllString(b, "~$1"))
+}
+
+// compareRPM implements the rpm ordering of [epoch:]version-release
+func compareRPM(a, b string) int {
+	ea, va, ra := splitVersion(a)
+	eb, vb, rb := splitVersion(b)
+	if ea != eb {
+		return sign(ea - eb)
+	}
+	if c := rpmvercmp(va, vb); c != 0 {
+		return c
+	}
+	return rpmvercmp(ra, rb)
+}
+
+// rpmvercmp compares alternating numeric and alphabetic segments; ~ sorts
+// before and ^ after the end of the version
+func rpmvercmp(a, b string) int {
+	if a == b {
+		return 0
+	}
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		for i < len(a) && !isAlnum(a[i]) && a[i] != '~' && a[i] != '^' {
+			i++
+		}
+		for j < len(b) && !isAlnum(b[j]) && b[j] != '~' && b[j] != '^' {
+			j++
+		}
+
+		atTilde, btTilde := i < len(a) && a[i] == '~', j < len(b) && b[j] == '~'
+		if atTilde || btTilde {
+			if !atTilde {
+				return 1
+			}
+			if !btTilde {
+				return -1
+			}
+			i++
+			j++
+			continue
+		}
+
+		atCaret, btCaret := i < len(a) && a[i] == '^', j < len(b) && b[j] == '^'
+		if atCaret || btCaret {
+			if i >= len(a) {
+				return -1
+			}
+			if j >= len(b) {
+				return 1
+			}
+			if !atCaret {
+				return 1
+			}
+			if !btCaret {
+				return -1
+			}
+			i++
+			j++
+			continue
+		}
+
+		if i >= len(a) || j >= len(b) {
+			break
+		}
+
+		numeric := isDigit(a[i])
+		segA, segB := segment(a, &i, numeric), segment(b, &j, numeric)
+		if segB == "" {
+			// A numeric segment is newer than an alphabetic one
+			if numeric {
+				return 1
+			}
+			return -1
+		}
+		if numeric {
+			segA, segB = strings.TrimLeft(segA, "0"), strings.TrimLeft(segB, "0")
+			if len(segA) != len(segB) {
+				return sign(len(segA) - len(segB))
+			}
+		}
+		if c := strings.Compare(segA, segB); c != 0 {
+			return c
+		}
+	}
+
+	switch {
+	case i >= len(a) && j >= len(b):
+		return 0
+	case i < len(a):
+		return 1
+	}
+	return -1
+}
+
+// segment consumes the run of digits, or of letters, starting at *i
+func segment(s string, i *int, numeric bool) string {
+	start := *i
+	for *i < len(s) && ((numeric && isDigit(s[*i])) || (!numeric && isLetter(s[*i]))) {
+		*i++
+	}
+	return s[start:*i]
+}
+
+// compareSemver orders semantic versions as used by npm and Go modules.
+// Build metadata is ignored and a pre-release sorts before its release.
+func compareSemver(a, b string) int {
+	a, _, _ = strings.Cut(strings.TrimPrefix(a, "v"), "+")
+	b, _, _ = strings.Cut(strings.TrimPrefix(b, "v"), "+")
+	mainA, preA, _ := strings.Cut(a, "-")
+	mainB, preB, _ := strings.Cut(b, "-")
+
+	if c := compareIdentifiers(strings.Split(mainA, "."), strings.Split(mainB, "."), true); c != 0 {
+		return c
+	}
+	switch {
+	case preA == preB:
+		return 0
+	case preA == "":
+		return 1
+	case preB == "":
+		return -1
+	}
+	return compareIdentifiers(strings.Split(preA, "."), strings.Split(preB, "."), false)
+}
+
+// compareIdentifiers compares dot separated identifiers: numbers numerically
+// and before words. Missing release numbers count as 0; a shorter pre-release
+// sorts first.
+func compareIdentifiers(a, b []string, release bool) int {
+	for k := 0; k < len(a) || k < len(b); k++ {
+		if k >= len(a) || k >= len(b) {
+			if !release {
+				return sign(len(a) - len(b))
+			}
+			x, y := "0", "0"
+			if k < len(a) {
+				x = a[k]
+			}
+			if k < len(b) {
+				y = b[k]
+			}
+			if c := compareIdentifier(x, y); c != 0 {
+				return c
+			}
+			continue
+		}
+		if c := compareIdentifier(a[k], b[k]); c != 0 {
+			return c
+		}
+	}
+	return 0
+}
+
+func compareIdentifier(a, b string) int {
+	na, errA := strconv.ParseUint(a, 10, 64)
+	nb, errB := strconv.ParseUint(b, 10, 64)
+	switch {
+	case errA == nil && errB == nil:
+		if na == nb {
+			return 0
+		}
+		if na < nb {
+			return -1
+		}
+		return 1
+	case errA == nil:
+		return -1
+	case errB == nil:
+		return 1
+	}
+	return strings.Compare(a, b)
+}
+
+var pep440 = regexp.MustCompile(`^v?(?:(\d+)!)?(\d+(?:\.\d+)*)` +
+	`(?:[-_.]?(a|alpha|b|beta|c|rc|pre|preview)[-_.]?(\d*))?` +
+	`(?:-(\d+)|[-_.]?(post|rev|r)[-_.]?(\d*))?` +
+	`(?:[-_.]?(dev)[-_.]?(\d*))?(?:\+.*)?$`)
+
+// pythonVersion is a parsed PEP 440 version. Missing parts are represented
+// by sentinels so that plain tuples order correctly: dev releases before
+// pre-releases before the release before post-releases.
+type pythonVersion struct {
+	epoch   int
+	release []string
+	pre     [2]int // phase (a, b, rc as 0, 1, 2) and number
+	post    int
+	dev     int
+}
+
+const (
+	absentLow  = -1 << 31
+	absentHigh = 1<<31 - 1
+)
+
+func parsePython(v string) (pythonVersion, bool) {
+	m := pep440.FindStringSubmatch(strings.ToLower(strings.TrimSpace(v)))
+	if m == nil {
+		return pythonVersion{}, false
+	}
+	pv := pythonVersion{release: strings.Split(m[2], "."), pre: [2]int{absentHigh, 0}, post: absentLow, dev: absentHigh}
+	pv.epoch, _ = strconv.Atoi(m[1])
+	if m[3] != "" {
+		phase := map[string]int{"a": 0, "alpha": 0, "b": 1, "beta": 1, "c": 2, "rc": 2, "pre": 2, "preview": 2}[m[3]]
+		n, _ := strconv.Atoi(m[4])
+		pv.pre = [2]int{phase, n}
+	}
+	if m[5] != "" || m[6] != "" {
+		pv.post, _ = strconv.Atoi(m[5] + m[7])
+	}
+	if m[8] != "" {
+		pv.dev, _ = strconv.Atoi(m[9])
+		// A dev release of a final version sorts before its pre-releases
+		if m[3] == "" && pv.post == absentLow {
+			pv.pre = [2]int{absentLow, 0}
+		}
+	}
+	return pv, true
+}
+
+// comparePython orders PyPI versions following PEP 440, falling back to
+// semver ordering for versions it cannot parse
+func comparePython(a, b string) int {
+	pa, okA := parsePython(a)
+	pb, okB := parsePython(b)
+	if !okA || !okB {
+		return compareSemver(a, b)
+	}
+	if pa.epoch != pb.epoch {
+		return sign(pa.epoch - pb.epoch)
+	}
+	if c := compareIdentifiers(pa.release, pb.release, true); c != 0 {
+		return c
+	}
+	for _, pair := range [][2]int{{pa.pre[0], pb.pre[0]}, {pa.pre[1], pb.pre[1]}, {pa.post, pb.post}, {pa.dev, pb.dev}} {
+		if pair[0] != pair[1] {
+			if pair[0] < pair[1] {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
+
+func isDigit(c byte) bool  { return c >= '0' && c <= '9' }
+func isLetter(c byte) bool { return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') }
+func isAlnum(c byte) bool  { return isDigit(c) || isLetter(c) }
+
+func sign(n int) int {
+	switch {
+	case n < 0:
+		return -1
+	case n > 0:
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,69 @@
+package vuln
+
+import "testing"
+
+func TestCompareVersions(t *testing.T) {
+	tests := []struct {
+		name    string
+		compare compareFunc
+		a, b    string
+		want    int
+	}{
+		{"dpkg tilde before release", compareDebian, "1.0~rc1", "1.0", -1},
+		{"dpkg double tilde", compareDebian, "1.0~~", "1.0~", -1},
+		{"dpkg epoch", compareDebian, "1:0.9", "2.0", 1},
+		{"dpkg numeric", compareDebian, "1.2.10", "1.2.9", 1},
+		{"dpkg letter after end", compareDebian, "1.0a", "1.0", 1},
+		{"dpkg leading zeros", compareDebian, "1.00", "1.0", 0},
+		{"dpkg revision", compareDebian, "2.36-9", "2.36-10", -1},
+		{"dpkg security update", compareDebian, "2.36-9", "2.36-9+deb12u3", -1},
+		{"dpkg openssl letters", compareDebian, "1.1.1w-0+deb11u1", "1.1.1n-0+deb11u5", 1},
+		{"dpkg plus after dot", compareDebian, "1.0+1", "1.0.1", -1},
+
+		{"apk release", compareAlpine, "3.1.4-r5", "3.1.4-r4", 1},
+		{"apk rc before release", compareAlpine, "1.2.3_rc1-r0", "1.2.3-r0", -1},
+		{"apk alpha before beta", compareAlpine, "1.2_alpha1", "1.2_beta1", -1},
+		{"apk numeric", compareAlpine, "1.36.10-r0", "1.36.9-r0", 1},
+
+		{"rpm caret after release", compareRPM, "1.0^git1", "1.0", 1},
+		{"rpm caret before next version", compareRPM, "1.0^git1", "1.0.1", -1},
+		{"rpm tilde before release", compareRPM, "1.0~rc1", "1.0", -1},
+		{"rpm epoch", compareRPM, "1:0.9", "2.0", 1},
+		{"rpm release", compareRPM, "2.0-1.el9", "2.0-2.el9", -1},
+		{"rpm numeric segment", compareRPM, "1.0.10", "1.0.9", 1},
+		{"rpm number after letters", compareRPM, "1.0a", "1.0.1", -1},
+		{"rpm separators ignored", compareRPM, "1_0", "1.0", 0},
+		{"rpm leading zeros", compareRPM, "1.010", "1.10", 0},
+
+		{"semver pre-release", compareSemver, "1.0.0-rc.1", "1.0.0", -1},
+		{"semver shorter pre-release", compareSemver, "1.0.0-alpha", "1.0.0-alpha.1", -1},
+		{"semver numeric pre-release", compareSemver, "1.0.0-alpha.2", "1.0.0-alpha.11", -1},
+		{"semver number before word", compareSemver, "1.0.0-1", "1.0.0-alpha", -1},
+		{"semver words", compareSemver, "1.0.0-alpha.beta", "1.0.0-beta", -1},
+		{"semver minor", compareSemver, "1.10.0", "1.9.0", 1},
+		{"semver v prefix and build", compareSemver, "v1.2.3", "1.2.3+build.5", 0},
+		{"semver missing patch", compareSemver, "1.2", "1.2.0", 0},
+
+		{"pep440 dev before pre", comparePython, "1.0.dev1", "1.0a1", -1},
+		{"pep440 alpha before beta", comparePython, "1.0a1", "1.0b1", -1},
+		{"pep440 beta before rc", comparePython, "1.0b2", "1.0rc1", -1},
+		{"pep440 rc before release", comparePython, "1.0rc1", "1.0", -1},
+		{"pep440 post after release", comparePython, "1.0.post1", "1.0", 1},
+		{"pep440 dev of pre", comparePython, "1.0a1.dev1", "1.0a1", -1},
+		{"pep440 epoch", comparePython, "1!0.1", "2.0", 1},
+		{"pep440 padding", comparePython, "1.0", "1.0.0", 0},
+		{"pep440 implicit post", comparePython, "1.0-1", "1.0.post1", 0},
+		{"pep440 spellings", comparePython, "1.0c1", "1.0rc1", 0},
+		{"pep440 local version", comparePython, "1.0+local.1", "1.0", 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := sign(tt.compare(tt.a, tt.b)); got != tt.want {
+				t.Errorf("compare(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+			}
+			if got := sign(tt.compare(tt.b, tt.a)); got != -tt.want {
+				t.Errorf("compare(%q, %q) = %d, want %d", tt.b, tt.a, got, -tt.want)
+			}
+		})
+	}
+}